@@ -0,0 +1,118 @@
+// Command audit checks and exports the audit log of an environment.
+//
+//	audit [-env dev] verify [-file export.jsonl]
+//	audit [-env dev] export [-after seq] > export.jsonl
+//
+// verify checks the hash chain, of the configured database or of an export,
+// and reports the first broken link. It exits with status 1 if the chain is
+// broken and 2 if the log cannot be read. export writes the log as JSON Lines.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+)
+
+func main() {
+	var env string
+	flag.StringVar(&env, "env", "dev", "Environment (dev, staging, prod)")
+	flag.Usage = usage
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "verify":
+		os.Exit(verify(env, flag.Args()[1:]))
+	case "export":
+		os.Exit(export(env, flag.Args()[1:]))
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: audit [-env dev] verify [-file export.jsonl]")
+	fmt.Fprintln(os.Stderr, "       audit [-env dev] export [-after seq]")
+}
+
+func verify(env string, args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	file := flags.String("file", "", "Check this JSON Lines export instead of the database")
+	flags.Parse(args)
+
+	var v *audit.Verifier
+	var err error
+	if *file != "" {
+		var f *os.File
+		if f, err = os.Open(*file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		v, err = audit.VerifyJSONL(f)
+	} else {
+		store, closeStore, openErr := openStore(env)
+		if openErr != nil {
+			fmt.Fprintln(os.Stderr, openErr)
+			return 2
+		}
+		defer closeStore()
+		v, err = audit.Verify(context.Background(), store)
+	}
+
+	if audit.IsBreak(err) {
+		fmt.Printf("FAILED after %d good entries: %v\n", v.Count, err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("OK: %d entries, head %s\n", v.Count, v.Head())
+	return 0
+}
+
+func export(env string, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	after := flags.Int64("after", 0, "Only export entries with a greater seq")
+	flags.Parse(args)
+
+	store, closeStore, err := openStore(env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer closeStore()
+	if err := audit.Export(context.Background(), store, os.Stdout, *after); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// openStore opens the audit log of env's database. The memory driver keeps
+// the log inside the server process, out of reach of this command.
+func openStore(env string) (audit.Store, func(), error) {
+	config, err := config.LoadConfig(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.Database.Driver == database.DriverMemory {
+		return nil, nil, fmt.Errorf("the %s environment keeps the audit log in memory; use the sqlite or postgres driver", env)
+	}
+	db, err := database.ConnectDB(&config)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := audit.NewStore(config.Database.Driver, db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return store, func() { db.Close() }, nil
+}
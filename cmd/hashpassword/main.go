@@ -0,0 +1,28 @@
+// Command hashpassword prints an argon2id hash for a password read from stdin,
+// for use as auth.basic.users[].passwordhash in the config files.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+)
+
+func main() {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(hash)
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nilemarezz/go-init-template/pkg/cache"
 	"github.com/nilemarezz/go-init-template/pkg/config"
 	"github.com/nilemarezz/go-init-template/pkg/database"
 	"github.com/nilemarezz/go-init-template/pkg/logger"
+	"github.com/nilemarezz/go-init-template/pkg/mail"
+	"github.com/nilemarezz/go-init-template/pkg/tlsconfig"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 
+	"github.com/nilemarezz/go-init-template/internal/admin"
+	"github.com/nilemarezz/go-init-template/internal/apikey"
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
 	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/authz"
+	"github.com/nilemarezz/go-init-template/internal/book"
+	"github.com/nilemarezz/go-init-template/internal/idempotency"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/nilemarezz/go-init-template/internal/oidc"
+	"github.com/nilemarezz/go-init-template/internal/oidc/mockidp"
+	"github.com/nilemarezz/go-init-template/internal/ratelimit"
+	"github.com/nilemarezz/go-init-template/internal/user"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	// gin-swagger middleware
@@ -33,6 +51,20 @@ import (
 
 // @securityDefinitions.basic  BasicAuth
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT bearer token, sent as "Bearer <token>".
+
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+
+// @securityDefinitions.apikey  SessionAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Session token from /users/login, sent as "Session <token>". Browsers send the session cookie instead.
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
@@ -56,21 +88,206 @@ func main() {
 	if err != nil {
 		panic("failed to connect database")
 	}
-	defer db.Close()
+	if db != nil {
+		defer db.Close()
+	}
+
+	// Apply schema migrations
+	if config.Database.Driver == database.DriverPostgres {
+		if err := database.Migrate(db); err != nil {
+			panic(err)
+		}
+	}
+
+	authorRepo, err := author.NewRepository(config.Database.Driver, db)
+	if err != nil {
+		panic(err)
+	}
+
+	// Listen for row changes made by other instances
+	var changes *database.ChangeListener
+	if config.Database.Driver == database.DriverPostgres && config.Database.ChangeFeed {
+		changes = database.NewChangeListener(&config, db)
+		go func() {
+			if err := changes.Run(context.Background()); err != nil {
+				logger.Error("change listener stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	// Wrap the repository with a cache for hot author lookups
+	if config.Cache.Enabled {
+		authorCache, err := cache.NewCache(&config)
+		if err != nil {
+			panic(err)
+		}
+		cachedRepo := author.NewCachedAuthorRepository(authorRepo, authorCache, config.Cache.TTL, config.Cache.NegativeTTL)
+		if changes != nil {
+			changes.Subscribe(cachedRepo.HandleChange)
+		}
+		authorRepo = cachedRepo
+	}
+
+	// Initialize books, and refuse to delete authors that still have books
+	bookRepo, err := book.NewRepository(config.Database.Driver, db)
+	if err != nil {
+		panic(err)
+	}
+	authorRepo = book.GuardAuthorDeletes(authorRepo, bookRepo)
+
+	// Record author writes, credential and account changes, logins and
+	// rejected requests in the audit log
+	auditStore, err := audit.NewStore(config.Database.Driver, db)
+	if err != nil {
+		panic(err)
+	}
+	auditLog := audit.NewLog(auditStore)
+	authorRepo = author.NewAuditedAuthorRepository(authorRepo, auditLog)
 
 	router := gin.Default()
 
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Take client addresses, which every caller is rate limited by,
+	// from X-Forwarded-For only when a trusted proxy sent it
+	if err := router.SetTrustedProxies(config.HTTP.TrustedProxies); err != nil {
+		panic(err)
+	}
+
+	// Add request ids, auth failure auditing, CORS, security headers and request
+	// body limits to every route
+	router.Use(
+		middleware.RequestID(),
+		auditLog.RecordAuthFailures(),
+		middleware.SecurityHeaders(config.HTTP.Headers),
+		middleware.CORS(config.HTTP.CORS),
+		middleware.BodyLimit(config.HTTP),
+	)
+
+	// Without an admin listener, serve Swagger and metrics on the public port
+	if !config.Admin.Enabled {
+		router.GET("/swagger/*any", middleware.SwaggerHeaders(config.HTTP.Headers), ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+		// Initialize  /metrics routes for prometheus metrics
+		router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
+
+	// Initialize authentication
+	authn, err := auth.NewAuthenticationFromConfig(&config, db, auditLog)
+	if err != nil {
+		panic(err)
+	}
+
+	// Initialize API key authentication
+	apiKeyRepo, err := apikey.NewRepository(config.Database.Driver, db)
+	if err != nil {
+		panic(err)
+	}
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo, auditLog)
+	authn.Add(apikey.NewAuthenticator(apiKeyService))
 
-	// Initialize  /metrics routes for prometheus metrics
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// Initialize user accounts and session authentication
+	mailSender, err := mail.NewSender(&config)
+	if err != nil {
+		panic(err)
+	}
+	userRepo, err := user.NewRepository(config.Database.Driver, db)
+	if err != nil {
+		panic(err)
+	}
+	userService := user.NewUserService(userRepo, mailSender, auditLog, user.OptionsFromConfig(&config))
+	authn.Add(user.NewAuthenticator(userService))
+
+	// Initialize authorization policy
+	policy := authz.NewPolicyFromConfig(&config)
+
+	// Initialize rate limiting, by client address and then by the caller
+	// authenticated for the request. The address is charged before any
+	// credentials are checked, so invalid credentials spend tokens too
+	limiter, err := ratelimit.NewLimiterFromConfig(&config)
+	if err != nil {
+		panic(err)
+	}
+
+	api := router.Group("", limiter.Handler(authn), authn.Optional())
+
+	// Replay responses to retried author and book writes, keyed by the caller
+	// authenticated for the request. API key and session responses carry
+	// secrets, so they are never stored
+	idempotencyGuard, err := idempotency.NewGuardFromConfig(&config, db)
+	if err != nil {
+		panic(err)
+	}
+	writes := api.Group("", idempotencyGuard.Handler())
 
 	// Init routes
-	author.SetupRouter(router, db)
+	author.SetupRouter(writes, authorRepo, authn, policy)
+	book.SetupRouter(writes, bookRepo, authorRepo, authn, policy)
+	apikey.SetupRouter(api, apiKeyService, authn, policy)
+	user.SetupRouter(api, userService, authn, config.User.CookieSecure)
+
+	// Initialize OpenID Connect sign-in, optionally against the embedded mock IdP
+	if config.Auth.OIDC.Enabled {
+		if config.Auth.OIDC.MockIdP {
+			idp, err := mockidp.NewFromConfig(&config)
+			if err != nil {
+				panic(err)
+			}
+			if idp.Path() == "" {
+				panic("auth.oidc.issuer needs a path to serve the mock IdP under, e.g. http://localhost:8080/mock-idp")
+			}
+			logger.Warning("serving the mock OIDC identity provider; do not use in production", zap.String("issuer", config.Auth.OIDC.Issuer))
+			router.Any(idp.Path()+"/*any", gin.WrapH(idp))
+		}
+		rp, err := oidc.NewRelyingParty(config.Auth.OIDC)
+		if err != nil {
+			panic(err)
+		}
+		oidc.SetupRouter(api, rp, userService, config.User.CookieSecure)
+	}
+
+	// Initialize admin listener for operational endpoints
+	if config.Admin.Enabled {
+		checks := map[string]admin.HealthCheck{}
+		if db != nil {
+			checks["database"] = db.PingContext
+		}
+		adminRouter := gin.Default()
+		if err := adminRouter.SetTrustedProxies(config.HTTP.TrustedProxies); err != nil {
+			panic(err)
+		}
+		adminRouter.Use(middleware.SecurityHeaders(config.HTTP.Headers))
+		admin.SetupRouter(adminRouter, router, checks, admin.NewAuthenticationFromConfig(&config, auditLog), config.HTTP.Headers)
+		go func() {
+			a := fmt.Sprintf(":%s", config.Admin.Port)
+			logger.Info("admin listener " + a)
+			if err := adminRouter.Run(a); err != nil {
+				logger.Error("admin listener stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	// Initialize web service
 	s := fmt.Sprintf(":%s", config.App.Port)
 	logger.Info(s)
-	router.Run(s)
+	server := &http.Server{Addr: s, Handler: router}
+	if !config.App.TLS.Enabled {
+		if err := server.ListenAndServe(); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	// Serve HTTPS, reloading rotated certificates in the background
+	certs, err := tlsconfig.NewReloader(config.App.TLS.CertFile, config.App.TLS.KeyFile, config.App.TLS.ClientCAFile)
+	if err != nil {
+		panic(err)
+	}
+	go certs.Run(context.Background(), config.App.TLS.ReloadInterval)
+	server.TLSConfig, err = tlsconfig.NewServerConfig(config.App.TLS, certs)
+	if err != nil {
+		panic(err)
+	}
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		panic(err)
+	}
 
 }
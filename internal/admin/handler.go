@@ -0,0 +1,131 @@
+// handler.go
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+// checkTimeout bounds each readiness check.
+const checkTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency is ready to serve traffic.
+type HealthCheck func(ctx context.Context) error
+
+// SetupRouter registers the operational endpoints on the admin router.
+// public is the business API engine whose routes are listed under /routes.
+// Health checks are always open so that probes need no credentials; the other
+// endpoints require authn when it is non-nil.
+func SetupRouter(router gin.IRouter, public *gin.Engine, checks map[string]HealthCheck, authn *auth.Authentication, headers config.SecurityHeadersConfig) {
+
+	handler := NewAdminHandler(public, checks)
+
+	router.GET("/healthz", handler.Live)
+	router.GET("/readyz", handler.Ready)
+
+	protected := router.Group("")
+	if authn != nil {
+		protected.Use(authn.Required())
+	}
+	{
+		protected.GET("/metrics", gin.WrapH(promhttp.Handler()))
+		protected.GET("/swagger/*any", middleware.SwaggerHeaders(headers), ginSwagger.WrapHandler(swaggerFiles.Handler))
+		protected.GET("/loglevel", gin.WrapH(logger.Level))
+		protected.PUT("/loglevel", gin.WrapH(logger.Level))
+		protected.GET("/routes", handler.Routes)
+
+		debug := protected.Group("/debug/pprof")
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// Index serves the named runtime profiles, e.g. heap and goroutine.
+		debug.GET("/:profile", gin.WrapF(pprof.Index))
+	}
+}
+
+// NewAuthenticationFromConfig returns Basic auth for the admin users, or nil
+// if admin auth is disabled. lockouts, which may be nil, is told about
+// lockouts.
+func NewAuthenticationFromConfig(config *config.Config, lockouts auth.LockoutRecorder) *auth.Authentication {
+	if !config.Admin.Auth.Enabled {
+		return nil
+	}
+	return auth.NewAuthentication(auth.NewBasicAuthenticator(
+		auth.NewConfigCredentialStore(config.Admin.Auth.Users),
+		auth.LockoutPolicy{
+			MaxFailures: config.Auth.Basic.MaxFailures,
+			Duration:    config.Auth.Basic.LockoutDuration,
+			Recorder:    lockouts,
+		},
+	))
+}
+
+type AdminHandler struct {
+	public *gin.Engine
+	checks map[string]HealthCheck
+}
+
+func NewAdminHandler(public *gin.Engine, checks map[string]HealthCheck) *AdminHandler {
+	return &AdminHandler{public: public, checks: checks}
+}
+
+// Route is one entry of the public route listing.
+type Route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// HealthStatus is the body of the health check endpoints.
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Live reports that the process is running.
+func (h *AdminHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Ready runs the readiness checks and returns 503 if any fails.
+func (h *AdminHandler) Ready(c *gin.Context) {
+	status := HealthStatus{Status: "ok", Checks: make(map[string]string, len(h.checks))}
+	code := http.StatusOK
+	for name, check := range h.checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), checkTimeout)
+		err := check(ctx)
+		cancel()
+		if err != nil {
+			status.Status = "unavailable"
+			status.Checks[name] = err.Error()
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		status.Checks[name] = "ok"
+	}
+	c.JSON(code, status)
+}
+
+// Routes lists the routes registered on the public API.
+func (h *AdminHandler) Routes(c *gin.Context) {
+	routes := make([]Route, 0)
+	for _, route := range h.public.Routes() {
+		routes = append(routes, Route{Method: route.Method, Path: route.Path, Handler: route.Handler})
+	}
+	c.JSON(http.StatusOK, routes)
+}
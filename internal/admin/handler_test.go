@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func newAdminRouter(checks map[string]HealthCheck, authn *auth.Authentication) *gin.Engine {
+	public := gin.New()
+	public.GET("/authors/", func(c *gin.Context) {})
+	router := gin.New()
+	SetupRouter(router, public, checks, authn, config.SecurityHeadersConfig{})
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReady_ReportsFailingChecks(t *testing.T) {
+	// Arrange
+	healthy := newAdminRouter(map[string]HealthCheck{"database": func(context.Context) error { return nil }}, nil)
+	unhealthy := newAdminRouter(map[string]HealthCheck{"database": func(context.Context) error { return errors.New("connection refused") }}, nil)
+
+	// Act
+	ok := serve(healthy, "GET", "/readyz", "", nil)
+	failing := serve(unhealthy, "GET", "/readyz", "", nil)
+	live := serve(unhealthy, "GET", "/healthz", "", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, ok.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{"database":"ok"}}`, ok.Body.String())
+	assert.Equal(t, http.StatusServiceUnavailable, failing.Code)
+	assert.JSONEq(t, `{"status":"unavailable","checks":{"database":"connection refused"}}`, failing.Body.String())
+	assert.Equal(t, http.StatusOK, live.Code)
+}
+
+func TestRoutes_ListsPublicRoutes(t *testing.T) {
+	// Arrange
+	router := newAdminRouter(nil, nil)
+
+	// Act
+	w := serve(router, "GET", "/routes", "", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"method":"GET","path":"/authors/"`)
+}
+
+func TestLogLevel_ChangesAtRuntime(t *testing.T) {
+	// Arrange
+	router := newAdminRouter(nil, nil)
+	defer logger.Level.SetLevel(zapcore.InfoLevel)
+
+	// Act
+	put := serve(router, "PUT", "/loglevel", `{"level":"debug"}`, nil)
+	get := serve(router, "GET", "/loglevel", "", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, put.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, get.Body.String())
+	assert.Equal(t, zapcore.DebugLevel, logger.Level.Level())
+}
+
+func TestSetupRouter_ServesPprofAndMetrics(t *testing.T) {
+	// Arrange
+	router := newAdminRouter(nil, nil)
+
+	// Act
+	index := serve(router, "GET", "/debug/pprof/", "", nil)
+	goroutine := serve(router, "GET", "/debug/pprof/goroutine?debug=1", "", nil)
+	metrics := serve(router, "GET", "/metrics", "", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, index.Code)
+	assert.Equal(t, http.StatusOK, goroutine.Code)
+	assert.Contains(t, goroutine.Body.String(), "goroutine profile")
+	assert.Equal(t, http.StatusOK, metrics.Code)
+}
+
+func TestSetupRouter_RequiresAuthExceptHealth(t *testing.T) {
+	// Arrange
+	hash, err := auth.HashPassword("secret")
+	require.NoError(t, err)
+	authn := NewAuthenticationFromConfig(&config.Config{Admin: config.AdminConfig{Auth: config.AdminAuthConfig{
+		Enabled: true,
+		Users:   []config.BasicAuthUser{{Username: "ops", PasswordHash: hash}},
+	}}}, nil)
+	router := newAdminRouter(nil, authn)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("ops", "secret")
+
+	// Act
+	anonymous := serve(router, "GET", "/metrics", "", nil)
+	authenticated := serve(router, "GET", "/metrics", "", req.Header)
+	health := serve(router, "GET", "/healthz", "", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	assert.Contains(t, anonymous.Header().Get("WWW-Authenticate"), "Basic")
+	assert.Equal(t, http.StatusOK, authenticated.Code)
+	assert.Equal(t, http.StatusOK, health.Code)
+}
+
+func TestNewAuthenticationFromConfig_Disabled(t *testing.T) {
+	// Act
+	authn := NewAuthenticationFromConfig(&config.Config{}, nil)
+
+	// Assert
+	assert.Nil(t, authn)
+}
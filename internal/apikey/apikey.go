@@ -0,0 +1,50 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// resourceName is the name used for API keys in domain errors.
+const resourceName = "API key"
+
+// APIKey represents a long-lived machine credential. Only a hash of the
+// secret is stored; the secret itself is shown once when the key is created
+// or rotated.
+type APIKey struct {
+	ID         int            `db:"id" json:"id"`
+	Prefix     string         `db:"prefix" json:"prefix" example:"a1b2c3d4"`
+	Hash       string         `db:"key_hash" json:"-"`
+	Name       string         `db:"name" json:"name" example:"nightly-sync"`
+	Owner      string         `db:"owner" json:"owner" example:"catalog-team"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes" swaggertype:"array,string" example:"authors:read"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	UsageCount int64          `db:"usage_count" json:"usage_count"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// CreateRequest is the body of a request to create an API key.
+type CreateRequest struct {
+	Name      string     `json:"name" binding:"required" example:"nightly-sync"`
+	Owner     string     `json:"owner" binding:"required" example:"catalog-team"`
+	Scopes    []string   `json:"scopes" example:"authors:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// SecretResponse returns an API key together with its secret. It is only
+// sent when a key is created or rotated.
+type SecretResponse struct {
+	APIKey
+	Secret string `json:"secret" example:"gik_a1b2c3d4_..."`
+}
+
+// Active reports whether the key may currently be used.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
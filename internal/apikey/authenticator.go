@@ -0,0 +1,49 @@
+package apikey
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+)
+
+var keyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_key_requests_total",
+	Help: "Requests authenticated with an API key, by key prefix.",
+}, []string{"prefix"})
+
+// Authenticator authenticates requests carrying "Authorization: ApiKey <key>"
+// or "X-API-Key: <key>".
+type Authenticator struct {
+	service APIKeyService
+}
+
+// NewAuthenticator creates an auth.Authenticator backed by service.
+func NewAuthenticator(service APIKeyService) *Authenticator {
+	return &Authenticator{service: service}
+}
+
+func (a *Authenticator) Scheme() string {
+	return "ApiKey"
+}
+
+func (a *Authenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	secret := c.GetHeader("X-API-Key")
+	if scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "ApiKey") {
+		secret = value
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	key, err := a.service.Authenticate(secret)
+	if err != nil {
+		return nil, err
+	}
+	keyRequests.WithLabelValues(key.Prefix).Inc()
+	return &auth.Principal{Subject: key.Owner, Method: "apikey", CredentialID: key.Prefix, Scopes: key.Scopes}, nil
+}
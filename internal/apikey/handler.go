@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/authz"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+)
+
+func SetupRouter(router gin.IRouter, service APIKeyService, authn *auth.Authentication, policy *authz.Policy) {
+
+	handler := NewAPIKeyHandler(service)
+
+	adminRoutes := router.Group("/admin/apikeys", authn.Required(), policy.Require(authz.APIKeysManage))
+	{
+		adminRoutes.POST("/", handler.CreateKey)
+		adminRoutes.GET("/", handler.ListKeys)
+		adminRoutes.POST("/:id/rotate", handler.RotateKey)
+		adminRoutes.DELETE("/:id", handler.RevokeKey)
+	}
+}
+
+type APIKeyHandler struct {
+	service APIKeyService
+}
+
+func NewAPIKeyHandler(service APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateKey creates a new API key.
+// @Summary Create an API key
+// @Description Create a new API key. The secret is only returned in this response.
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param key body CreateRequest true "API key to create"
+// @Success 201 {object} SecretResponse
+// @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing apikeys:manage permission"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Router /admin/apikeys [post]
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	key, secret, err := h.service.CreateKey(c.Request.Context(), &req)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	// The secret must not be kept by caches or idempotency keys.
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, SecretResponse{APIKey: *key, Secret: secret})
+}
+
+// ListKeys lists all API keys.
+// @Summary List API keys
+// @Description Retrieve all API keys, including revoked and expired ones. Secrets are never returned.
+// @Tags apikeys
+// @Produce json
+// @Success 200 {array} APIKey
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing apikeys:manage permission"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Router /admin/apikeys [get]
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.service.ListKeys()
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RotateKey replaces the secret of an API key.
+// @Summary Rotate an API key
+// @Description Issue a new secret for an active API key. The old secret stops working immediately.
+// @Tags apikeys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} SecretResponse
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing apikeys:manage permission"
+// @Failure 404 {object} httputil.HTTPError "API key not found or revoked"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Router /admin/apikeys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httputil.NewError(c, http.StatusBadRequest, errors.New("invalid id"))
+		return
+	}
+
+	key, secret, err := h.service.RotateKey(c.Request.Context(), id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, SecretResponse{APIKey: *key, Secret: secret})
+}
+
+// RevokeKey revokes an API key.
+// @Summary Revoke an API key
+// @Description Permanently revoke an API key. Revoking an already revoked key succeeds.
+// @Tags apikeys
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing apikeys:manage permission"
+// @Failure 404 {object} httputil.HTTPError "API key not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Router /admin/apikeys/{id} [delete]
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httputil.NewError(c, http.StatusBadRequest, errors.New("invalid id"))
+		return
+	}
+
+	if err := h.service.RevokeKey(c.Request.Context(), id); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/authz"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyEndpoints_RequireAuthentication(t *testing.T) {
+	// Arrange
+	service := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	router := gin.New()
+	SetupRouter(router, service, auth.NewAuthentication(NewAuthenticator(service)), authz.NewPolicyFromConfig(&config.Config{}))
+	req, _ := http.NewRequest("GET", "/admin/apikeys/", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "ApiKey", w.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthenticator_AcceptsBothHeaders(t *testing.T) {
+	// Arrange
+	service := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	key, secret, err := service.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog", Scopes: []string{"authors:read"}})
+	require.NoError(t, err)
+	authenticator := NewAuthenticator(service)
+
+	for _, header := range [][2]string{{"Authorization", "ApiKey " + secret}, {"X-API-Key", secret}} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set(header[0], header[1])
+
+		// Act
+		principal, err := authenticator.Authenticate(c)
+
+		// Assert
+		require.NoError(t, err, header[0])
+		assert.Equal(t, &auth.Principal{Subject: "catalog", Method: "apikey", CredentialID: key.Prefix, Scopes: []string{"authors:read"}}, principal)
+	}
+}
+
+func TestCreateKey_ShowsSecretOnceAndListHidesIt(t *testing.T) {
+	// Arrange: bootstrap a key through the service, then manage keys over HTTP with it.
+	service := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	_, adminSecret, err := service.CreateKey(context.Background(), &CreateRequest{Name: "admin", Owner: "ops", Scopes: []string{authz.APIKeysManage}})
+	require.NoError(t, err)
+	router := gin.New()
+	SetupRouter(router, service, auth.NewAuthentication(NewAuthenticator(service)), authz.NewPolicyFromConfig(&config.Config{}))
+
+	create := httptest.NewRecorder()
+	createReq, _ := http.NewRequest("POST", "/admin/apikeys/", strings.NewReader(`{"name":"sync","owner":"catalog"}`))
+	createReq.Header.Set("X-API-Key", adminSecret)
+	list := httptest.NewRecorder()
+	listReq, _ := http.NewRequest("GET", "/admin/apikeys/", nil)
+	listReq.Header.Set("X-API-Key", adminSecret)
+
+	// Act
+	router.ServeHTTP(create, createReq)
+	router.ServeHTTP(list, listReq)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, create.Code)
+	var created SecretResponse
+	require.NoError(t, json.Unmarshal(create.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, "no-store", create.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.NotContains(t, list.Body.String(), created.Secret)
+	assert.NotContains(t, list.Body.String(), "key_hash")
+	var keys []APIKey
+	require.NoError(t, json.Unmarshal(list.Body.Bytes(), &keys))
+	assert.Len(t, keys, 2)
+}
+
+func TestRevokeKey_Endpoint(t *testing.T) {
+	// Arrange
+	service := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	_, adminSecret, err := service.CreateKey(context.Background(), &CreateRequest{Name: "admin", Owner: "ops", Scopes: []string{authz.APIKeysManage}})
+	require.NoError(t, err)
+	victim, victimSecret, err := service.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+	router := gin.New()
+	SetupRouter(router, service, auth.NewAuthentication(NewAuthenticator(service)), authz.NewPolicyFromConfig(&config.Config{}))
+
+	req, _ := http.NewRequest("DELETE", "/admin/apikeys/"+strconv.Itoa(victim.ID), nil)
+	req.Header.Set("X-API-Key", adminSecret)
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err = service.Authenticate(victimSecret)
+	assert.Equal(t, errInvalidKey, err)
+}
+
+func TestAPIKeyEndpoints_RequireManagePermission(t *testing.T) {
+	// Arrange
+	service := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	_, secret, err := service.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog", Scopes: []string{authz.AuthorsWrite}})
+	require.NoError(t, err)
+	router := gin.New()
+	SetupRouter(router, service, auth.NewAuthentication(NewAuthenticator(service)), authz.NewPolicyFromConfig(&config.Config{}))
+	req, _ := http.NewRequest("GET", "/admin/apikeys/", nil)
+	req.Header.Set("X-API-Key", secret)
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,119 @@
+package apikey
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+type memoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[int]APIKey
+	nextID int
+}
+
+// NewMemoryAPIKeyRepository returns a thread-safe APIKeyRepository that keeps
+// keys in process memory. It is intended for local development and tests.
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &memoryAPIKeyRepository{keys: make(map[int]APIKey), nextID: 1}
+}
+
+func (m *memoryAPIKeyRepository) CreateKey(key *APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.keys {
+		if existing.Prefix == key.Prefix {
+			return errs.NewConflictError(resourceName, "api_keys_prefix_key", "prefix")
+		}
+	}
+	key.ID = m.nextID
+	key.CreatedAt = time.Now()
+	m.nextID++
+	m.keys[key.ID] = *key
+	return nil
+}
+
+func (m *memoryAPIKeyRepository) GetKeyByPrefix(prefix string) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.Prefix == prefix {
+			return &key, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryAPIKeyRepository) GetKeyById(id int) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &key, nil
+}
+
+func (m *memoryAPIKeyRepository) ListKeys() ([]*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]*APIKey, 0, len(m.keys))
+	for id := 1; id < m.nextID; id++ {
+		if key, ok := m.keys[id]; ok {
+			keys = append(keys, &key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryAPIKeyRepository) UpdateKeyHash(id int, prefix, hash string) error {
+	return m.update(id, func(key *APIKey) bool {
+		if key.RevokedAt != nil {
+			return false
+		}
+		key.Prefix = prefix
+		key.Hash = hash
+		return true
+	})
+}
+
+func (m *memoryAPIKeyRepository) RevokeKey(id int, at time.Time) error {
+	return m.update(id, func(key *APIKey) bool {
+		if key.RevokedAt == nil {
+			key.RevokedAt = &at
+		}
+		return true
+	})
+}
+
+func (m *memoryAPIKeyRepository) RecordUsage(id int, at time.Time) error {
+	err := m.update(id, func(key *APIKey) bool {
+		key.LastUsedAt = &at
+		key.UsageCount++
+		return true
+	})
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// update applies fn to the key with the given id under the write lock. fn
+// returns false if the key is not in a state that can be updated.
+func (m *memoryAPIKeyRepository) update(id int, fn func(key *APIKey) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok || !fn(&key) {
+		return sql.ErrNoRows
+	}
+	m.keys[id] = key
+	return nil
+}
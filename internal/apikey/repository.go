@@ -0,0 +1,113 @@
+package apikey
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+const selectColumns = "id, prefix, key_hash, name, owner, scopes, expires_at, last_used_at, usage_count, revoked_at, created_at"
+
+type APIKeyRepository interface {
+	CreateKey(key *APIKey) error
+	GetKeyByPrefix(prefix string) (*APIKey, error)
+	GetKeyById(id int) (*APIKey, error)
+	ListKeys() ([]*APIKey, error)
+	UpdateKeyHash(id int, prefix, hash string) error
+	RevokeKey(id int, at time.Time) error
+	RecordUsage(id int, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// NewRepository returns the APIKeyRepository implementation for the
+// configured database driver. API keys need Postgres; other drivers fall back
+// to an in-memory store that is lost on restart.
+func NewRepository(driver string, db *sqlx.DB) (APIKeyRepository, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewAPIKeyRepository(db), nil
+	case database.DriverSQLite, database.DriverMemory:
+		logger.Warning("API keys are kept in memory", zap.String("driver", driver))
+		return NewMemoryAPIKeyRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func (r apiKeyRepository) CreateKey(key *APIKey) error {
+	err := r.db.QueryRowx(
+		`INSERT INTO api_keys (prefix, key_hash, name, owner, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		key.Prefix, key.Hash, key.Name, key.Owner, key.Scopes, key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r apiKeyRepository) GetKeyByPrefix(prefix string) (*APIKey, error) {
+	var key APIKey
+	err := r.db.Get(&key, "SELECT "+selectColumns+" FROM api_keys WHERE prefix = $1", prefix)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &key, nil
+}
+
+func (r apiKeyRepository) GetKeyById(id int) (*APIKey, error) {
+	var key APIKey
+	err := r.db.Get(&key, "SELECT "+selectColumns+" FROM api_keys WHERE id = $1", id)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &key, nil
+}
+
+func (r apiKeyRepository) ListKeys() ([]*APIKey, error) {
+	keys := []*APIKey{}
+	err := r.db.Select(&keys, "SELECT "+selectColumns+" FROM api_keys ORDER BY id")
+	return keys, errs.FromPostgres(err, resourceName)
+}
+
+func (r apiKeyRepository) UpdateKeyHash(id int, prefix, hash string) error {
+	res, err := r.db.Exec("UPDATE api_keys SET prefix = $1, key_hash = $2 WHERE id = $3 AND revoked_at IS NULL", prefix, hash, id)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r apiKeyRepository) RevokeKey(id int, at time.Time) error {
+	res, err := r.db.Exec("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2", at, id)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r apiKeyRepository) RecordUsage(id int, at time.Time) error {
+	_, err := r.db.Exec("UPDATE api_keys SET last_used_at = $1, usage_count = usage_count + 1 WHERE id = $2", at, id)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func requireRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
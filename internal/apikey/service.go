@@ -0,0 +1,176 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+// auditResource names API keys in audit log entries.
+const auditResource = "apikey"
+
+// keyPrefix marks strings as API keys of this service, which helps secret
+// scanners recognise leaked keys.
+const keyPrefix = "gik"
+
+var errInvalidKey = errors.New("invalid API key")
+
+type APIKeyService interface {
+	CreateKey(ctx context.Context, req *CreateRequest) (*APIKey, string, error)
+	ListKeys() ([]*APIKey, error)
+	RotateKey(ctx context.Context, id int) (*APIKey, string, error)
+	RevokeKey(ctx context.Context, id int) error
+	Authenticate(secret string) (*APIKey, error)
+}
+
+type apiKeyService struct {
+	repo APIKeyRepository
+	log  *audit.Log
+	now  func() time.Time
+}
+
+// NewAPIKeyService creates an APIKeyService that records key creation,
+// rotation and revocation in log.
+func NewAPIKeyService(repo APIKeyRepository, log *audit.Log) APIKeyService {
+	return &apiKeyService{repo: repo, log: log, now: time.Now}
+}
+
+// CreateKey stores a new key and returns it with its secret, which cannot be
+// recovered later.
+func (s apiKeyService) CreateKey(ctx context.Context, req *CreateRequest) (*APIKey, string, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, "", errs.NewValidationError(resourceName, "expires_at", "must be in the future")
+	}
+
+	prefix, secret, hash, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &APIKey{
+		Prefix:    prefix,
+		Hash:      hash,
+		Name:      req.Name,
+		Owner:     req.Owner,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	if err := s.repo.CreateKey(key); err != nil {
+		return nil, "", err
+	}
+	s.record(ctx, audit.APIKeyCreate, key.ID, map[string]interface{}{"name": key.Name, "owner": key.Owner, "scopes": key.Scopes, "prefix": key.Prefix})
+	return key, secret, nil
+}
+
+func (s apiKeyService) ListKeys() ([]*APIKey, error) {
+	return s.repo.ListKeys()
+}
+
+// RotateKey replaces the secret of an active key. The old secret stops
+// working immediately.
+func (s apiKeyService) RotateKey(ctx context.Context, id int) (*APIKey, string, error) {
+	prefix, secret, hash, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.repo.UpdateKeyHash(id, prefix, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errs.NewNotFoundError(resourceName)
+		}
+		return nil, "", err
+	}
+	key, err := s.repo.GetKeyById(id)
+	if err != nil {
+		return nil, "", err
+	}
+	s.record(ctx, audit.APIKeyRotate, key.ID, map[string]interface{}{"prefix": key.Prefix})
+	return key, secret, nil
+}
+
+func (s apiKeyService) RevokeKey(ctx context.Context, id int) error {
+	err := s.repo.RevokeKey(id, s.now())
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError(resourceName)
+	}
+	if err != nil {
+		return err
+	}
+	s.record(ctx, audit.APIKeyRevoke, id, nil)
+	return nil
+}
+
+// Authenticate returns the active key matching secret and records its use.
+func (s apiKeyService) Authenticate(secret string) (*APIKey, error) {
+	prefix, ok := parsePrefix(secret)
+	if !ok {
+		return nil, errInvalidKey
+	}
+
+	key, err := s.repo.GetKeyByPrefix(prefix)
+	if err == sql.ErrNoRows {
+		return nil, errInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, errInvalidKey
+	}
+
+	now := s.now()
+	if !key.Active(now) {
+		return nil, errInvalidKey
+	}
+	if err := s.repo.RecordUsage(key.ID, now); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s apiKeyService) record(ctx context.Context, eventType string, id int, data interface{}) {
+	s.log.Record(ctx, audit.Event{
+		Type:       eventType,
+		Resource:   auditResource,
+		ResourceID: strconv.Itoa(id),
+		Data:       data,
+	})
+}
+
+// generateKey returns a new secret of the form gik_<prefix>_<random>, the
+// prefix used to look it up and the hash to store.
+func generateKey() (prefix, secret, hash string, err error) {
+	raw := make([]byte, 28)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(raw[:4])
+	secret = keyPrefix + "_" + prefix + "_" + hex.EncodeToString(raw[4:])
+	return prefix, secret, hashSecret(secret), nil
+}
+
+func parsePrefix(secret string) (string, bool) {
+	parts := strings.Split(secret, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashSecret hashes a key secret. Secrets carry 192 bits of randomness, so a
+// fast hash is sufficient and keeps authentication cheap.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,168 @@
+package apikey
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateKey_ReturnsSecretOnce(t *testing.T) {
+	// Arrange
+	repo := NewMemoryAPIKeyRepository()
+	svc := NewAPIKeyService(repo, audit.NewLog(audit.NewMemoryStore()))
+
+	// Act
+	key, secret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog", Scopes: []string{"authors:read"}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "gik_"+key.Prefix+"_"))
+	stored, err := repo.GetKeyById(key.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, stored.Hash, secret)
+	assert.Equal(t, hashSecret(secret), stored.Hash)
+}
+
+func TestCreateKey_RejectsPastExpiry(t *testing.T) {
+	// Arrange
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	past := time.Now().Add(-time.Hour)
+
+	// Act
+	_, _, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog", ExpiresAt: &past})
+
+	// Assert
+	assert.IsType(t, &errs.ValidationError{}, err)
+}
+
+func TestAuthenticate_RecordsUsage(t *testing.T) {
+	// Arrange
+	repo := NewMemoryAPIKeyRepository()
+	svc := NewAPIKeyService(repo, audit.NewLog(audit.NewMemoryStore()))
+	key, secret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+
+	// Act
+	_, err1 := svc.Authenticate(secret)
+	authenticated, err2 := svc.Authenticate(secret)
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, key.ID, authenticated.ID)
+	stored, _ := repo.GetKeyById(key.ID)
+	assert.Equal(t, int64(2), stored.UsageCount)
+	assert.NotNil(t, stored.LastUsedAt)
+}
+
+func TestAuthenticate_RejectsInvalidKeys(t *testing.T) {
+	// Arrange
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	key, secret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+
+	for name, candidate := range map[string]string{
+		"malformed":     "not-a-key",
+		"unknownPrefix": "gik_00000000_" + strings.Repeat("0", 48),
+		"wrongSecret":   "gik_" + key.Prefix + "_" + strings.Repeat("0", 48),
+	} {
+		// Act
+		_, err := svc.Authenticate(candidate)
+
+		// Assert
+		assert.Equal(t, errInvalidKey, err, name)
+	}
+	_, err = svc.Authenticate(secret)
+	assert.NoError(t, err)
+}
+
+func TestRotateKey_InvalidatesOldSecret(t *testing.T) {
+	// Arrange
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	key, oldSecret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+
+	// Act
+	rotated, newSecret, err := svc.RotateKey(context.Background(), key.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, rotated.ID)
+	_, oldErr := svc.Authenticate(oldSecret)
+	_, newErr := svc.Authenticate(newSecret)
+	assert.Equal(t, errInvalidKey, oldErr)
+	assert.NoError(t, newErr)
+}
+
+func TestRevokeKey(t *testing.T) {
+	// Arrange
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore()))
+	key, secret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+
+	// Act
+	revokeErr := svc.RevokeKey(context.Background(), key.ID)
+	_, authErr := svc.Authenticate(secret)
+	_, _, rotateErr := svc.RotateKey(context.Background(), key.ID)
+	missingErr := svc.RevokeKey(context.Background(), 999)
+
+	// Assert
+	assert.NoError(t, revokeErr)
+	assert.Equal(t, errInvalidKey, authErr)
+	assert.IsType(t, &errs.NotFoundError{}, rotateErr)
+	assert.IsType(t, &errs.NotFoundError{}, missingErr)
+}
+
+func TestAuthenticate_RejectsExpiredKey(t *testing.T) {
+	// Arrange
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(audit.NewMemoryStore())).(*apiKeyService)
+	expiresAt := time.Now().Add(time.Hour)
+	_, secret, err := svc.CreateKey(context.Background(), &CreateRequest{Name: "sync", Owner: "catalog", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	// Act
+	svc.now = func() time.Time { return expiresAt.Add(time.Second) }
+	_, err = svc.Authenticate(secret)
+
+	// Assert
+	assert.Equal(t, errInvalidKey, err)
+}
+
+func TestKeyChanges_AreAudited(t *testing.T) {
+	// Arrange
+	store := audit.NewMemoryStore()
+	svc := NewAPIKeyService(NewMemoryAPIKeyRepository(), audit.NewLog(store))
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Method: "basic"})
+
+	// Act
+	key, _, err := svc.CreateKey(ctx, &CreateRequest{Name: "sync", Owner: "catalog"})
+	require.NoError(t, err)
+	_, _, rotateErr := svc.RotateKey(ctx, key.ID)
+	revokeErr := svc.RevokeKey(ctx, key.ID)
+	missingErr := svc.RevokeKey(ctx, 999)
+
+	// Assert
+	require.NoError(t, rotateErr)
+	require.NoError(t, revokeErr)
+	assert.Error(t, missingErr)
+	var entries []*audit.Entry
+	require.NoError(t, store.Entries(context.Background(), 0, func(e *audit.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	require.Len(t, entries, 3)
+	for i, eventType := range []string{audit.APIKeyCreate, audit.APIKeyRotate, audit.APIKeyRevoke} {
+		assert.Equal(t, eventType, entries[i].Type)
+		assert.Equal(t, "apikey", entries[i].Resource)
+		assert.Equal(t, strconv.Itoa(key.ID), entries[i].ResourceID)
+		assert.Equal(t, "basic:ops", entries[i].Actor)
+	}
+}
@@ -0,0 +1,153 @@
+// Package audit keeps an append-only, hash-chained log of security-relevant
+// and data-changing events. Each entry carries the hash of the entry before
+// it, so editing, removing or reordering a stored entry breaks the chain from
+// that entry on, which Verify reports.
+package audit
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the PrevHash of the first entry.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Event types.
+const (
+	AuthorCreate = "author.create"
+	AuthorUpdate = "author.update"
+	AuthorDelete = "author.delete"
+	AuthorRevert = "author.revert"
+	// AuthUnauthenticated and AuthForbidden record requests rejected with
+	// 401 and 403.
+	AuthUnauthenticated = "auth.unauthenticated"
+	AuthForbidden       = "auth.forbidden"
+	// AuthLockout records a Basic username locked out after too many
+	// failed attempts.
+	AuthLockout  = "auth.lockout"
+	APIKeyCreate = "apikey.create"
+	APIKeyRotate = "apikey.rotate"
+	APIKeyRevoke = "apikey.revoke"
+	// UserLogin records a session started with a password or an external
+	// identity, and UserIdentityLink an external identity linked to an
+	// account.
+	UserLogin         = "user.login"
+	UserIdentityLink  = "user.identity_link"
+	UserPasswordReset = "user.password_reset"
+	UserSessionRevoke = "user.session_revoke"
+)
+
+// Entry is one record in the audit log.
+type Entry struct {
+	Seq  int64     `db:"seq" json:"seq" example:"42"`
+	Time time.Time `db:"time" json:"time" example:"2024-01-02T15:04:05.123456Z"`
+	Type string    `db:"type" json:"type" example:"author.update"`
+	// Actor is the method-qualified subject of the principal that caused the
+	// event, as given by auth.Principal.Owner, or empty for anonymous
+	// requests.
+	Actor      string `db:"actor" json:"actor,omitempty" example:"jwt:user-1"`
+	RequestID  string `db:"request_id" json:"request_id,omitempty" example:"5f2b8c1e9d4a7b3c6e0f1a2b3c4d5e6f"`
+	Resource   string `db:"resource" json:"resource,omitempty" example:"author"`
+	ResourceID string `db:"resource_id" json:"resource_id,omitempty" example:"1"`
+	Data       Data   `db:"data" json:"data,omitempty"`
+	// PrevHash is the Hash of the previous entry, or GenesisHash.
+	PrevHash string `db:"prev_hash" json:"prev_hash"`
+	// Hash is the hex SHA-256 of the other fields; see ComputeHash.
+	Hash string `db:"hash" json:"hash"`
+}
+
+// ComputeHash returns the hash of every field of e except Hash. The fields
+// are hashed as a JSON array, so no two different entries encode the same.
+func (e *Entry) ComputeHash() string {
+	encoded, _ := json.Marshal([]string{
+		strconv.FormatInt(e.Seq, 10),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Type,
+		e.Actor,
+		e.RequestID,
+		e.Resource,
+		e.ResourceID,
+		string(e.Data),
+		e.PrevHash,
+	})
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// seal links e to the entry before it, which is nil for the first entry, and
+// sets its hash. Stores call it while holding the lock that orders appends.
+func (e *Entry) seal(prev *Entry, at time.Time) {
+	e.Seq = 1
+	e.PrevHash = GenesisHash
+	if prev != nil {
+		e.Seq = prev.Seq + 1
+		e.PrevHash = prev.Hash
+	}
+	e.Time = at
+	e.Hash = e.ComputeHash()
+}
+
+// Data is the JSON payload of an entry. It is stored as text, byte for byte,
+// so that the hash can be recomputed from the stored entry.
+type Data []byte
+
+// NewData encodes v as a payload. A nil v gives an empty payload.
+func NewData(v interface{}) (Data, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Data) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = nil
+		return nil
+	}
+	*d = append((*d)[:0], b...)
+	return nil
+}
+
+// Scan implements sql.Scanner.
+func (d *Data) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+	case []byte:
+		*d = append(Data(nil), v...)
+	case string:
+		*d = Data(v)
+	default:
+		return fmt.Errorf("cannot scan %T as audit data", value)
+	}
+	if len(*d) == 0 {
+		*d = nil
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Data) Value() (driver.Value, error) {
+	return string(d), nil
+}
+
+// now is the clock used by the stores. Times are kept in UTC at microsecond
+// precision, like Postgres, so they hash the same after a round trip.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	logger.InitTestLogger()
+
+	// Run all tests
+	code := m.Run()
+	os.Exit(code)
+}
+
+func newSQLiteDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite", filepath.Join(t.TempDir(), "audit.db")+"?_pragma=busy_timeout(5000)")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func stores() map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			store, err := NewSQLiteStore(newSQLiteDB(t))
+			require.NoError(t, err)
+			return store
+		},
+	}
+}
+
+func entries(t *testing.T, store Store, after int64) []*Entry {
+	var all []*Entry
+	require.NoError(t, store.Entries(context.Background(), after, func(e *Entry) error {
+		all = append(all, e)
+		return nil
+	}))
+	return all
+}
+
+func TestStore_AppendChainsEntries(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			store := newStore(t)
+			ctx := context.Background()
+			data, err := NewData(map[string]string{"name": "<Ursula>"})
+			require.NoError(t, err)
+
+			// Act
+			require.NoError(t, store.Append(ctx, &Entry{Type: AuthorCreate, Actor: "jwt:user-1", Resource: "author", ResourceID: "1", Data: data}))
+			require.NoError(t, store.Append(ctx, &Entry{Type: AuthorUpdate, RequestID: "req-1"}))
+			require.NoError(t, store.Append(ctx, &Entry{Type: AuthorDelete}))
+			v, err := Verify(ctx, store)
+
+			// Assert
+			require.NoError(t, err)
+			all := entries(t, store, 0)
+			require.Len(t, all, 3)
+			assert.Equal(t, GenesisHash, all[0].PrevHash)
+			assert.Equal(t, string(data), string(all[0].Data))
+			assert.Equal(t, "jwt:user-1", all[0].Actor)
+			assert.Nil(t, all[1].Data)
+			for i, entry := range all {
+				assert.Equal(t, int64(i+1), entry.Seq)
+				assert.Equal(t, entry.ComputeHash(), entry.Hash)
+				if i > 0 {
+					assert.Equal(t, all[i-1].Hash, entry.PrevHash)
+					assert.False(t, entry.Time.Before(all[i-1].Time))
+				}
+			}
+			assert.Equal(t, int64(3), v.Count)
+			assert.Equal(t, all[2].Hash, v.Head())
+			assert.Len(t, entries(t, store, 1), 2)
+		})
+	}
+}
+
+func TestStore_ConcurrentAppendsDoNotFork(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			store := newStore(t)
+			var wg sync.WaitGroup
+
+			// Act
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, store.Append(context.Background(), &Entry{Type: AuthorUpdate}))
+				}()
+			}
+			wg.Wait()
+			v, err := Verify(context.Background(), store)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, int64(20), v.Count)
+		})
+	}
+}
+
+func TestVerify_ReportsFirstBrokenLink(t *testing.T) {
+	// Arrange
+	db := newSQLiteDB(t)
+	store, err := NewSQLiteStore(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, store.Append(ctx, &Entry{Type: AuthorUpdate, Data: Data(`{"n":1}`)}))
+	}
+
+	// Act
+	_, updateErr := db.Exec(`UPDATE audit_log SET data = '{"n":2}' WHERE seq = 2`)
+	_, deleteErr := db.Exec(`DELETE FROM audit_log WHERE seq = 3`)
+	// Tamper around the triggers, as someone with access to the file could.
+	_, err = db.Exec(`DROP TRIGGER audit_log_no_update; DROP TRIGGER audit_log_no_delete`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE audit_log SET data = '{"n":2}' WHERE seq = 2`)
+	require.NoError(t, err)
+	_, edited := Verify(ctx, store)
+	_, err = db.Exec(`UPDATE audit_log SET data = '{"n":1}' WHERE seq = 2; DELETE FROM audit_log WHERE seq = 3`)
+	require.NoError(t, err)
+	_, removed := Verify(ctx, store)
+
+	// Assert
+	assert.ErrorContains(t, updateErr, "append-only")
+	assert.ErrorContains(t, deleteErr, "append-only")
+	assert.True(t, IsBreak(edited))
+	assert.Equal(t, &Break{Seq: 2, Reason: "hash does not match the entry's contents"}, edited)
+	assert.Equal(t, &Break{Seq: 3, Reason: "expected seq 3, found seq 4"}, removed)
+}
+
+func TestExport_VerifiesAsJSONL(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	ctx := context.Background()
+	for _, actor := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Append(ctx, &Entry{Type: AuthorCreate, Actor: actor, Data: Data(`{"name":"x"}`)}))
+	}
+	var full, tail bytes.Buffer
+	require.NoError(t, Export(ctx, store, &full, 0))
+	require.NoError(t, Export(ctx, store, &tail, 1))
+
+	// Act
+	fullResult, fullErr := VerifyJSONL(bytes.NewReader(full.Bytes()))
+	_, tailErr := VerifyJSONL(bytes.NewReader(tail.Bytes()))
+	_, tamperedErr := VerifyJSONL(strings.NewReader(strings.Replace(full.String(), `"actor":"b"`, `"actor":"z"`, 1)))
+
+	// Assert
+	assert.Equal(t, 3, strings.Count(full.String(), "\n"))
+	assert.Equal(t, 2, strings.Count(tail.String(), "\n"))
+	assert.NoError(t, fullErr)
+	assert.Equal(t, int64(3), fullResult.Count)
+	assert.NoError(t, tailErr)
+	assert.Equal(t, &Break{Seq: 2, Reason: "hash does not match the entry's contents"}, tamperedErr)
+}
+
+func TestRecordAuthFailures(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	router := gin.New()
+	router.Use(middleware.RequestID(), NewLog(store).RecordAuthFailures())
+	router.GET("/unauthorized", func(c *gin.Context) { c.Status(http.StatusUnauthorized) })
+	router.GET("/forbidden", func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "user-1", Method: "jwt"})
+		c.Status(http.StatusForbidden)
+	})
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Act
+	for _, path := range []string{"/unauthorized?token=secret", "/ok", "/forbidden"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-"+strings.Trim(path, "/"))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Assert
+	all := entries(t, store, 0)
+	require.Len(t, all, 2)
+	assert.Equal(t, AuthUnauthenticated, all[0].Type)
+	assert.Equal(t, "", all[0].Actor)
+	assert.NotContains(t, string(all[0].Data), "secret")
+	assert.Contains(t, string(all[0].Data), `"path":"/unauthorized"`)
+	assert.Equal(t, AuthForbidden, all[1].Type)
+	assert.Equal(t, "jwt:user-1", all[1].Actor)
+	assert.Equal(t, "req-forbidden", all[1].RequestID)
+}
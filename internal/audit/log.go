@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+var (
+	entriesRecorded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_entries_total",
+		Help: "Audit log entries appended, by event type.",
+	}, []string{"type"})
+	appendFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_append_failures_total",
+		Help: "Audit log entries that could not be appended, by event type.",
+	}, []string{"type"})
+)
+
+// Event is something to record in the audit log.
+type Event struct {
+	Type       string
+	Resource   string
+	ResourceID string
+	// Data is encoded as JSON into the entry's payload.
+	Data interface{}
+}
+
+// Log records events on behalf of the principal and request in their
+// context.
+type Log struct {
+	store Store
+}
+
+func NewLog(store Store) *Log {
+	return &Log{store: store}
+}
+
+// Record appends event to the log. The event has already happened, so a
+// failure to record it is logged and counted in audit_append_failures_total
+// rather than returned.
+func (l *Log) Record(ctx context.Context, event Event) {
+	entry := &Entry{
+		Type:       event.Type,
+		RequestID:  middleware.RequestIDFrom(ctx),
+		Resource:   event.Resource,
+		ResourceID: event.ResourceID,
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		entry.Actor = principal.Owner()
+	}
+	data, err := NewData(event.Data)
+	if err == nil {
+		entry.Data = data
+		// The request may be cancelled once the response is written; the
+		// entry must still be appended.
+		err = l.store.Append(context.WithoutCancel(ctx), entry)
+	}
+	if err != nil {
+		appendFailures.WithLabelValues(event.Type).Inc()
+		logger.Error("failed to append audit log entry", zap.String("type", event.Type), zap.String("request_id", entry.RequestID), zap.Error(err))
+		return
+	}
+	entriesRecorded.WithLabelValues(event.Type).Inc()
+}
+
+// RecordLockout records a Basic username locked out after too many failed
+// attempts. It implements auth.LockoutRecorder.
+func (l *Log) RecordLockout(ctx context.Context, username string) {
+	l.Record(ctx, Event{Type: AuthLockout, Data: map[string]interface{}{"username": username}})
+}
+
+// RecordAuthFailures records every request answered with 401 or 403, whether
+// by the auth middleware, the authorization policy or a handler. Register it
+// after middleware.RequestID so that entries carry the request id.
+func (l *Log) RecordAuthFailures() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		var eventType string
+		switch c.Writer.Status() {
+		case http.StatusUnauthorized:
+			eventType = AuthUnauthenticated
+		case http.StatusForbidden:
+			eventType = AuthForbidden
+		default:
+			return
+		}
+		// Handlers further down have replaced c.Request with one whose
+		// context holds the principal, if any. The query string is left out
+		// as it may carry credentials.
+		l.Record(c.Request.Context(), Event{
+			Type: eventType,
+			Data: map[string]interface{}{
+				"method":    c.Request.Method,
+				"path":      c.Request.URL.Path,
+				"status":    c.Writer.Status(),
+				"client_ip": c.ClientIP(),
+			},
+		})
+	}
+}
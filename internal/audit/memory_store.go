@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewMemoryStore returns a thread-safe Store that keeps the log in process
+// memory. It is intended for local development and tests.
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+func (m *memoryStore) Append(ctx context.Context, entry *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prev *Entry
+	if len(m.entries) > 0 {
+		prev = &m.entries[len(m.entries)-1]
+	}
+	entry.seal(prev, now())
+	stored := *entry
+	stored.Data = append(Data(nil), entry.Data...)
+	m.entries = append(m.entries, stored)
+	return nil
+}
+
+func (m *memoryStore) Entries(ctx context.Context, after int64, fn func(*Entry) error) error {
+	m.mu.RLock()
+	entries := m.entries
+	m.mu.RUnlock()
+
+	// Entries are never changed once appended, so the snapshot can be read
+	// without the lock.
+	for i := range entries {
+		if entries[i].Seq <= after {
+			continue
+		}
+		entry := entries[i]
+		entry.Data = append(Data(nil), entries[i].Data...)
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteSchema is idempotent, so it is applied on every start rather than
+// tracked with PRAGMA user_version, which the author tables already use.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS audit_log (
+	seq         INTEGER   PRIMARY KEY,
+	time        TIMESTAMP NOT NULL,
+	type        TEXT      NOT NULL,
+	actor       TEXT      NOT NULL DEFAULT '',
+	request_id  TEXT      NOT NULL DEFAULT '',
+	resource    TEXT      NOT NULL DEFAULT '',
+	resource_id TEXT      NOT NULL DEFAULT '',
+	data        TEXT      NOT NULL DEFAULT '',
+	prev_hash   TEXT      NOT NULL,
+	hash        TEXT      NOT NULL
+);
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END`
+
+// sqliteTimeLayout has a fixed width so timestamps compare correctly as
+// text.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+type sqliteStore struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteStore returns a Store backed by SQLite and creates the audit_log
+// table if needed.
+func NewSQLiteStore(db *sqlx.DB) (Store, error) {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Append(ctx context.Context, entry *Entry) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// SQLite serialises writers, so the last entry cannot change before the
+	// insert commits.
+	prev, err := lastEntry(ctx, tx, "SELECT "+entryColumns+" FROM audit_log ORDER BY seq DESC LIMIT 1")
+	if err != nil {
+		return err
+	}
+	entry.seal(prev, now())
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (`+entryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Seq, entry.Time.Format(sqliteTimeLayout), entry.Type, entry.Actor, entry.RequestID, entry.Resource,
+		entry.ResourceID, entry.Data, entry.PrevHash, entry.Hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Entries(ctx context.Context, after int64, fn func(*Entry) error) error {
+	return eachEntry(ctx, s.db, fn, "SELECT "+entryColumns+" FROM audit_log WHERE seq > ? ORDER BY seq", after)
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+// Store persists the audit log. Stores only append: there is no way to change
+// or remove an entry through them.
+type Store interface {
+	// Append assigns entry the next sequence number, the current time and
+	// the hash of the last entry, sets its hash and stores it. Concurrent
+	// appends are ordered so that the chain never forks.
+	Append(ctx context.Context, entry *Entry) error
+	// Entries calls fn with each entry whose Seq is greater than after, in
+	// sequence order, and stops at the first error fn returns. fn must not
+	// use the store.
+	Entries(ctx context.Context, after int64, fn func(*Entry) error) error
+}
+
+// entryColumns are selected for every entry read.
+const entryColumns = `seq, time, type, actor, request_id, resource, resource_id, data, prev_hash, hash`
+
+type postgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore returns a Store backed by the audit_log table, which a
+// trigger keeps append-only.
+func NewPostgresStore(db *sqlx.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// NewStore returns the Store implementation for the configured database
+// driver. db is unused, and may be nil, for the memory driver.
+func NewStore(driver string, db *sqlx.DB) (Store, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewPostgresStore(db), nil
+	case database.DriverSQLite:
+		return NewSQLiteStore(db)
+	case database.DriverMemory:
+		logger.Warning("the audit log is kept in memory", zap.String("driver", driver))
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func (s *postgresStore) Append(ctx context.Context, entry *Entry) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// EXCLUSIVE mode orders appends but still lets readers through.
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE audit_log IN EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+	prev, err := lastEntry(ctx, tx, "SELECT "+entryColumns+" FROM audit_log ORDER BY seq DESC LIMIT 1")
+	if err != nil {
+		return err
+	}
+	entry.seal(prev, now())
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (`+entryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		entry.Seq, entry.Time, entry.Type, entry.Actor, entry.RequestID, entry.Resource, entry.ResourceID,
+		entry.Data, entry.PrevHash, entry.Hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *postgresStore) Entries(ctx context.Context, after int64, fn func(*Entry) error) error {
+	return eachEntry(ctx, s.db, fn, "SELECT "+entryColumns+" FROM audit_log WHERE seq > $1 ORDER BY seq", after)
+}
+
+// lastEntry returns the entry query selects, or nil if the log is empty.
+func lastEntry(ctx context.Context, tx *sqlx.Tx, query string) (*Entry, error) {
+	var prev Entry
+	err := tx.GetContext(ctx, &prev, query)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+// eachEntry streams the entries query selects to fn.
+func eachEntry(ctx context.Context, db *sqlx.DB, fn func(*Entry) error, query string, args ...interface{}) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry Entry
+		if err := rows.StructScan(&entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
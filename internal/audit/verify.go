@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Break describes the first entry at which the chain does not hold.
+type Break struct {
+	Seq    int64
+	Reason string
+}
+
+func (b *Break) Error() string {
+	return fmt.Sprintf("audit log broken at seq %d: %s", b.Seq, b.Reason)
+}
+
+// Verifier checks entries one at a time, in order, against the entries
+// before them.
+type Verifier struct {
+	prev *Entry
+	// Count is the number of entries checked so far.
+	Count int64
+}
+
+// Head returns the hash of the last entry checked, or GenesisHash. Keeping
+// the head somewhere outside the database lets a later Verify also detect
+// entries removed from the end of the log.
+func (v *Verifier) Head() string {
+	if v.prev == nil {
+		return GenesisHash
+	}
+	return v.prev.Hash
+}
+
+// Check returns a *Break if entry does not follow the entries checked before
+// it. The first entry checked must be seq 1, unless Resume was called.
+func (v *Verifier) Check(entry *Entry) error {
+	wantSeq, wantPrev := int64(1), GenesisHash
+	if v.prev != nil {
+		wantSeq, wantPrev = v.prev.Seq+1, v.prev.Hash
+	}
+	switch {
+	case entry.Seq != wantSeq:
+		return &Break{Seq: wantSeq, Reason: fmt.Sprintf("expected seq %d, found seq %d", wantSeq, entry.Seq)}
+	case wantPrev != "" && entry.PrevHash != wantPrev:
+		return &Break{Seq: entry.Seq, Reason: "prev_hash does not match the hash of the previous entry"}
+	case entry.ComputeHash() != entry.Hash:
+		return &Break{Seq: entry.Seq, Reason: "hash does not match the entry's contents"}
+	}
+	prev := *entry
+	v.prev = &prev
+	v.Count++
+	return nil
+}
+
+// Resume makes the next entry checked follow seq, with its PrevHash taken on
+// trust, for checking an export that does not start at seq 1.
+func (v *Verifier) Resume(seq int64) {
+	v.prev = &Entry{Seq: seq}
+}
+
+// Verify checks every entry in store. It returns the Verifier, whose Count
+// and Head describe the entries checked, and a *Break for the first broken
+// link.
+func Verify(ctx context.Context, store Store) (*Verifier, error) {
+	v := &Verifier{}
+	return v, store.Entries(ctx, 0, v.Check)
+}
+
+// VerifyJSONL checks an export written by Export. An export that starts
+// after seq 1 is checked from its first entry on.
+func VerifyJSONL(r io.Reader) (*Verifier, error) {
+	v := &Verifier{}
+	decoder := json.NewDecoder(r)
+	for {
+		var entry Entry
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return v, nil
+		}
+		if err != nil {
+			return v, fmt.Errorf("entry %d: %w", v.Count+1, err)
+		}
+		if v.Count == 0 && entry.Seq > 1 {
+			v.Resume(entry.Seq - 1)
+		}
+		if err := v.Check(&entry); err != nil {
+			return v, err
+		}
+	}
+}
+
+// Export writes the entries after seq after to w as JSON Lines, one entry
+// per line in sequence order.
+func Export(ctx context.Context, store Store, w io.Writer, after int64) error {
+	encoder := json.NewEncoder(w)
+	return store.Entries(ctx, after, func(entry *Entry) error {
+		return encoder.Encode(entry)
+	})
+}
+
+// IsBreak reports whether err is a *Break, as opposed to a failure to read
+// the log.
+func IsBreak(err error) bool {
+	var b *Break
+	return errors.As(err, &b)
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid username or password")
+	errAccountLocked      = errors.New("too many failed attempts, try again later")
+)
+
+// dummyHash is verified for unknown users so that response times do not
+// reveal which usernames exist.
+var dummyHash, _ = HashPassword("dummy password")
+
+// LockoutPolicy locks a username after MaxFailures consecutive failed
+// attempts for Duration. Failures are forgotten once a username has had none
+// for Duration. A zero MaxFailures disables lockout.
+type LockoutPolicy struct {
+	MaxFailures int
+	Duration    time.Duration
+	// Recorder, if set, is told about every lockout.
+	Recorder LockoutRecorder
+}
+
+// LockoutRecorder records usernames locked out by a BasicAuthenticator, e.g.
+// in the audit log.
+type LockoutRecorder interface {
+	RecordLockout(ctx context.Context, username string)
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// BasicAuthenticator authenticates "Authorization: Basic" requests against a
+// CredentialStore.
+type BasicAuthenticator struct {
+	store   CredentialStore
+	lockout LockoutPolicy
+	now     func() time.Time
+
+	mu sync.Mutex
+	// attempts holds only usernames in the store, so that made-up usernames
+	// cannot grow it.
+	attempts map[string]*loginAttempts
+}
+
+// NewBasicAuthenticator creates a BasicAuthenticator for store.
+func NewBasicAuthenticator(store CredentialStore, lockout LockoutPolicy) *BasicAuthenticator {
+	return &BasicAuthenticator{
+		store:    store,
+		lockout:  lockout,
+		now:      time.Now,
+		attempts: make(map[string]*loginAttempts),
+	}
+}
+
+func (b *BasicAuthenticator) Scheme() string {
+	return `Basic realm="go-init-template"`
+}
+
+func (b *BasicAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	if b.locked(username) {
+		return nil, errAccountLocked
+	}
+
+	credential, err := b.store.Lookup(username)
+	if err != nil && !errors.Is(err, ErrUnknownUser) {
+		return nil, err
+	}
+
+	hash := dummyHash
+	if credential != nil {
+		hash = credential.PasswordHash
+	}
+	match, err := VerifyPassword(hash, password)
+	if err != nil {
+		logger.Warning("basic auth: cannot verify password hash", zap.String("username", username), zap.Error(err))
+	}
+	if credential == nil || !match {
+		if credential != nil && b.recordFailure(username) && b.lockout.Recorder != nil {
+			b.lockout.Recorder.RecordLockout(c.Request.Context(), username)
+		}
+		return nil, errInvalidCredentials
+	}
+
+	b.recordSuccess(username)
+	return &Principal{Subject: credential.Username, Method: "basic", Roles: credential.Roles}, nil
+}
+
+func (b *BasicAuthenticator) locked(username string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	attempts, ok := b.attempts[username]
+	return ok && b.now().Before(attempts.lockedUntil)
+}
+
+// recordFailure counts a failed attempt and reports whether it locked the
+// username out.
+func (b *BasicAuthenticator) recordFailure(username string) bool {
+	if b.lockout.MaxFailures <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+	attempts, ok := b.attempts[username]
+	if !ok {
+		attempts = &loginAttempts{}
+		b.attempts[username] = attempts
+	}
+	attempts.failures++
+	attempts.lastFailure = now
+	if attempts.failures >= b.lockout.MaxFailures {
+		attempts.failures = 0
+		attempts.lockedUntil = now.Add(b.lockout.Duration)
+		logger.Warning("basic auth: user locked out", zap.String("username", username))
+		return true
+	}
+	return false
+}
+
+// sweep forgets the usernames that are not locked out and have had no
+// failure for the lockout duration. b.mu must be held.
+func (b *BasicAuthenticator) sweep(now time.Time) {
+	for username, attempts := range b.attempts {
+		if !now.Before(attempts.lockedUntil) && now.Sub(attempts.lastFailure) >= b.lockout.Duration {
+			delete(b.attempts, username)
+		}
+	}
+}
+
+func (b *BasicAuthenticator) recordSuccess(username string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.attempts, username)
+}
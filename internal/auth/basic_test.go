@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBasicAuthenticator(t *testing.T) *BasicAuthenticator {
+	hash, err := HashPassword("s3cret")
+	require.NoError(t, err)
+	store := NewConfigCredentialStore([]config.BasicAuthUser{
+		{Username: "admin", PasswordHash: hash, Roles: []string{"admin"}},
+	})
+	return NewBasicAuthenticator(store, LockoutPolicy{MaxFailures: 3, Duration: time.Minute})
+}
+
+func basicAuthenticate(b *BasicAuthenticator, username, password string) (*Principal, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.SetBasicAuth(username, password)
+	return b.Authenticate(c)
+}
+
+func TestBasicAuthenticator_ValidCredentials(t *testing.T) {
+	// Arrange
+	authenticator := newTestBasicAuthenticator(t)
+
+	// Act
+	principal, err := basicAuthenticate(authenticator, "admin", "s3cret")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "admin", Method: "basic", Roles: []string{"admin"}}, principal)
+}
+
+func TestBasicAuthenticator_InvalidCredentials(t *testing.T) {
+	// Arrange
+	authenticator := newTestBasicAuthenticator(t)
+
+	// Act
+	_, wrongPassword := basicAuthenticate(authenticator, "admin", "wrong")
+	_, unknownUser := basicAuthenticate(authenticator, "nobody", "s3cret")
+
+	// Assert
+	assert.Equal(t, errInvalidCredentials, wrongPassword)
+	assert.Equal(t, errInvalidCredentials, unknownUser)
+}
+
+func TestBasicAuthenticator_NoCredentials(t *testing.T) {
+	// Arrange
+	authenticator := newTestBasicAuthenticator(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	// Act
+	_, err := authenticator.Authenticate(c)
+
+	// Assert
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestBasicAuthenticator_LocksOutAfterRepeatedFailures(t *testing.T) {
+	// Arrange
+	authenticator := newTestBasicAuthenticator(t)
+	recorder := &lockoutRecorder{}
+	authenticator.lockout.Recorder = recorder
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		_, _ = basicAuthenticate(authenticator, "admin", "wrong")
+	}
+
+	// Act
+	_, locked := basicAuthenticate(authenticator, "admin", "s3cret")
+	authenticator.now = func() time.Time { return now.Add(2 * time.Minute) }
+	_, unlocked := basicAuthenticate(authenticator, "admin", "s3cret")
+
+	// Assert
+	assert.Equal(t, errAccountLocked, locked)
+	assert.NoError(t, unlocked)
+	assert.Equal(t, []string{"admin"}, recorder.usernames)
+}
+
+func TestBasicAuthenticator_TracksOnlyRecentFailuresOfKnownUsers(t *testing.T) {
+	// Arrange
+	authenticator := newTestBasicAuthenticator(t)
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		_, _ = basicAuthenticate(authenticator, "nobody", "wrong")
+	}
+	_, _ = basicAuthenticate(authenticator, "admin", "wrong")
+	_, _ = basicAuthenticate(authenticator, "admin", "wrong")
+
+	// Act
+	unknown := len(authenticator.attempts)
+	authenticator.now = func() time.Time { return now.Add(2 * time.Minute) }
+	_, _ = basicAuthenticate(authenticator, "admin", "wrong")
+	_, afterQuietPeriod := basicAuthenticate(authenticator, "admin", "s3cret")
+
+	// Assert: unknown usernames are not tracked, and failures older than the
+	// lockout duration no longer count.
+	assert.Equal(t, 1, unknown)
+	assert.NoError(t, afterQuietPeriod)
+}
+
+type lockoutRecorder struct {
+	usernames []string
+}
+
+func (r *lockoutRecorder) RecordLockout(ctx context.Context, username string) {
+	r.usernames = append(r.usernames, username)
+}
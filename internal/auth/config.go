@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Supported values for the auth.basic.source config key.
+const (
+	CredentialSourceConfig   = "config"
+	CredentialSourceDatabase = "database"
+)
+
+// NewAuthenticationFromConfig builds the authenticators enabled in config.
+// db is only used when Basic auth credentials are stored in the database, and
+// lockouts, which may be nil, is told about Basic lockouts. Client certificate authentication goes first, since it cannot be spoofed by
+// request headers.
+func NewAuthenticationFromConfig(config *config.Config, db *sqlx.DB, lockouts LockoutRecorder) (*Authentication, error) {
+	authn := NewAuthentication()
+
+	if config.Auth.MTLS.Enabled {
+		if config.App.TLS.ClientCAFile == "" {
+			return nil, fmt.Errorf("mtls auth needs app.tls.clientcafile")
+		}
+		authn.Add(NewClientCertAuthenticator())
+	}
+
+	if jwtConfig := config.Auth.JWT; jwtConfig.Enabled {
+		keys, err := NewKeySet(jwtConfig.JWKS, jwtConfig.RefreshInterval)
+		if err != nil {
+			return nil, err
+		}
+		authn.Add(NewJWTAuthenticator(keys, JWTOptions{
+			Issuer:    jwtConfig.Issuer,
+			Audience:  jwtConfig.Audience,
+			ClockSkew: jwtConfig.ClockSkew,
+		}))
+	}
+
+	if basicConfig := config.Auth.Basic; basicConfig.Enabled {
+		var store CredentialStore
+		switch basicConfig.Source {
+		case CredentialSourceConfig:
+			store = NewConfigCredentialStore(basicConfig.Users)
+		case CredentialSourceDatabase:
+			if db == nil {
+				return nil, fmt.Errorf("basic auth source %q needs a database connection", basicConfig.Source)
+			}
+			store = NewDBCredentialStore(db)
+		default:
+			return nil, fmt.Errorf("unsupported basic auth source %q", basicConfig.Source)
+		}
+		authn.Add(NewBasicAuthenticator(store, LockoutPolicy{
+			MaxFailures: basicConfig.MaxFailures,
+			Duration:    basicConfig.LockoutDuration,
+			Recorder:    lockouts,
+		}))
+	}
+
+	return authn, nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// ErrUnknownUser is returned by a CredentialStore for usernames it does not hold.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Credential is a username with its password hash and granted roles.
+type Credential struct {
+	Username     string         `db:"username"`
+	PasswordHash string         `db:"password_hash"`
+	Roles        pq.StringArray `db:"roles"`
+}
+
+// CredentialStore looks up Basic auth credentials by username.
+type CredentialStore interface {
+	Lookup(username string) (*Credential, error)
+}
+
+type configCredentialStore struct {
+	credentials map[string]*Credential
+}
+
+// NewConfigCredentialStore returns a CredentialStore holding the users listed
+// under auth.basic.users in config.
+func NewConfigCredentialStore(users []config.BasicAuthUser) CredentialStore {
+	credentials := make(map[string]*Credential, len(users))
+	for _, user := range users {
+		credentials[user.Username] = &Credential{
+			Username:     user.Username,
+			PasswordHash: user.PasswordHash,
+			Roles:        user.Roles,
+		}
+	}
+	return &configCredentialStore{credentials: credentials}
+}
+
+func (s *configCredentialStore) Lookup(username string) (*Credential, error) {
+	credential, ok := s.credentials[username]
+	if !ok {
+		return nil, ErrUnknownUser
+	}
+	return credential, nil
+}
+
+type dbCredentialStore struct {
+	db *sqlx.DB
+}
+
+// NewDBCredentialStore returns a CredentialStore backed by the
+// basic_auth_credentials table.
+func NewDBCredentialStore(db *sqlx.DB) CredentialStore {
+	return &dbCredentialStore{db: db}
+}
+
+func (s *dbCredentialStore) Lookup(username string) (*Credential, error) {
+	var credential Credential
+	err := s.db.Get(&credential, "SELECT username, password_hash, roles FROM basic_auth_credentials WHERE username = $1", username)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
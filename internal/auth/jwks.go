@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+// minRefreshInterval limits how often an unknown key id can force a refresh,
+// so tokens with random kids cannot be used to hammer the JWKS source.
+const minRefreshInterval = 30 * time.Second
+
+// jsonWebKey is a single key of a JSON Web Key Set (RFC 7517).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey is a parsed key together with the algorithm family it may
+// be used with.
+type verificationKey struct {
+	kty string
+	key interface{}
+}
+
+// KeySet is a JSON Web Key Set loaded from a local file or a URL. It is
+// reloaded every refreshInterval, and early when a token names an unknown key
+// id, so rotated keys are picked up without a restart.
+type KeySet struct {
+	source          string
+	refreshInterval time.Duration
+	client          *http.Client
+	now             func() time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]verificationKey
+	fetchedAt time.Time
+
+	refreshMu sync.Mutex
+}
+
+// NewKeySet loads the key set from source, which is either a file path or an
+// http(s) URL.
+func NewKeySet(source string, refreshInterval time.Duration) (*KeySet, error) {
+	ks := &KeySet{
+		source:          source,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		now:             time.Now,
+	}
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Key returns the key with the given id. An empty kid matches the only key of
+// a single-key set.
+func (ks *KeySet) Key(kid string) (verificationKey, error) {
+	if ks.stale(ks.refreshInterval) {
+		ks.refreshOrLog()
+	}
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	// The signer may have rotated to a key we have not seen yet.
+	if ks.stale(minRefreshInterval) {
+		ks.refreshOrLog()
+		if key, ok := ks.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (ks *KeySet) lookup(kid string) (verificationKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (ks *KeySet) stale(maxAge time.Duration) bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return maxAge > 0 && ks.now().Sub(ks.fetchedAt) >= maxAge
+}
+
+// refreshOrLog refreshes the key set, keeping the current keys on failure.
+func (ks *KeySet) refreshOrLog() {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+	// Another request may have refreshed while we waited for the lock.
+	if !ks.stale(minRefreshInterval) {
+		return
+	}
+	if err := ks.refresh(); err != nil {
+		logger.Warning("JWKS refresh failed, keeping previous keys", zap.Error(err))
+		ks.mu.Lock()
+		ks.fetchedAt = ks.now()
+		ks.mu.Unlock()
+	}
+}
+
+func (ks *KeySet) refresh() error {
+	raw, err := ks.read()
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS from %s: %v", ks.source, err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			return fmt.Errorf("failed to parse JWKS key %q: %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetchedAt = ks.now()
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeySet) read() ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+
+	resp, err := ks.client.Get(ks.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func parseJSONWebKey(jwk jsonWebKey) (verificationKey, error) {
+	switch jwk.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{kty: jwk.Kty, key: secret}, nil
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{kty: jwk.Kty, key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return verificationKey{}, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return verificationKey{}, errors.New("point is not on curve P-256")
+		}
+		return verificationKey{kty: jwk.Kty, key: key}, nil
+	}
+	return verificationKey{}, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
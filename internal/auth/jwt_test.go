@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func b64(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// writeJWKS writes the given JWKs to a file and returns its path.
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) string {
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "jwks.json")
+	}
+	raw, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw, 0600))
+	return path
+}
+
+func octJWK(kid string, secret []byte) map[string]string {
+	return map[string]string{"kty": "oct", "kid": kid, "k": b64(secret)}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes())}
+}
+
+func validClaims() Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    "https://issuer.test",
+			Audience:  jwt.ClaimStrings{"authors-api"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: []string{"editor"},
+		Scope: "authors:read authors:write",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func authenticate(t *testing.T, authenticator Authenticator, header string) (*Principal, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	if header != "" {
+		c.Request.Header.Set("Authorization", header)
+	}
+	return authenticator.Authenticate(c)
+}
+
+func newTestJWTAuthenticator(t *testing.T, path string) *JWTAuthenticator {
+	keys, err := NewKeySet(path, time.Hour)
+	require.NoError(t, err)
+	return NewJWTAuthenticator(keys, JWTOptions{Issuer: "https://issuer.test", Audience: "authors-api", ClockSkew: 30 * time.Second})
+}
+
+func TestJWTAuthenticator_SupportedAlgorithms(t *testing.T) {
+	// Arrange
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	path := writeJWKS(t, "", octJWK("hs", hmacSecret), rsaJWK("rs", &rsaKey.PublicKey), ecJWK("es", &ecKey.PublicKey))
+	authenticator := newTestJWTAuthenticator(t, path)
+
+	tokens := map[string]string{
+		"HS256": sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, validClaims()),
+		"RS256": sign(t, jwt.SigningMethodRS256, "rs", rsaKey, validClaims()),
+		"ES256": sign(t, jwt.SigningMethodES256, "es", ecKey, validClaims()),
+	}
+
+	for alg, token := range tokens {
+		// Act
+		principal, err := authenticate(t, authenticator, "Bearer "+token)
+
+		// Assert
+		require.NoError(t, err, alg)
+		assert.Equal(t, &Principal{
+			Subject: "user-1",
+			Method:  "jwt",
+			Roles:   []string{"editor"},
+			Scopes:  []string{"authors:read", "authors:write"},
+		}, principal, alg)
+	}
+}
+
+func TestJWTAuthenticator_NoBearerToken(t *testing.T) {
+	// Arrange
+	authenticator := newTestJWTAuthenticator(t, writeJWKS(t, "", octJWK("hs", hmacSecret)))
+
+	// Act
+	_, missing := authenticate(t, authenticator, "")
+	_, basic := authenticate(t, authenticator, "Basic dXNlcjpwYXNz")
+
+	// Assert
+	assert.Equal(t, ErrNoCredentials, missing)
+	assert.Equal(t, ErrNoCredentials, basic)
+}
+
+func TestJWTAuthenticator_RejectsInvalidClaims(t *testing.T) {
+	// Arrange
+	authenticator := newTestJWTAuthenticator(t, writeJWKS(t, "", octJWK("hs", hmacSecret)))
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"other-api"}
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.test"
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+
+	for name, claims := range map[string]Claims{
+		"audience": wrongAudience,
+		"issuer":   wrongIssuer,
+		"expired":  expired,
+		"noExpiry": noExpiry,
+	} {
+		// Act
+		_, err := authenticate(t, authenticator, "Bearer "+sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claims))
+
+		// Assert
+		assert.Error(t, err, name)
+		assert.NotEqual(t, ErrNoCredentials, err, name)
+	}
+}
+
+func TestJWTAuthenticator_AllowsClockSkew(t *testing.T) {
+	// Arrange
+	authenticator := newTestJWTAuthenticator(t, writeJWKS(t, "", octJWK("hs", hmacSecret)))
+	claims := validClaims()
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+
+	// Act
+	_, err := authenticate(t, authenticator, "Bearer "+sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claims))
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestJWTAuthenticator_RejectsAlgorithmKeyMismatch(t *testing.T) {
+	// Arrange: an HS256 token signed with bytes that happen to be the id of an RSA key.
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	authenticator := newTestJWTAuthenticator(t, writeJWKS(t, "", rsaJWK("rs", &rsaKey.PublicKey)))
+
+	// Act
+	_, err = authenticate(t, authenticator, "Bearer "+sign(t, jwt.SigningMethodHS256, "rs", hmacSecret, validClaims()))
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestKeySet_PicksUpRotatedKeys(t *testing.T) {
+	// Arrange
+	path := writeJWKS(t, "", octJWK("old", hmacSecret))
+	authenticator := newTestJWTAuthenticator(t, path)
+	newSecret := []byte("fedcba9876543210fedcba9876543210")
+	writeJWKS(t, path, octJWK("old", hmacSecret), octJWK("new", newSecret))
+	token := "Bearer " + sign(t, jwt.SigningMethodHS256, "new", newSecret, validClaims())
+
+	// Act: the unknown kid forces a refresh once minRefreshInterval has passed.
+	_, beforeRefresh := authenticate(t, authenticator, token)
+	authenticator.keys.now = func() time.Time { return time.Now().Add(time.Minute) }
+	_, afterRefresh := authenticate(t, authenticator, token)
+
+	// Assert
+	assert.Error(t, beforeRefresh)
+	assert.NoError(t, afterRefresh)
+}
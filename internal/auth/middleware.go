@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no
+// credentials for its scheme, so the next authenticator should be tried.
+var ErrNoCredentials = errors.New("authentication required")
+
+// Authenticator verifies the credentials of one authentication scheme.
+type Authenticator interface {
+	// Authenticate returns the caller's principal, ErrNoCredentials if the
+	// request has no credentials for this scheme, or any other error if the
+	// credentials are present but invalid.
+	Authenticate(c *gin.Context) (*Principal, error)
+	// Scheme is the value sent in the WWW-Authenticate header, e.g. "Bearer",
+	// or empty if the scheme has no challenge.
+	Scheme() string
+}
+
+// Authentication holds the configured authenticators and builds middleware
+// that route groups use to declare whether authentication is required.
+type Authentication struct {
+	authenticators []Authenticator
+}
+
+// NewAuthentication creates an Authentication that tries authenticators in order.
+func NewAuthentication(authenticators ...Authenticator) *Authentication {
+	return &Authentication{authenticators: authenticators}
+}
+
+// Add appends an authenticator to the chain.
+func (a *Authentication) Add(authenticator Authenticator) {
+	a.authenticators = append(a.authenticators, authenticator)
+}
+
+// Required rejects requests without valid credentials with 401.
+func (a *Authentication) Required() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.authenticate(c) {
+			return
+		}
+		if _, ok := PrincipalFrom(c); !ok {
+			a.unauthorized(c, ErrNoCredentials)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Optional authenticates requests that carry credentials and lets anonymous
+// requests through. Invalid credentials are still rejected with 401.
+func (a *Authentication) Optional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.authenticate(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticationContextKey holds the Authentication that handled the request,
+// so later middleware can send its challenges with Unauthorized.
+const authenticationContextKey = "auth.authentication"
+
+// authenticationErrorContextKey holds the error of credentials that Identify
+// rejected, so that they are neither checked nor counted as failures twice.
+const authenticationErrorContextKey = "auth.error"
+
+// Identify runs the authenticators until one recognises the request's
+// credentials, without rejecting the request. It returns the principal, nil
+// for anonymous requests, or the error of invalid credentials. Middleware
+// that runs ahead of Optional and Required, such as rate limiting, uses it to
+// tell callers apart; they then reject invalid credentials as usual.
+func (a *Authentication) Identify(c *gin.Context) (*Principal, error) {
+	if principal, ok := PrincipalFrom(c); ok {
+		return principal, nil
+	}
+	if value, ok := c.Get(authenticationErrorContextKey); ok {
+		return nil, value.(error)
+	}
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			c.Set(authenticationErrorContextKey, err)
+			return nil, err
+		}
+		SetPrincipal(c, principal)
+		return principal, nil
+	}
+	return nil, nil
+}
+
+// authenticate identifies the caller and rejects invalid credentials. It
+// returns false if the request was aborted.
+func (a *Authentication) authenticate(c *gin.Context) bool {
+	c.Set(authenticationContextKey, a)
+	if _, err := a.Identify(c); err != nil {
+		a.unauthorized(c, err)
+		return false
+	}
+	return true
+}
+
+func (a *Authentication) unauthorized(c *gin.Context, err error) {
+	for _, authenticator := range a.authenticators {
+		if scheme := authenticator.Scheme(); scheme != "" {
+			c.Writer.Header().Add("WWW-Authenticate", scheme)
+		}
+	}
+	httputil.NewError(c, http.StatusUnauthorized, err)
+	c.Abort()
+}
+
+// Unauthorized aborts the request with 401 and the WWW-Authenticate challenges
+// of the Authentication middleware that ran for it, if any.
+func Unauthorized(c *gin.Context, err error) {
+	if value, ok := c.Get(authenticationContextKey); ok {
+		value.(*Authentication).unauthorized(c, err)
+		return
+	}
+	httputil.NewError(c, http.StatusUnauthorized, err)
+	c.Abort()
+}
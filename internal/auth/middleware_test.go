@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAuthenticator struct {
+	principal *Principal
+	err       error
+}
+
+func (s stubAuthenticator) Scheme() string { return "Stub" }
+
+func (s stubAuthenticator) Authenticate(*gin.Context) (*Principal, error) {
+	return s.principal, s.err
+}
+
+func serve(middleware gin.HandlerFunc) (*httptest.ResponseRecorder, *Principal) {
+	var seen *Principal
+	router := gin.New()
+	router.GET("/", middleware, func(c *gin.Context) {
+		seen, _ = FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestAuthentication_RequiredWithoutCredentials(t *testing.T) {
+	// Arrange
+	authn := NewAuthentication(stubAuthenticator{err: ErrNoCredentials})
+
+	// Act
+	w, _ := serve(authn.Required())
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Stub", w.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthentication_OptionalWithoutCredentials(t *testing.T) {
+	// Arrange
+	authn := NewAuthentication(stubAuthenticator{err: ErrNoCredentials})
+
+	// Act
+	w, principal := serve(authn.Optional())
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, principal)
+}
+
+func TestAuthentication_InvalidCredentialsAlwaysRejected(t *testing.T) {
+	// Arrange
+	authn := NewAuthentication(stubAuthenticator{err: errors.New("invalid token")})
+
+	// Act
+	w, _ := serve(authn.Optional())
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthentication_TriesAuthenticatorsInOrder(t *testing.T) {
+	// Arrange
+	expected := &Principal{Subject: "user-1", Method: "stub"}
+	authn := NewAuthentication(
+		stubAuthenticator{err: ErrNoCredentials},
+		stubAuthenticator{principal: expected},
+	)
+
+	// Act
+	w, principal := serve(authn.Required())
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expected, principal)
+}
+
+type countingAuthenticator struct {
+	calls *int
+}
+
+func (countingAuthenticator) Scheme() string { return "Counting" }
+
+func (a countingAuthenticator) Authenticate(*gin.Context) (*Principal, error) {
+	*a.calls++
+	return nil, errors.New("invalid password")
+}
+
+func TestAuthentication_IdentifyChecksCredentialsOnce(t *testing.T) {
+	// Arrange
+	calls := 0
+	authn := NewAuthentication(countingAuthenticator{calls: &calls})
+	var identifyErr error
+	identify := func(c *gin.Context) {
+		_, identifyErr = authn.Identify(c)
+		c.Next()
+	}
+	router := gin.New()
+	router.GET("/", identify, authn.Optional(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.EqualError(t, identifyErr, "invalid password")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Counting", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCertAuthenticator authenticates requests by the client certificate
+// verified during the TLS handshake. The subject common name (or the full
+// distinguished name if it has none) becomes the principal's subject and the
+// organizational units become its roles.
+type ClientCertAuthenticator struct{}
+
+// NewClientCertAuthenticator creates a ClientCertAuthenticator.
+func NewClientCertAuthenticator() *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{}
+}
+
+// Scheme is empty: client certificates are negotiated by TLS, not requested
+// with a WWW-Authenticate challenge.
+func (a *ClientCertAuthenticator) Scheme() string {
+	return ""
+}
+
+func (a *ClientCertAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	state := c.Request.TLS
+	// Only trust chains verified against the client CA pool; PeerCertificates
+	// alone may hold an unverified certificate.
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := state.VerifiedChains[0][0]
+
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+	return &Principal{
+		Subject:      subject,
+		Method:       "mtls",
+		CredentialID: cert.SerialNumber.String(),
+		Roles:        cert.Subject.OrganizationalUnit,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCertAuthenticator(t *testing.T) {
+	// Arrange
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "catalog-sync", OrganizationalUnit: []string{"editor"}},
+	}
+	authenticator := NewClientCertAuthenticator()
+	newContext := func(state *tls.ConnectionState) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.TLS = state
+		return c
+	}
+
+	// Act
+	principal, err := authenticator.Authenticate(newContext(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}))
+	_, plainErr := authenticator.Authenticate(newContext(nil))
+	_, unverifiedErr := authenticator.Authenticate(newContext(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "catalog-sync", Method: "mtls", CredentialID: "42", Roles: []string{"editor"}}, principal)
+	assert.ErrorIs(t, plainErr, ErrNoCredentials)
+	assert.ErrorIs(t, unverifiedErr, ErrNoCredentials)
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword_RoundTrip(t *testing.T) {
+	// Arrange
+	hash, err := HashPassword("s3cret")
+	require.NoError(t, err)
+
+	// Act
+	match, matchErr := VerifyPassword(hash, "s3cret")
+	mismatch, mismatchErr := VerifyPassword(hash, "wrong")
+
+	// Assert
+	assert.NoError(t, matchErr)
+	assert.NoError(t, mismatchErr)
+	assert.True(t, match)
+	assert.False(t, mismatch)
+}
+
+func TestVerifyPassword_Bcrypt(t *testing.T) {
+	// Arrange
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// Act
+	match, _ := VerifyPassword(string(hash), "s3cret")
+	mismatch, _ := VerifyPassword(string(hash), "wrong")
+
+	// Assert
+	assert.True(t, match)
+	assert.False(t, mismatch)
+}
+
+func TestVerifyPassword_UnsupportedHash(t *testing.T) {
+	// Act
+	match, err := VerifyPassword("plaintext", "plaintext")
+
+	// Assert
+	assert.False(t, match)
+	assert.Equal(t, ErrUnsupportedHash, err)
+}
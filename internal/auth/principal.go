@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject uniquely identifies the caller, e.g. a user id or key id.
+	Subject string
+	// Method is the scheme that authenticated the caller, e.g. "jwt".
+	Method string
+	// CredentialID identifies the credential used when a subject can hold
+	// several, e.g. an API key prefix.
+	CredentialID string
+	Roles        []string
+	Scopes       []string
+}
+
+type principalKey struct{}
+
+// principalContextKey is the gin context key holding the *Principal.
+const principalContextKey = "auth.principal"
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// SetPrincipal attaches p to both the gin context and the request context, so
+// that layers below the handler can read it with FromContext.
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalContextKey, p)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+}
+
+// Owner returns the subject qualified by the method that authenticated it,
+// e.g. "basic:alice". Subjects are only unique within a method: a Basic
+// username, an API key owner and a certificate CN may all be "alice".
+func (p *Principal) Owner() string {
+	return p.Method + ":" + p.Subject
+}
+
+// ClientKey identifies the caller of a request: by credential when the
+// subject can hold several, e.g. an API key, then by method-qualified subject,
+// then by client IP for anonymous requests. kind names which of them key is,
+// e.g. for metric labels.
+func ClientKey(c *gin.Context, principal *Principal) (kind, key string) {
+	if principal != nil {
+		if principal.CredentialID != "" {
+			return principal.Method, principal.Method + ":" + principal.CredentialID
+		}
+		return "user", "user:" + principal.Owner()
+	}
+	return "ip", "ip:" + c.ClientIP()
+}
+
+// PrincipalFrom returns the principal authenticated for the request, if any.
+func PrincipalFrom(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalContextKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := value.(*Principal)
+	return p, ok
+}
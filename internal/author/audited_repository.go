@@ -0,0 +1,119 @@
+package author
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+)
+
+// auditResource names authors in audit log entries.
+const auditResource = "author"
+
+// auditedAuthorRepository records every successful write in the audit log.
+type auditedAuthorRepository struct {
+	AuthorRepository
+	log *audit.Log
+}
+
+// NewAuditedAuthorRepository wraps repo so that creates, updates, deletes and
+// reverts are recorded in log once they succeed. The revisions keep the
+// changed fields; the audit entries record who changed what and when.
+func NewAuditedAuthorRepository(repo AuthorRepository, log *audit.Log) AuthorRepository {
+	return &auditedAuthorRepository{AuthorRepository: repo, log: log}
+}
+
+func (a *auditedAuthorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	if err := a.AuthorRepository.CreateAuthor(ctx, author); err != nil {
+		return err
+	}
+	a.record(ctx, audit.AuthorCreate, author.ID, map[string]interface{}{"name": author.Name})
+	return nil
+}
+
+func (a *auditedAuthorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	if err := a.AuthorRepository.UpdateAuthor(ctx, author, id); err != nil {
+		return err
+	}
+	a.record(ctx, audit.AuthorUpdate, id, map[string]interface{}{"name": author.Name})
+	return nil
+}
+
+func (a *auditedAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	if err := a.AuthorRepository.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, audit.AuthorDelete, id, nil)
+	return nil
+}
+
+func (a *auditedAuthorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	author, err := a.AuthorRepository.RevertAuthor(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, audit.AuthorRevert, id, map[string]interface{}{"name": author.Name, "revision": revision})
+	return author, nil
+}
+
+func (a *auditedAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	if err := a.AuthorRepository.ApplyAuthorBatch(ctx, ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		switch op.Op {
+		case BatchCreate:
+			a.record(ctx, audit.AuthorCreate, op.Author.ID, map[string]interface{}{"name": op.Author.Name, "batch": true})
+		case BatchUpdate:
+			a.record(ctx, audit.AuthorUpdate, op.ID, map[string]interface{}{"name": op.Author.Name, "batch": true})
+		case BatchDelete:
+			a.record(ctx, audit.AuthorDelete, op.ID, map[string]interface{}{"batch": true})
+		}
+	}
+	return nil
+}
+
+func (a *auditedAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	results, err := a.AuthorRepository.ApplyAuthorOperations(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		if results[i] != nil {
+			continue
+		}
+		switch op.Op {
+		case BatchCreate:
+			a.record(ctx, audit.AuthorCreate, op.Author.ID, map[string]interface{}{"name": op.Author.Name, "batch": true})
+		case BatchUpdate:
+			a.record(ctx, audit.AuthorUpdate, op.ID, map[string]interface{}{"name": op.Author.Name, "batch": true})
+		case BatchDelete:
+			a.record(ctx, audit.AuthorDelete, op.ID, map[string]interface{}{"batch": true})
+		}
+	}
+	return results, nil
+}
+
+func (a *auditedAuthorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	if err := a.AuthorRepository.ImportAuthors(ctx, ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		switch op.Op {
+		case BatchCreate:
+			a.record(ctx, audit.AuthorCreate, op.Author.ID, map[string]interface{}{"name": op.Author.Name, "import": true})
+		case BatchUpdate:
+			a.record(ctx, audit.AuthorUpdate, op.ID, map[string]interface{}{"name": op.Author.Name, "import": true})
+		}
+	}
+	return nil
+}
+
+func (a *auditedAuthorRepository) record(ctx context.Context, eventType string, id int, data interface{}) {
+	a.log.Record(ctx, audit.Event{
+		Type:       eventType,
+		Resource:   auditResource,
+		ResourceID: strconv.Itoa(id),
+		Data:       data,
+	})
+}
@@ -0,0 +1,46 @@
+package author
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+)
+
+func TestAuditedAuthorRepository_RecordsSuccessfulWrites(t *testing.T) {
+	// Arrange
+	store := audit.NewMemoryStore()
+	repo := NewAuditedAuthorRepository(NewMemoryAuthorRepository(), audit.NewLog(store))
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "user-1", Method: "jwt"})
+	author := &Author{Name: "Ursula"}
+
+	// Act
+	require.NoError(t, repo.CreateAuthor(ctx, author))
+	require.NoError(t, repo.UpdateAuthor(ctx, &Author{Name: "Ursula K."}, author.ID))
+	_, err := repo.RevertAuthor(ctx, author.ID, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteAuthor(ctx, author.ID))
+	failed := repo.UpdateAuthor(ctx, &Author{Name: "Gone"}, author.ID)
+
+	// Assert
+	assert.Error(t, failed)
+	var entries []*audit.Entry
+	require.NoError(t, store.Entries(context.Background(), 0, func(e *audit.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	require.Len(t, entries, 4)
+	for i, eventType := range []string{audit.AuthorCreate, audit.AuthorUpdate, audit.AuthorRevert, audit.AuthorDelete} {
+		assert.Equal(t, eventType, entries[i].Type)
+		assert.Equal(t, "author", entries[i].Resource)
+		assert.Equal(t, "1", entries[i].ResourceID)
+		assert.Equal(t, "jwt:user-1", entries[i].Actor)
+	}
+	assert.JSONEq(t, `{"name":"Ursula","revision":1}`, string(entries[2].Data))
+	_, err = audit.Verify(context.Background(), store)
+	assert.NoError(t, err)
+}
@@ -1,9 +1,59 @@
 package author
 
-// Author represents an author.
+import "time"
+
+// resourceName is the name used for authors in domain errors.
+const resourceName = "Author"
+
+// Author represents an author. Only Name is required; the profile fields are
+// optional and omitted from responses when empty, so clients that only know
+// id and name see the same documents as before.
 // @Summary Author struct to represent an author
 // @Description Struct to represent an author
 type Author struct {
 	ID   int    `db:"id" json:"id" `
 	Name string `db:"name" json:"name" example:"test_author"`
+	// SortName is used to sort the author, e.g. "Le Guin, Ursula K.".
+	SortName   string      `db:"sort_name" json:"sort_name,omitempty" example:"Le Guin, Ursula K."`
+	GivenName  string      `db:"given_name" json:"given_name,omitempty" example:"Ursula K."`
+	FamilyName string      `db:"family_name" json:"family_name,omitempty" example:"Le Guin"`
+	BirthDate  PartialDate `db:"birth_date" json:"birth_date,omitempty" example:"1929-10-21"`
+	DeathDate  PartialDate `db:"death_date" json:"death_date,omitempty" example:"2018-01"`
+	// Nationality is an ISO 3166-1 alpha-2 country code.
+	Nationality string `db:"nationality" json:"nationality,omitempty" example:"US"`
+	Biography   string `db:"biography" json:"biography,omitempty"`
+	Links       Links  `db:"links" json:"links,omitempty"`
+	// Identifiers maps an identifier scheme (isni, orcid, viaf, wikidata,
+	// lccn or openlibrary) to the author's identifier in it.
+	Identifiers Identifiers `db:"identifiers" json:"identifiers,omitempty" swaggertype:"object,string" example:"wikidata:Q181659"`
+	// The audit fields are set by the repository from the request principal
+	// and the clock, and are ignored in request bodies. CreatedBy and
+	// UpdatedBy are the method-qualified subjects of the principals that
+	// made the writes, e.g. "basic:alice".
+	CreatedAt time.Time `db:"created_at" json:"created_at" example:"2024-01-02T15:04:05Z"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at" example:"2024-01-02T15:04:05Z"`
+	CreatedBy string    `db:"created_by" json:"created_by,omitempty" example:"basic:alice"`
+	UpdatedBy string    `db:"updated_by" json:"updated_by,omitempty" example:"basic:alice"`
+}
+
+// Link is a web page about the author.
+type Link struct {
+	URL   string `json:"url" example:"https://www.ursulakleguin.com"`
+	Label string `json:"label,omitempty" example:"Official website"`
+}
+
+// clone copies author so that stored authors and snapshots share no links or
+// identifiers with callers.
+func (a *Author) clone() Author {
+	c := *a
+	if a.Links != nil {
+		c.Links = append(Links(nil), a.Links...)
+	}
+	if a.Identifiers != nil {
+		c.Identifiers = make(Identifiers, len(a.Identifiers))
+		for k, v := range a.Identifiers {
+			c.Identifiers[k] = v
+		}
+	}
+	return c
 }
@@ -0,0 +1,576 @@
+// Package authortest provides a contract test suite that every
+// author.AuthorRepository implementation is expected to pass.
+package authortest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RepositoryFactory returns a new, empty repository for a single subtest.
+// Implementations should register any cleanup with t.Cleanup.
+type RepositoryFactory func(t *testing.T) author.AuthorRepository
+
+// RunRepositoryContract runs the AuthorRepository contract against the
+// repositories returned by newRepo.
+func RunRepositoryContract(t *testing.T, newRepo RepositoryFactory) {
+	t.Run("CreateAssignsID", func(t *testing.T) { testCreateAssignsID(t, newRepo(t)) })
+	t.Run("GetByID", func(t *testing.T) { testGetByID(t, newRepo(t)) })
+	t.Run("GetByIDNotFound", func(t *testing.T) { testGetByIDNotFound(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("ProfileRoundTrip", func(t *testing.T) { testProfileRoundTrip(t, newRepo(t)) })
+	t.Run("UpdateReplacesProfile", func(t *testing.T) { testUpdateReplacesProfile(t, newRepo(t)) })
+	t.Run("AuditFields", func(t *testing.T) { testAuditFields(t, newRepo(t)) })
+	t.Run("GetUpdatedSince", func(t *testing.T) { testGetUpdatedSince(t, newRepo(t)) })
+	t.Run("UpdateNotFound", func(t *testing.T) { testUpdateNotFound(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("DeleteNotFound", func(t *testing.T) { testDeleteNotFound(t, newRepo(t)) })
+	t.Run("History", func(t *testing.T) { testHistory(t, newRepo(t)) })
+	t.Run("HistoryEmpty", func(t *testing.T) { testHistoryEmpty(t, newRepo(t)) })
+	t.Run("Revert", func(t *testing.T) { testRevert(t, newRepo(t)) })
+	t.Run("RevertErrors", func(t *testing.T) { testRevertErrors(t, newRepo(t)) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, newRepo(t)) })
+	t.Run("BatchMissingRollsBack", func(t *testing.T) { testBatchMissingRollsBack(t, newRepo(t)) })
+	t.Run("Operations", func(t *testing.T) { testOperations(t, newRepo(t)) })
+	t.Run("Stream", func(t *testing.T) { testStream(t, newRepo(t)) })
+	t.Run("FindByIdentifier", func(t *testing.T) { testFindByIdentifier(t, newRepo(t)) })
+	t.Run("Import", func(t *testing.T) { testImport(t, newRepo(t)) })
+	t.Run("ImportMissingRollsBack", func(t *testing.T) { testImportMissingRollsBack(t, newRepo(t)) })
+	t.Run("GetAllEmpty", func(t *testing.T) { testGetAllEmpty(t, newRepo(t)) })
+	t.Run("GetAllOrderedByID", func(t *testing.T) { testGetAllOrderedByID(t, newRepo(t)) })
+	t.Run("ConcurrentCreate", func(t *testing.T) { testConcurrentCreate(t, newRepo(t)) })
+	t.Run("ConcurrentUpdate", func(t *testing.T) { testConcurrentUpdate(t, newRepo(t)) })
+}
+
+func testCreateAssignsID(t *testing.T, repo author.AuthorRepository) {
+	first := &author.Author{Name: "John Doe"}
+	second := &author.Author{Name: "Jane Smith"}
+
+	require.NoError(t, repo.CreateAuthor(context.Background(), first))
+	require.NoError(t, repo.CreateAuthor(context.Background(), second))
+
+	assert.NotZero(t, first.ID)
+	assert.NotZero(t, second.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func testGetByID(t *testing.T, repo author.AuthorRepository) {
+	created := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+
+	found, err := repo.GetAuthorById(created.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, created, found)
+}
+
+func testGetByIDNotFound(t *testing.T, repo author.AuthorRepository) {
+	_, err := repo.GetAuthorById(424242)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testUpdate(t *testing.T, repo author.AuthorRepository) {
+	created := &author.Author{Name: "John Doe"}
+	other := &author.Author{Name: "Jane Smith"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+	require.NoError(t, repo.CreateAuthor(context.Background(), other))
+
+	update := &author.Author{Name: "Johnny Doe"}
+
+	err := repo.UpdateAuthor(context.Background(), update, created.ID)
+
+	require.NoError(t, err)
+	updated, err := repo.GetAuthorById(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, "Johnny Doe", updated.Name)
+	assert.Equal(t, update, updated, "UpdateAuthor must leave author describing the stored row")
+	untouched, err := repo.GetAuthorById(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other, untouched)
+}
+
+func newProfiledAuthor() *author.Author {
+	return &author.Author{
+		Name:        "Ursula K. Le Guin",
+		SortName:    "Le Guin, Ursula K.",
+		GivenName:   "Ursula K.",
+		FamilyName:  "Le Guin",
+		BirthDate:   "1929-10-21",
+		DeathDate:   "2018-01",
+		Nationality: "US",
+		Biography:   "American author of speculative fiction.",
+		Links:       author.Links{{URL: "https://www.ursulakleguin.com", Label: "Official website"}},
+		Identifiers: author.Identifiers{"wikidata": "Q181659", "viaf": "93920661"},
+	}
+}
+
+func testProfileRoundTrip(t *testing.T, repo author.AuthorRepository) {
+	created := newProfiledAuthor()
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+
+	found, err := repo.GetAuthorById(created.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, created, found)
+}
+
+func testUpdateReplacesProfile(t *testing.T, repo author.AuthorRepository) {
+	created := newProfiledAuthor()
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+	update := &author.Author{Name: "Ursula Le Guin", BirthDate: "1929", Identifiers: author.Identifiers{"openlibrary": "OL31353A"}}
+
+	err := repo.UpdateAuthor(context.Background(), update, created.ID)
+
+	require.NoError(t, err)
+	updated, err := repo.GetAuthorById(created.ID)
+	require.NoError(t, err)
+	update.ID = created.ID
+	assert.Equal(t, update, updated)
+}
+
+func testAuditFields(t *testing.T, repo author.AuthorRepository) {
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Method: "basic"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "bob", Method: "basic"})
+	forged := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := &author.Author{Name: "John Doe", CreatedBy: "mallory", UpdatedBy: "mallory", CreatedAt: forged, UpdatedAt: forged}
+	require.NoError(t, repo.CreateAuthor(alice, created))
+	update := &author.Author{Name: "Johnny Doe", CreatedBy: "mallory", CreatedAt: forged}
+
+	err := repo.UpdateAuthor(bob, update, created.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "basic:alice", created.CreatedBy)
+	assert.Equal(t, "basic:alice", created.UpdatedBy)
+	assert.True(t, created.CreatedAt.After(forged), "created_at must come from the clock")
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+	updated, err := repo.GetAuthorById(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "basic:alice", updated.CreatedBy)
+	assert.Equal(t, "basic:bob", updated.UpdatedBy)
+	assert.True(t, created.CreatedAt.Equal(updated.CreatedAt))
+	assert.False(t, updated.UpdatedAt.Before(updated.CreatedAt))
+}
+
+func testGetUpdatedSince(t *testing.T, repo author.AuthorRepository) {
+	// Space the writes out so each gets its own timestamp.
+	write := func(op func() error) {
+		time.Sleep(2 * time.Millisecond)
+		require.NoError(t, op())
+	}
+	first := &author.Author{Name: "First"}
+	second := &author.Author{Name: "Second"}
+	third := &author.Author{Name: "Third"}
+	write(func() error { return repo.CreateAuthor(context.Background(), first) })
+	write(func() error { return repo.CreateAuthor(context.Background(), second) })
+	write(func() error { return repo.CreateAuthor(context.Background(), third) })
+	write(func() error {
+		return repo.UpdateAuthor(context.Background(), &author.Author{Name: "First again"}, first.ID)
+	})
+
+	changed, err := repo.GetAuthorsUpdatedSince(second.UpdatedAt)
+	none, noneErr := repo.GetAuthorsUpdatedSince(time.Now().Add(time.Hour))
+
+	require.NoError(t, err)
+	names := make([]string, len(changed))
+	for i, a := range changed {
+		names[i] = a.Name
+	}
+	assert.Equal(t, []string{"Second", "Third", "First again"}, names)
+	require.NoError(t, noneErr)
+	assert.NotNil(t, none)
+	assert.Empty(t, none)
+}
+
+func testUpdateNotFound(t *testing.T, repo author.AuthorRepository) {
+	err := repo.UpdateAuthor(context.Background(), &author.Author{Name: "Nobody"}, 424242)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testDelete(t *testing.T, repo author.AuthorRepository) {
+	created := &author.Author{Name: "John Doe"}
+	other := &author.Author{Name: "Jane Smith"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+	require.NoError(t, repo.CreateAuthor(context.Background(), other))
+
+	err := repo.DeleteAuthor(context.Background(), created.ID)
+
+	require.NoError(t, err)
+	_, err = repo.GetAuthorById(created.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+	untouched, err := repo.GetAuthorById(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other, untouched)
+}
+
+func testDeleteNotFound(t *testing.T, repo author.AuthorRepository) {
+	err := repo.DeleteAuthor(context.Background(), 424242)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testHistory(t *testing.T, repo author.AuthorRepository) {
+	ctx := middleware.WithRequestID(auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Method: "basic"}), "req-1")
+	created := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(ctx, created))
+	require.NoError(t, repo.UpdateAuthor(ctx, &author.Author{Name: "Johnny Doe", Nationality: "GB"}, created.ID))
+	require.NoError(t, repo.DeleteAuthor(ctx, created.ID))
+
+	revisions, err := repo.GetAuthorRevisions(created.ID)
+
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	for i, op := range []string{author.OpCreate, author.OpUpdate, author.OpDelete} {
+		assert.Equal(t, created.ID, revisions[i].AuthorID)
+		assert.Equal(t, i+1, revisions[i].Revision)
+		assert.Equal(t, op, revisions[i].Op)
+		assert.Equal(t, "basic:alice", revisions[i].Actor)
+		assert.Equal(t, "req-1", revisions[i].RequestID)
+		assert.False(t, revisions[i].CreatedAt.IsZero())
+	}
+	assert.Nil(t, revisions[0].Before)
+	assert.Equal(t, "John Doe", revisions[0].After.Name)
+	assert.Equal(t, "John Doe", revisions[1].Before.Name)
+	assert.Equal(t, "Johnny Doe", revisions[1].After.Name)
+	assert.Equal(t, "GB", revisions[1].After.Nationality)
+	assert.Equal(t, "Johnny Doe", revisions[2].Before.Name)
+	assert.Nil(t, revisions[2].After)
+	second, err := repo.GetAuthorRevision(created.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, revisions[1], second)
+}
+
+func testHistoryEmpty(t *testing.T, repo author.AuthorRepository) {
+	revisions, err := repo.GetAuthorRevisions(424242)
+	_, revErr := repo.GetAuthorRevision(424242, 1)
+
+	require.NoError(t, err)
+	assert.NotNil(t, revisions)
+	assert.Empty(t, revisions)
+	assert.Equal(t, sql.ErrNoRows, revErr)
+}
+
+func testRevert(t *testing.T, repo author.AuthorRepository) {
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "bob", Method: "basic"})
+	created := &author.Author{Name: "John Doe", Links: author.Links{{URL: "https://example.com"}}}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+	require.NoError(t, repo.UpdateAuthor(context.Background(), &author.Author{Name: "Johnny Doe"}, created.ID))
+
+	reverted, err := repo.RevertAuthor(bob, created.ID, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", reverted.Name)
+	assert.Equal(t, created.Links, reverted.Links)
+	assert.Equal(t, "basic:bob", reverted.UpdatedBy)
+	stored, err := repo.GetAuthorById(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reverted, stored)
+	revisions, err := repo.GetAuthorRevisions(created.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, author.OpRevert, revisions[2].Op)
+	assert.Equal(t, "Johnny Doe", revisions[2].Before.Name)
+	assert.Equal(t, "John Doe", revisions[2].After.Name)
+}
+
+func testRevertErrors(t *testing.T, repo author.AuthorRepository) {
+	created := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+	require.NoError(t, repo.DeleteAuthor(context.Background(), created.ID))
+
+	_, missingRevision := repo.RevertAuthor(context.Background(), created.ID, 7)
+	_, toDeletion := repo.RevertAuthor(context.Background(), created.ID, 2)
+	_, deletedAuthor := repo.RevertAuthor(context.Background(), created.ID, 1)
+
+	assert.Equal(t, sql.ErrNoRows, missingRevision)
+	assert.IsType(t, &errs.ValidationError{}, toDeletion)
+	assert.Equal(t, sql.ErrNoRows, deletedAuthor)
+}
+
+func testBatch(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	kept := &author.Author{Name: "John Doe"}
+	removed := &author.Author{Name: "Jane Smith"}
+	require.NoError(t, repo.CreateAuthor(ctx, kept))
+	require.NoError(t, repo.CreateAuthor(ctx, removed))
+	ops := []author.BatchOperation{
+		{Op: author.BatchCreate, Author: &author.Author{Name: "Ursula K. Le Guin", Links: author.Links{{Label: "Site", URL: "https://example.com"}}}},
+		{Op: author.BatchUpdate, ID: kept.ID, Author: &author.Author{Name: "John Q. Doe", Nationality: "US"}},
+		{Op: author.BatchDelete, ID: removed.ID},
+		{Op: author.BatchCreate, Author: &author.Author{Name: "Octavia Butler"}},
+	}
+
+	require.NoError(t, repo.ApplyAuthorBatch(ctx, ops))
+
+	authors, err := repo.GetAllAuthors()
+	require.NoError(t, err)
+	require.Len(t, authors, 3)
+	assert.Equal(t, []*author.Author{ops[1].Author, ops[0].Author, ops[3].Author}, authors)
+	assert.Less(t, ops[0].Author.ID, ops[3].Author.ID)
+	assert.Greater(t, ops[0].Author.ID, removed.ID)
+	assert.Equal(t, kept.CreatedAt, ops[1].Author.CreatedAt)
+
+	history, err := repo.GetAuthorRevisions(kept.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, author.OpUpdate, history[1].Op)
+	history, err = repo.GetAuthorRevisions(removed.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, author.OpDelete, history[1].Op)
+	history, err = repo.GetAuthorRevisions(ops[3].Author.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, author.OpCreate, history[0].Op)
+
+	// Ids handed out by the batch are not reused.
+	next := &author.Author{Name: "Next"}
+	require.NoError(t, repo.CreateAuthor(ctx, next))
+	assert.Greater(t, next.ID, ops[3].Author.ID)
+}
+
+func testBatchMissingRollsBack(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	existing := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(ctx, existing))
+
+	err := repo.ApplyAuthorBatch(ctx, []author.BatchOperation{
+		{Op: author.BatchCreate, Author: &author.Author{Name: "Jane Smith"}},
+		{Op: author.BatchUpdate, ID: existing.ID, Author: &author.Author{Name: "Renamed"}},
+		{Op: author.BatchDelete, ID: 424242},
+	})
+
+	assert.Equal(t, &author.BatchError{Index: 2, Err: sql.ErrNoRows}, err)
+	authors, getErr := repo.GetAllAuthors()
+	require.NoError(t, getErr)
+	assert.Equal(t, []*author.Author{existing}, authors)
+	history, getErr := repo.GetAuthorRevisions(existing.ID)
+	require.NoError(t, getErr)
+	assert.Len(t, history, 1)
+}
+
+func testOperations(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	existing := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(ctx, existing))
+	created := &author.Author{Name: "Jane Smith"}
+
+	results, err := repo.ApplyAuthorOperations(ctx, []author.BatchOperation{
+		{Op: author.BatchCreate, Author: created},
+		{Op: author.BatchUpdate, ID: 424242, Author: &author.Author{Name: "Missing"}},
+		{Op: author.BatchUpdate, ID: existing.ID, Author: &author.Author{Name: "Renamed"}},
+		{Op: author.BatchDelete, ID: 424243},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []error{nil, sql.ErrNoRows, nil, sql.ErrNoRows}, results)
+	authors, getErr := repo.GetAllAuthors()
+	require.NoError(t, getErr)
+	require.Len(t, authors, 2)
+	assert.Equal(t, "Renamed", authors[0].Name)
+	assert.Equal(t, created.ID, authors[1].ID)
+	history, getErr := repo.GetAuthorRevisions(existing.ID)
+	require.NoError(t, getErr)
+	assert.Len(t, history, 2)
+}
+
+func testStream(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	for _, name := range []string{"Charlie", "Alice", "Bob"} {
+		require.NoError(t, repo.CreateAuthor(ctx, &author.Author{Name: name, Identifiers: author.Identifiers{"viaf": "1"}}))
+	}
+	all, err := repo.GetAllAuthors()
+	require.NoError(t, err)
+	stop := errors.New("stop")
+
+	var streamed []*author.Author
+	err = repo.StreamAuthors(ctx, func(a *author.Author) error {
+		streamed = append(streamed, a)
+		return nil
+	})
+	var first []string
+	stopped := repo.StreamAuthors(ctx, func(a *author.Author) error {
+		first = append(first, a.Name)
+		return stop
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, all, streamed)
+	assert.Equal(t, stop, stopped)
+	assert.Equal(t, []string{"Charlie"}, first)
+}
+
+func testFindByIdentifier(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	authors := []*author.Author{
+		{Name: "Ursula K. Le Guin", Identifiers: author.Identifiers{"wikidata": "Q181659", "viaf": "93920661"}},
+		{Name: "Octavia Butler", Identifiers: author.Identifiers{"wikidata": "Q237013"}},
+		{Name: "Unidentified"},
+		{Name: "Ursula again", Identifiers: author.Identifiers{"wikidata": "Q181659"}},
+	}
+	for _, a := range authors {
+		require.NoError(t, repo.CreateAuthor(ctx, a))
+	}
+
+	found, err := repo.FindAuthorsByIdentifier("wikidata", []string{"Q181659", "Q1", "93920661"})
+	require.NoError(t, err)
+	none, noneErr := repo.FindAuthorsByIdentifier("viaf", []string{"Q181659"})
+
+	assert.Equal(t, []*author.Author{authors[0], authors[3]}, found)
+	require.NoError(t, noneErr)
+	assert.Empty(t, none)
+}
+
+func testImport(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	existing := &author.Author{Name: "John Doe", Biography: "Before"}
+	require.NoError(t, repo.CreateAuthor(ctx, existing))
+	ops := []author.BatchOperation{
+		{Op: author.BatchUpdate, ID: existing.ID, Author: &author.Author{Name: "John Q. Doe", Links: author.Links{{URL: "https://example.com"}}}},
+	}
+	// More rows than one batch takes, to cover the repositories that split
+	// imports.
+	for i := 0; i < author.MaxBatchOperations+1; i++ {
+		ops = append(ops, author.BatchOperation{Op: author.BatchCreate, Author: &author.Author{Name: fmt.Sprintf("Author %d", i),
+			Identifiers: author.Identifiers{"viaf": fmt.Sprint(i + 1)}}})
+	}
+
+	require.NoError(t, repo.ImportAuthors(ctx, ops))
+
+	authors, err := repo.GetAllAuthors()
+	require.NoError(t, err)
+	want := make([]*author.Author, len(ops))
+	for i, op := range ops {
+		want[i] = op.Author
+	}
+	assert.Equal(t, want, authors)
+	assert.Equal(t, existing.CreatedAt, ops[0].Author.CreatedAt)
+	history, err := repo.GetAuthorRevisions(existing.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, author.OpUpdate, history[1].Op)
+	assert.Equal(t, "Before", history[1].Before.Biography)
+	last := ops[len(ops)-1].Author
+	history, err = repo.GetAuthorRevisions(last.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, author.OpCreate, history[0].Op)
+	assert.Equal(t, last.Identifiers, history[0].After.Identifiers)
+
+	next := &author.Author{Name: "Next"}
+	require.NoError(t, repo.CreateAuthor(ctx, next))
+	assert.Greater(t, next.ID, last.ID)
+}
+
+func testImportMissingRollsBack(t *testing.T, repo author.AuthorRepository) {
+	ctx := context.Background()
+	existing := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(ctx, existing))
+	ops := make([]author.BatchOperation, 0, author.MaxBatchOperations+2)
+	for i := 0; i < author.MaxBatchOperations; i++ {
+		ops = append(ops, author.BatchOperation{Op: author.BatchCreate, Author: &author.Author{Name: fmt.Sprintf("Author %d", i)}})
+	}
+	ops = append(ops,
+		author.BatchOperation{Op: author.BatchUpdate, ID: existing.ID, Author: &author.Author{Name: "Renamed"}},
+		author.BatchOperation{Op: author.BatchUpdate, ID: 424242, Author: &author.Author{Name: "Nobody"}})
+
+	err := repo.ImportAuthors(ctx, ops)
+
+	assert.Equal(t, &author.BatchError{Index: len(ops) - 1, Err: sql.ErrNoRows}, err)
+	authors, getErr := repo.GetAllAuthors()
+	require.NoError(t, getErr)
+	assert.Equal(t, []*author.Author{existing}, authors)
+}
+
+func testGetAllEmpty(t *testing.T, repo author.AuthorRepository) {
+	authors, err := repo.GetAllAuthors()
+
+	require.NoError(t, err)
+	assert.NotNil(t, authors, "an empty repository must return an empty slice, not nil")
+	assert.Empty(t, authors)
+}
+
+func testGetAllOrderedByID(t *testing.T, repo author.AuthorRepository) {
+	// Create in an order that differs from alphabetical to catch name ordering.
+	names := []string{"Charlie", "Alice", "Bob"}
+	for _, name := range names {
+		require.NoError(t, repo.CreateAuthor(context.Background(), &author.Author{Name: name}))
+	}
+
+	authors, err := repo.GetAllAuthors()
+
+	require.NoError(t, err)
+	require.Len(t, authors, len(names))
+	for i, a := range authors {
+		assert.Equal(t, names[i], a.Name)
+		if i > 0 {
+			assert.Less(t, authors[i-1].ID, a.ID)
+		}
+	}
+}
+
+func testConcurrentCreate(t *testing.T, repo author.AuthorRepository) {
+	const workers = 20
+	var wg sync.WaitGroup
+	ids := make(chan int, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a := &author.Author{Name: fmt.Sprintf("Author %d", i)}
+			if assert.NoError(t, repo.CreateAuthor(context.Background(), a)) {
+				ids <- a.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate id %d", id)
+		seen[id] = true
+	}
+	authors, err := repo.GetAllAuthors()
+	require.NoError(t, err)
+	assert.Len(t, authors, workers)
+}
+
+func testConcurrentUpdate(t *testing.T, repo author.AuthorRepository) {
+	created := &author.Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), created))
+
+	const workers = 20
+	var wg sync.WaitGroup
+	names := make(map[string]bool)
+	for i := 0; i < workers; i++ {
+		name := fmt.Sprintf("Name %d", i)
+		names[name] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.UpdateAuthor(context.Background(), &author.Author{Name: name}, created.ID))
+		}()
+	}
+	wg.Wait()
+
+	// Last writer wins; the stored row must be one of the written values.
+	found, err := repo.GetAuthorById(created.ID)
+	require.NoError(t, err)
+	assert.True(t, names[found.Name], "unexpected name %q", found.Name)
+}
@@ -0,0 +1,182 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+// Batch operations.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOperations caps the operations in one batch. It keeps every
+// multi-row statement well under the bind parameter limits of Postgres and
+// SQLite.
+const MaxBatchOperations = 1000
+
+// ValidateBatchSize checks the number of operations in a batch.
+func ValidateBatchSize(n int) error {
+	if n == 0 {
+		return errs.NewValidationError(resourceName, "operations", "must not be empty")
+	}
+	if n > MaxBatchOperations {
+		return errs.NewValidationError(resourceName, "operations", fmt.Sprintf("must not have more than %d items", MaxBatchOperations))
+	}
+	return nil
+}
+
+// ErrBatchAborted is the result of the operations of an atomic batch that
+// were not applied because another operation failed.
+var ErrBatchAborted = errors.New("not applied because another operation in the atomic batch failed")
+
+// BatchOperation is one create, update or delete in a batch. Updates replace
+// the whole author, like PUT /authors/{id}.
+type BatchOperation struct {
+	Op string `json:"op" enums:"create,update,delete" example:"update"`
+	// ID is the author to update or delete.
+	ID     int     `json:"id,omitempty" example:"1"`
+	Author *Author `json:"author,omitempty"`
+}
+
+// BatchResult is the outcome of one operation of a batch: the author as
+// stored, unless the operation deleted it, or the error.
+type BatchResult struct {
+	Author *Author
+	Err    error
+}
+
+// BatchError reports the operation of a batch that failed.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// batchPlan lists the positions of the operations of a batch by kind. The
+// SQL repositories run each kind as one multi-row statement, deletes first
+// and creates last.
+type batchPlan struct {
+	creates, updates, deletes []int
+	// existing are the ids that must exist: those updated or deleted.
+	existing []int
+}
+
+func planBatch(ops []BatchOperation) batchPlan {
+	var plan batchPlan
+	for i, op := range ops {
+		switch op.Op {
+		case BatchCreate:
+			plan.creates = append(plan.creates, i)
+			continue
+		case BatchUpdate:
+			plan.updates = append(plan.updates, i)
+		case BatchDelete:
+			plan.deletes = append(plan.deletes, i)
+		}
+		plan.existing = append(plan.existing, op.ID)
+	}
+	return plan
+}
+
+// missingAuthor returns a *BatchError for the first update or delete of an
+// author that is not in found.
+func missingAuthor(ops []BatchOperation, found map[int]*Author) error {
+	for i, op := range ops {
+		if op.Op == BatchCreate {
+			continue
+		}
+		if _, ok := found[op.ID]; !ok {
+			return &BatchError{Index: i, Err: sql.ErrNoRows}
+		}
+	}
+	return nil
+}
+
+// batchRevisions numbers the revisions recorded by a batch, which all carry
+// the time of the batch.
+type batchRevisions struct {
+	ctx context.Context
+	at  time.Time
+	// latest holds the last revision number of each author.
+	latest    map[int]int
+	revisions []*Revision
+}
+
+func (b *batchRevisions) add(op string, id int, before, after *Author) {
+	rev := newRevision(b.ctx, op, id, before, after, b.at)
+	b.latest[id]++
+	rev.Revision = b.latest[id]
+	b.revisions = append(b.revisions, rev)
+}
+
+// applyEach runs apply for each of ops in a savepoint of tx, so that an
+// operation that fails is rolled back on its own, and returns the error of
+// each. Failing to manage the savepoints ends the batch.
+func applyEach(ctx context.Context, tx *sqlx.Tx, ops []BatchOperation, apply func(op BatchOperation) error) ([]error, error) {
+	results := make([]error, len(ops))
+	for i, op := range ops {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+			return nil, err
+		}
+		if results[i] = apply(op); results[i] != nil {
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_operation"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_operation"); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// sqliteValues returns the placeholders of a multi-row VALUES list.
+func sqliteValues(rows, columns int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", columns), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+}
+
+// postgresValues returns the numbered placeholders of a multi-row VALUES
+// list. When types are given, each placeholder is cast to the type of its
+// column, as Postgres cannot otherwise type a VALUES list used as a table.
+func postgresValues(rows, columns int, types ...string) string {
+	var b strings.Builder
+	n := 0
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for column := 0; column < columns; column++ {
+			if column > 0 {
+				b.WriteString(", ")
+			}
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			if types != nil {
+				b.WriteString("::" + types[column])
+			}
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/nilemarezz/go-init-template/pkg/cache"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+// notFoundMarker is cached for ids that do not exist, so repeated lookups of a
+// missing author do not reach the database until NegativeTTL expires.
+var notFoundMarker = []byte("null")
+
+var (
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "author_cache_hits_total",
+		Help: "Author lookups served from the cache, by entry kind (found or not_found).",
+	}, []string{"kind"})
+	cacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "author_cache_misses_total",
+		Help: "Author lookups that had to be loaded from the underlying repository.",
+	})
+)
+
+// CachedAuthorRepository is an AuthorRepository decorator that caches lookups
+// by id.
+type CachedAuthorRepository struct {
+	repo        AuthorRepository
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	group       singleflight.Group
+
+	// mu guards epoch, which is bumped on every invalidation so loads that
+	// started before a write do not put a stale value back into the cache,
+	// and generation, which namespaces keys so Purge can drop every entry.
+	mu         sync.Mutex
+	epoch      uint64
+	generation uint64
+}
+
+// NewCachedAuthorRepository wraps repo with a read-through cache for
+// GetAuthorById. Found authors are cached for ttl and missing ones for
+// negativeTTL. Concurrent misses for the same id share a single load, and
+// every write invalidates the affected entry.
+func NewCachedAuthorRepository(repo AuthorRepository, c cache.Cache, ttl, negativeTTL time.Duration) *CachedAuthorRepository {
+	return &CachedAuthorRepository{repo: repo, cache: c, ttl: ttl, negativeTTL: negativeTTL}
+}
+
+func (c *CachedAuthorRepository) GetAllAuthors() ([]*Author, error) {
+	return c.repo.GetAllAuthors()
+}
+
+func (c *CachedAuthorRepository) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	return c.repo.GetAuthorsUpdatedSince(since)
+}
+
+func (c *CachedAuthorRepository) GetAuthorById(id int) (*Author, error) {
+	key := c.cacheKey(id)
+
+	value, ok, err := c.cache.Get(key)
+	if err != nil {
+		logger.Warning("author cache get failed", zap.String("key", key), zap.Error(err))
+	} else if ok {
+		return decodeCachedAuthor(value)
+	}
+	cacheMisses.Inc()
+
+	loaded, err, _ := c.group.Do(key, func() (interface{}, error) {
+		c.mu.Lock()
+		epoch := c.epoch
+		c.mu.Unlock()
+		author, err := c.repo.GetAuthorById(id)
+		switch {
+		case err == sql.ErrNoRows:
+			c.store(epoch, key, notFoundMarker, c.negativeTTL)
+		case err != nil:
+			return nil, err
+		default:
+			encoded, _ := json.Marshal(author)
+			c.store(epoch, key, encoded, c.ttl)
+		}
+		return author, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Every caller of a shared load gets its own copy.
+	author := *loaded.(*Author)
+	return &author, nil
+}
+
+func (c *CachedAuthorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	if err := c.repo.CreateAuthor(ctx, author); err != nil {
+		return err
+	}
+	// The new id may have been cached as not found.
+	c.Invalidate(author.ID)
+	return nil
+}
+
+func (c *CachedAuthorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	err := c.repo.UpdateAuthor(ctx, author, id)
+	c.Invalidate(id)
+	return err
+}
+
+func (c *CachedAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	err := c.repo.DeleteAuthor(ctx, id)
+	c.Invalidate(id)
+	return err
+}
+
+func (c *CachedAuthorRepository) GetAuthorRevisions(id int) ([]*Revision, error) {
+	return c.repo.GetAuthorRevisions(id)
+}
+
+func (c *CachedAuthorRepository) GetAuthorRevision(id, revision int) (*Revision, error) {
+	return c.repo.GetAuthorRevision(id, revision)
+}
+
+func (c *CachedAuthorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	author, err := c.repo.RevertAuthor(ctx, id, revision)
+	c.Invalidate(id)
+	return author, err
+}
+
+func (c *CachedAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	err := c.repo.ApplyAuthorBatch(ctx, ops)
+	for _, op := range ops {
+		switch {
+		case op.Op != BatchCreate:
+			c.Invalidate(op.ID)
+		case err == nil:
+			// The new ids may have been cached as not found.
+			c.Invalidate(op.Author.ID)
+		}
+	}
+	return err
+}
+
+func (c *CachedAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	results, err := c.repo.ApplyAuthorOperations(ctx, ops)
+	for i, op := range ops {
+		switch {
+		case op.Op != BatchCreate:
+			c.Invalidate(op.ID)
+		case err == nil && results[i] == nil:
+			c.Invalidate(op.Author.ID)
+		}
+	}
+	return results, err
+}
+
+func (c *CachedAuthorRepository) StreamAuthors(ctx context.Context, fn func(*Author) error) error {
+	return c.repo.StreamAuthors(ctx, fn)
+}
+
+func (c *CachedAuthorRepository) FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error) {
+	return c.repo.FindAuthorsByIdentifier(scheme, values)
+}
+
+func (c *CachedAuthorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	err := c.repo.ImportAuthors(ctx, ops)
+	for _, op := range ops {
+		switch {
+		case op.Op != BatchCreate:
+			c.Invalidate(op.ID)
+		case err == nil:
+			c.Invalidate(op.Author.ID)
+		}
+	}
+	return err
+}
+
+// Invalidate drops any cached entry for the author with the given id.
+func (c *CachedAuthorRepository) Invalidate(id int) {
+	key := c.cacheKey(id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.group.Forget(key)
+	if err := c.cache.Delete(key); err != nil {
+		logger.Warning("author cache invalidation failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// Purge drops every cached author. Old entries are left to expire in the
+// backend but are never read again.
+func (c *CachedAuthorRepository) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.generation++
+}
+
+// HandleChange keeps the cache consistent with changes made by other
+// instances. It is meant to be subscribed to a database.ChangeListener.
+func (c *CachedAuthorRepository) HandleChange(change database.Change) {
+	switch {
+	case change.Op == database.OpResync:
+		c.Purge()
+	case change.Table == "authors":
+		c.Invalidate(change.ID)
+	}
+}
+
+func (c *CachedAuthorRepository) store(epoch uint64, key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.epoch != epoch {
+		return
+	}
+	if err := c.cache.Set(key, value, ttl); err != nil {
+		logger.Warning("author cache set failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func decodeCachedAuthor(value []byte) (*Author, error) {
+	var author *Author
+	if err := json.Unmarshal(value, &author); err != nil {
+		return nil, err
+	}
+	if author == nil {
+		cacheHits.WithLabelValues("not_found").Inc()
+		return nil, sql.ErrNoRows
+	}
+	cacheHits.WithLabelValues("found").Inc()
+	return author, nil
+}
+
+func (c *CachedAuthorRepository) cacheKey(id int) string {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+	return "author:" + strconv.FormatUint(generation, 10) + ":" + strconv.Itoa(id)
+}
@@ -0,0 +1,154 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/pkg/cache"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository records how many lookups reach the wrapped repository and
+// can hold them until release is closed.
+type countingRepository struct {
+	AuthorRepository
+	mu      sync.Mutex
+	lookups int
+	release chan struct{}
+}
+
+func (c *countingRepository) GetAuthorById(id int) (*Author, error) {
+	c.mu.Lock()
+	c.lookups++
+	c.mu.Unlock()
+	if c.release != nil {
+		<-c.release
+	}
+	return c.AuthorRepository.GetAuthorById(id)
+}
+
+func (c *countingRepository) Lookups() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookups
+}
+
+func newTestCachedRepository() (AuthorRepository, *countingRepository) {
+	counting := &countingRepository{AuthorRepository: NewMemoryAuthorRepository()}
+	return NewCachedAuthorRepository(counting, cache.NewLRU(100), time.Minute, time.Minute), counting
+}
+
+func TestCachedAuthorRepository_CachesHits(t *testing.T) {
+	// Arrange
+	repo, counting := newTestCachedRepository()
+	author := &Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), author))
+
+	// Act
+	first, err1 := repo.GetAuthorById(author.ID)
+	second, err2 := repo.GetAuthorById(author.ID)
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, author, first)
+	assert.Equal(t, author, second)
+	assert.Equal(t, 1, counting.Lookups())
+}
+
+func TestCachedAuthorRepository_NegativeCaching(t *testing.T) {
+	// Arrange
+	repo, counting := newTestCachedRepository()
+
+	// Act
+	_, err1 := repo.GetAuthorById(1)
+	_, err2 := repo.GetAuthorById(1)
+
+	// Assert
+	assert.Equal(t, sql.ErrNoRows, err1)
+	assert.Equal(t, sql.ErrNoRows, err2)
+	assert.Equal(t, 1, counting.Lookups())
+}
+
+func TestCachedAuthorRepository_CreateInvalidatesNegativeEntry(t *testing.T) {
+	// Arrange
+	repo, _ := newTestCachedRepository()
+	_, err := repo.GetAuthorById(1)
+	require.Equal(t, sql.ErrNoRows, err)
+
+	// Act
+	require.NoError(t, repo.CreateAuthor(context.Background(), &Author{Name: "John Doe"}))
+	found, err := repo.GetAuthorById(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", found.Name)
+}
+
+func TestCachedAuthorRepository_UpdateInvalidates(t *testing.T) {
+	// Arrange
+	repo, counting := newTestCachedRepository()
+	author := &Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), author))
+	_, _ = repo.GetAuthorById(author.ID)
+
+	// Act
+	require.NoError(t, repo.UpdateAuthor(context.Background(), &Author{Name: "Jane Smith"}, author.ID))
+	found, err := repo.GetAuthorById(author.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Smith", found.Name)
+	assert.Equal(t, 2, counting.Lookups())
+}
+
+func TestCachedAuthorRepository_CollapsesConcurrentMisses(t *testing.T) {
+	// Arrange
+	repo, counting := newTestCachedRepository()
+	author := &Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), author))
+	counting.release = make(chan struct{})
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := repo.GetAuthorById(author.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, author, found)
+		}()
+	}
+	// Give every goroutine time to join the in-flight load before releasing it.
+	time.Sleep(50 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, counting.Lookups())
+}
+
+func TestCachedAuthorRepository_HandleChange(t *testing.T) {
+	// Arrange
+	counting := &countingRepository{AuthorRepository: NewMemoryAuthorRepository()}
+	repo := NewCachedAuthorRepository(counting, cache.NewLRU(100), time.Minute, time.Minute)
+	author := &Author{Name: "John Doe"}
+	require.NoError(t, repo.CreateAuthor(context.Background(), author))
+	_, _ = repo.GetAuthorById(author.ID)
+
+	// Act: another instance updated the author, then the listener resynced.
+	repo.HandleChange(database.Change{Seq: 1, Table: "authors", Op: database.OpUpdate, ID: author.ID})
+	_, _ = repo.GetAuthorById(author.ID)
+	repo.HandleChange(database.Change{Op: database.OpResync})
+	_, _ = repo.GetAuthorById(author.ID)
+	_, _ = repo.GetAuthorById(author.ID)
+
+	// Assert
+	assert.Equal(t, 3, counting.Lookups())
+}
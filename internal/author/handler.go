@@ -2,138 +2,761 @@
 package author
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 
-	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/authz"
 	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/jsonpatch"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
 )
 
-func SetupRouter(router *gin.Engine, db *sqlx.DB) {
+func SetupRouter(router gin.IRouter, authorRepo AuthorRepository, authn *auth.Authentication, policy *authz.Policy) {
 
-	authorRepo := NewAuthorRepository(db)
 	authorService := NewAuthorService(authorRepo)
 	handler := NewAuthorHandler(authorService)
+	handler.policy = policy
 
-	authorRoutes := router.Group("/authors")
+	authorRoutes := router.Group("/authors", authn.Optional())
 	{
-		authorRoutes.GET("/", handler.GetAllAuthor)
-		authorRoutes.GET("/:id", handler.GetAuthorByID)
-		authorRoutes.POST("/", handler.CreateAuthor)
-		authorRoutes.PUT("/", handler.UpdateAuthor)
-		// Add other routes like GET, PUT, DELETE here
+		authorRoutes.GET("/", policy.Require(authz.AuthorsRead), handler.GetAllAuthor)
+		authorRoutes.GET("/export", policy.Require(authz.AuthorsRead), handler.ExportAuthors)
+		authorRoutes.POST("/import", policy.Require(authz.AuthorsWrite), handler.ImportAuthors)
+		authorRoutes.GET("/:id", policy.Require(authz.AuthorsRead), handler.GetAuthorByID)
+		authorRoutes.POST("/", policy.Require(authz.AuthorsWrite), handler.CreateAuthor)
+		authorRoutes.PUT("/:id", policy.Require(authz.AuthorsWrite), handler.UpdateAuthor)
+		authorRoutes.PATCH("/:id", policy.Require(authz.AuthorsWrite), handler.PatchAuthor)
+		authorRoutes.DELETE("/:id", policy.Require(authz.AuthorsWrite), handler.DeleteAuthor)
+		authorRoutes.GET("/:id/history", policy.Require(authz.AuthorsRead), handler.GetAuthorHistory)
+		authorRoutes.GET("/:id/history/diff", policy.Require(authz.AuthorsRead), handler.DiffAuthorRevisions)
+		authorRoutes.GET("/:id/history/:rev", policy.Require(authz.AuthorsRead), handler.GetAuthorRevision)
+		authorRoutes.POST("/:id/revert/:rev", policy.Require(authz.AuthorsWrite), handler.RevertAuthor)
 	}
+	// Custom methods on the collection, such as /authors:batch, take the
+	// action after a colon. They cannot be registered on the group, which
+	// would put a slash before it.
+	router.POST("/authors:action", authn.Optional(), policy.Require(authz.AuthorsWrite), handler.CollectionAction)
 }
 
 type AuthorHandler struct {
 	service AuthorService
+	// policy enforces the ownership rule on updates; nil disables it.
+	policy *authz.Policy
 }
 
 func NewAuthorHandler(service AuthorService) *AuthorHandler {
 	return &AuthorHandler{service: service}
 }
 
-// GetAllAuthor fetches all authors.
+// GetAllAuthor fetches all authors, or those changed since a point in time.
 // @Summary Get all authors
-// @Description Retrieve a list of all authors
+// @Description Retrieve a list of all authors. With updated_since, only authors created or updated at or after that time are returned, oldest change first, so sync jobs can pass the latest updated_at they have seen.
 // @Produce json
+// @Param updated_since query string false "RFC 3339 timestamp, e.g. 2024-01-02T15:04:05Z"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {array} Author
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid updated_since"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
 // @Failure 500 {object} httputil.HTTPError
 // @Router /authors [get]
 func (h *AuthorHandler) GetAllAuthor(c *gin.Context) {
-	authors, err := h.service.GetAllAuthors()
+	var authors []*Author
+	var err error
+	if value, ok := c.GetQuery("updated_since"); ok {
+		since, parseErr := time.Parse(time.RFC3339Nano, value)
+		if parseErr != nil {
+			httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("updated_since must be an RFC 3339 timestamp: %w", parseErr))
+			return
+		}
+		authors, err = h.service.GetAuthorsUpdatedSince(since)
+	} else {
+		authors, err = h.service.GetAllAuthors()
+	}
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, httputil.StatusFromError(err), err)
 		return
 	}
-	c.JSON(200, authors)
+	// Deletes leave no timestamp behind, so lists only get an ETag.
+	httputil.JSONWithValidators(c, http.StatusOK, authors, time.Time{})
 }
 
 // GetAuthorByID retrieves an author by ID.
 // @Summary Get an author by ID
-// @Description Retrieve an author by its ID
+// @Description Retrieve an author by its ID. The response carries an ETag and a Last-Modified header; send them back in If-None-Match or If-Modified-Since to get 304 Not Modified while the author is unchanged.
 // @Produce json
 // @Param id path int true "Author ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of a cached copy"
 // @Success 200 {object} Author
+// @Success 304 "Not Modified"
 // @Failure 400 {object} httputil.HTTPError "Invalid ID format"
 // @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
 // @Failure 500 {object} httputil.HTTPError "Internal Server Error"
 // @Router /authors/{id} [get]
 func (h *AuthorHandler) GetAuthorByID(c *gin.Context) {
-	idStr := c.Param("id")
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	id, ok := paramInt(c, "id")
+	if !ok {
 		return
 	}
 
-	authors, err := h.service.GetAuthorById(id)
-
-	if errHandle, ok := err.(*errs.NotFoundError); ok {
-		httputil.NewError(c, http.StatusNotFound, errHandle)
-		return
-	} else if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	author, err := h.service.GetAuthorById(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
 		return
 	}
 
-	c.JSON(200, authors)
+	httputil.JSONWithValidators(c, http.StatusOK, author, author.UpdatedAt)
 }
 
 // CreateAuthor creates a new author.
 // @Summary Create a new author
-// @Description Create a new author with the provided data
+// @Description Create a new author with the provided data. The response is the stored author, and the Location header gives its URL.
 // @Accept json
 // @Produce json
 // @Param author body Author true "Author object"
-// @Success 201
+// @Param Idempotency-Key header string false "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Success 201 {object} Author
+// @Header 201 {string} Location "URL of the new author"
 // @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission"
+// @Failure 409 {object} httputil.HTTPError "Author already exists, or a request with the same Idempotency-Key is in progress"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 422 {object} httputil.HTTPError "Idempotency-Key already used with a different request"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
 // @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
 // @Router /authors [post]
 func (h *AuthorHandler) CreateAuthor(c *gin.Context) {
 	var newAuthor Author
-	if err := c.BindJSON(&newAuthor); err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	if err := c.ShouldBindJSON(&newAuthor); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
 		return
 	}
 
-	err := h.service.CreateAuthor(&newAuthor)
+	err := h.service.CreateAuthor(c.Request.Context(), &newAuthor)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, httputil.StatusFromError(err), err)
 		return
 	}
 
-	c.Status(201)
+	c.Header("Location", path.Join(c.Request.URL.Path, strconv.Itoa(newAuthor.ID)))
+	httputil.JSONWithValidators(c, http.StatusCreated, &newAuthor, newAuthor.UpdatedAt)
 }
 
-// UpdateAuthor updates an existing author.
-// @Summary Update an existing author
-// @Description Update an existing author with the provided data
+// UpdateAuthor replaces an existing author.
+// @Summary Replace an author
+// @Description Replace the author with the body. Fields left out of the body are cleared; use PATCH to change only some fields. An id in the body must match the path.
 // @Accept json
 // @Produce json
+// @Param id path int true "Author ID"
 // @Param author body Author true "Author object"
-// @Success 200
+// @Success 200 {object} Author
 // @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission, or not the author's creator"
 // @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 409 {object} httputil.HTTPError "Author already exists"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
 // @Failure 500 {object} httputil.HTTPError "Internal Server Error"
-// @Router /authors [put]
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors/{id} [put]
 func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+
+	if !h.canModify(c, id) {
+		return
+	}
+
 	var updatedAuthor Author
-	if err := c.BindJSON(&updatedAuthor); err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	if err := c.ShouldBindJSON(&updatedAuthor); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
 		return
 	}
 
-	err := h.service.UpdateAuthor(&updatedAuthor, updatedAuthor.ID)
+	h.saveAuthor(c, id, &updatedAuthor)
+}
 
+// PatchAuthor changes some fields of an existing author.
+// @Summary Patch an author
+// @Description Apply a JSON Merge Patch (RFC 7396, application/merge-patch+json) or a JSON Patch (RFC 6902, application/json-patch+json) to the author as returned by GET, then validate and save the result. In a merge patch, null clears a field and objects such as identifiers are merged; arrays such as links are replaced. A JSON Patch is applied all or nothing, and may use test operations to guard against concurrent changes. Changes to the audit fields are ignored and the id cannot be changed.
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param patch body object true "Merge patch object, or array of JSON Patch operations"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Success 200 {object} Author
+// @Failure 400 {object} httputil.HTTPError "Malformed patch, or the patched author is invalid"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission, or not the author's creator"
+// @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 409 {object} httputil.HTTPError "A test operation failed, the author already exists, or a request with the same Idempotency-Key is in progress"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 415 {object} httputil.HTTPError "Unsupported patch format"
+// @Failure 422 {object} httputil.HTTPError "The patch refers to a location that does not exist, or Idempotency-Key already used with a different request"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors/{id} [patch]
+func (h *AuthorHandler) PatchAuthor(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+
+	format := c.ContentType()
+	if format != jsonpatch.MediaTypeMergePatch && format != jsonpatch.MediaTypeJSONPatch {
+		c.Header("Accept-Patch", jsonpatch.MediaTypeMergePatch+", "+jsonpatch.MediaTypeJSONPatch)
+		httputil.NewError(c, http.StatusUnsupportedMediaType,
+			fmt.Errorf("the body must be %s or %s", jsonpatch.MediaTypeMergePatch, jsonpatch.MediaTypeJSONPatch))
+		return
+	}
+
+	if !h.canModify(c, id) {
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+	existing, err := h.service.GetAuthorById(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+	document, err := json.Marshal(existing)
 	if err != nil {
 		httputil.NewError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.Status(200)
+	var patched []byte
+	if format == jsonpatch.MediaTypeMergePatch {
+		patched, err = jsonpatch.MergePatch(document, body)
+	} else {
+		var patch jsonpatch.Patch
+		if patch, err = jsonpatch.DecodePatch(body); err == nil {
+			patched, err = patch.Apply(document)
+		}
+	}
+	if err != nil {
+		httputil.NewError(c, patchStatus(err), err)
+		return
+	}
+
+	// Decode into a new author so that removed fields are cleared.
+	var patchedAuthor Author
+	if err := json.Unmarshal(patched, &patchedAuthor); err != nil {
+		httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("the patched author is invalid: %w", err))
+		return
+	}
+
+	h.saveAuthor(c, id, &patchedAuthor)
+}
+
+// patchStatus maps an error from applying a patch to the status code that
+// should be returned.
+func patchStatus(err error) int {
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return http.StatusConflict
+	case errors.Is(err, jsonpatch.ErrCannotApply):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// saveAuthor stores author as the new state of the author with the given id
+// and writes it to the response.
+func (h *AuthorHandler) saveAuthor(c *gin.Context, id int, author *Author) {
+	if author.ID != 0 && author.ID != id {
+		httputil.NewError(c, http.StatusBadRequest, errors.New("the id of an author cannot be changed"))
+		return
+	}
+
+	if err := h.service.UpdateAuthor(c.Request.Context(), author, id); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, author, author.UpdatedAt)
+}
+
+// DeleteAuthor deletes an author.
+// @Summary Delete an author
+// @Description Delete an author. Authors that are still credited on books cannot be deleted.
+// @Param id path int true "Author ID"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission, or not the author's creator"
+// @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 409 {object} httputil.HTTPError "Author still has books"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors/{id} [delete]
+func (h *AuthorHandler) DeleteAuthor(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+
+	if !h.canModify(c, id) {
+		return
+	}
+
+	if err := h.service.DeleteAuthor(c.Request.Context(), id); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAuthorHistory lists the revisions of an author.
+// @Summary Get the change history of an author
+// @Description List every recorded create, update, delete and revert of an author, oldest first, with before and after snapshots. Deleted authors keep their history.
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of a cached copy"
+// @Success 200 {array} Revision
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /authors/{id}/history [get]
+func (h *AuthorHandler) GetAuthorHistory(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+
+	revisions, err := h.service.GetAuthorHistory(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	// History only grows, so it changed when its last revision was made.
+	var lastModified time.Time
+	if len(revisions) > 0 {
+		lastModified = revisions[len(revisions)-1].CreatedAt
+	}
+	httputil.JSONWithValidators(c, http.StatusOK, revisions, lastModified)
+}
+
+// GetAuthorRevision retrieves one revision of an author.
+// @Summary Get a revision of an author
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param rev path int true "Revision number"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of a cached copy"
+// @Success 200 {object} Revision
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID or revision format"
+// @Failure 404 {object} httputil.HTTPError "Revision not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /authors/{id}/history/{rev} [get]
+func (h *AuthorHandler) GetAuthorRevision(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+	revision, ok := paramInt(c, "rev")
+	if !ok {
+		return
+	}
+
+	rev, err := h.service.GetAuthorRevision(id, revision)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, rev, rev.CreatedAt)
+}
+
+// DiffAuthorRevisions compares two revisions of an author.
+// @Summary Diff two revisions of an author
+// @Description List the fields that differ between the author as it stood after revision from and after revision to. Audit fields are left out.
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param from query int true "Earlier revision number"
+// @Param to query int true "Later revision number"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {array} FieldChange
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID or revision numbers"
+// @Failure 404 {object} httputil.HTTPError "Revision not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /authors/{id}/history/diff [get]
+func (h *AuthorHandler) DiffAuthorRevisions(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		httputil.NewError(c, http.StatusBadRequest, errors.New("from and to must be revision numbers"))
+		return
+	}
+
+	changes, err := h.service.DiffAuthorRevisions(id, from, to)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, changes, time.Time{})
+}
+
+// RevertAuthor restores an author to an earlier revision.
+// @Summary Revert an author to a revision
+// @Description Set the author's fields back to those recorded after the given revision. The revert is recorded as a new revision. Revisions that deleted the author cannot be restored.
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} Author
+// @Failure 400 {object} httputil.HTTPError "Invalid ID or revision, or a revision that deleted the author"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission, or not the author's creator"
+// @Failure 404 {object} httputil.HTTPError "Author or revision not found"
+// @Failure 409 {object} httputil.HTTPError "Author already exists"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors/{id}/revert/{rev} [post]
+func (h *AuthorHandler) RevertAuthor(c *gin.Context) {
+	id, ok := paramInt(c, "id")
+	if !ok {
+		return
+	}
+	revision, ok := paramInt(c, "rev")
+	if !ok {
+		return
+	}
+
+	if !h.canModify(c, id) {
+		return
+	}
+
+	author, err := h.service.RevertAuthor(c.Request.Context(), id, revision)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, author, author.UpdatedAt)
+}
+
+// CollectionAction dispatches the custom methods on the authors collection.
+func (h *AuthorHandler) CollectionAction(c *gin.Context) {
+	switch c.Param("action") {
+	case ":batch":
+		h.BatchAuthors(c)
+	default:
+		httputil.NewError(c, http.StatusNotFound, fmt.Errorf("unknown action %q", c.Param("action")))
+	}
+}
+
+// BatchRequest is the body of POST /authors:batch.
+type BatchRequest struct {
+	// Atomic applies the operations all or none.
+	Atomic     bool             `json:"atomic" example:"true"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchItemResult is the outcome of one operation of a batch.
+type BatchItemResult struct {
+	Index  int                 `json:"index" example:"0"`
+	Status int                 `json:"status" example:"201"`
+	Author *Author             `json:"author,omitempty"`
+	Error  *httputil.HTTPError `json:"error,omitempty"`
+}
+
+// BatchResponse lists the outcome of every operation of a batch, in order.
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+// BatchAuthors applies several creates, updates and deletes in one request.
+// @Summary Create, update and delete authors in a batch
+// @Description Apply up to 1000 operations. An update replaces the whole author, like PUT. With atomic set, the operations are applied in one transaction, all or none: the status is 200 when they all succeed, otherwise that of the operation that failed, and the others report 424. Without it, each operation succeeds or fails on its own and the status is 207. Either way the results give the status of every operation, in order: 201 for a create, 200 for an update and 204 for a delete. An author may appear in only one operation.
+// @Accept json
+// @Produce json
+// @Param batch body BatchRequest true "Operations"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Success 200 {object} BatchResponse "Atomic batch applied"
+// @Success 207 {object} BatchResponse "Results of an independent batch"
+// @Failure 400 {object} BatchResponse "Bad request, or an operation of an atomic batch is invalid"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} BatchResponse "Missing authors:write permission, or an operation of an atomic batch changes an author the caller did not create"
+// @Failure 404 {object} BatchResponse "An operation of an atomic batch changes a missing author"
+// @Failure 409 {object} BatchResponse "An operation of an atomic batch deletes an author that still has books, or a request with the same Idempotency-Key is in progress"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 422 {object} httputil.HTTPError "Idempotency-Key already used with a different request"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors:batch [post]
+func (h *AuthorHandler) BatchAuthors(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+	if err := ValidateBatchSize(len(req.Operations)); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	// Operations the caller may not apply are left out of the batch, or
+	// abort it.
+	results := make([]BatchResult, len(req.Operations))
+	allowed := make([]BatchOperation, 0, len(req.Operations))
+	indexes := make([]int, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		if !h.mayApply(c, op) {
+			results[i].Err = authz.ErrForbidden
+			continue
+		}
+		allowed = append(allowed, op)
+		indexes = append(indexes, i)
+	}
+	if req.Atomic && len(allowed) < len(req.Operations) {
+		abortBatch(results)
+	} else if len(allowed) > 0 {
+		applied, err := h.service.ApplyAuthorBatch(c.Request.Context(), allowed, req.Atomic)
+		if err != nil {
+			httputil.NewError(c, httputil.StatusFromError(err), err)
+			return
+		}
+		for j, result := range applied {
+			results[indexes[j]] = result
+		}
+	}
+
+	response := BatchResponse{Results: make([]BatchItemResult, len(results))}
+	status := http.StatusMultiStatus
+	if req.Atomic {
+		status = http.StatusOK
+	}
+	for i, result := range results {
+		item := BatchItemResult{Index: i, Author: result.Author}
+		switch {
+		case result.Err == nil:
+			item.Status = batchSuccessStatus[req.Operations[i].Op]
+		case errors.Is(result.Err, ErrBatchAborted):
+			item.Status = http.StatusFailedDependency
+		case errors.Is(result.Err, authz.ErrForbidden):
+			item.Status = http.StatusForbidden
+		default:
+			item.Status = httputil.StatusFromError(result.Err)
+		}
+		if result.Err != nil {
+			item.Error = &httputil.HTTPError{Code: item.Status, Message: result.Err.Error()}
+			if status == http.StatusOK && item.Status != http.StatusFailedDependency {
+				status = item.Status
+			}
+		}
+		response.Results[i] = item
+	}
+	c.JSON(status, response)
+}
+
+// batchSuccessStatus is the status of each kind of operation that succeeds,
+// as if it had been sent on its own.
+var batchSuccessStatus = map[string]int{
+	BatchCreate: http.StatusCreated,
+	BatchUpdate: http.StatusOK,
+	BatchDelete: http.StatusNoContent,
+}
+
+// mayApply applies the ownership rule to a batch operation. Operations on
+// missing authors are let through for the service to report.
+func (h *AuthorHandler) mayApply(c *gin.Context, op BatchOperation) bool {
+	if h.policy == nil || op.Op == BatchCreate {
+		return true
+	}
+	principal, _ := auth.PrincipalFrom(c)
+	if h.policy.CanModify(principal, "") {
+		return true
+	}
+	existing, err := h.service.GetAuthorById(op.ID)
+	if err != nil {
+		return true
+	}
+	return h.policy.CanModify(principal, existing.CreatedBy)
+}
+
+// ExportAuthors streams every author as a file.
+// @Summary Export authors
+// @Description Download every author as CSV, NDJSON or an Excel workbook, one author per row. The authors are streamed from the database as they are read. Spreadsheet columns hold one field each; links are a JSON array and each identifier has an identifiers.<scheme> column. The file can be imported again with POST /authors/import.
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format" Enums(csv, ndjson, xlsx) default(csv)
+// @Success 200 {file} file "The authors"
+// @Failure 400 {object} httputil.HTTPError "Unknown format"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:read permission"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /authors/export [get]
+func (h *AuthorHandler) ExportAuthors(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatCSV)
+	contentType, err := FormatContentType(format)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "authors."+format))
+	c.Status(http.StatusOK)
+	if err := h.service.ExportAuthors(c.Request.Context(), format, c.Writer); err != nil {
+		// The status has been sent; the client sees a truncated file.
+		logger.Warning("author export failed", zap.Error(err))
+	}
+}
+
+// ImportAuthors creates and updates authors from a file.
+// @Summary Import authors
+// @Description Create and update authors from a CSV, NDJSON or Excel file in the body, laid out like those of GET /authors/export. The format comes from the format parameter or the Content-Type. Columns are matched to fields by name; map gives the column of a field that is named otherwise, e.g. map[name]=Full Name. Read-only columns such as id and columns that match no field are ignored. Every row is checked first, and the file is imported all or none, in one transaction: when any row has an error, the response is 422 and nothing is written. With dry_run, the file is only checked. With upsert_by, rows with an identifier in that scheme that an author already has update that author, changing only the fields in the file; the other rows create authors.
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce json
+// @Param file body string true "The file"
+// @Param format query string false "File format, if the Content-Type does not give it" Enums(csv, ndjson, xlsx)
+// @Param map query string false "Column of a field, as map[field]=column; repeat for more fields"
+// @Param dry_run query bool false "Check the file without importing it"
+// @Param upsert_by query string false "Identifier scheme that matches rows to existing authors" Enums(isni, lccn, openlibrary, orcid, viaf, wikidata)
+// @Param Idempotency-Key header string false "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Success 200 {object} ImportReport "File imported, or checked in a dry run"
+// @Failure 400 {object} httputil.HTTPError "Unreadable file, unknown format or bad parameters"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing authors:write permission"
+// @Failure 409 {object} httputil.HTTPError "A request with the same Idempotency-Key is in progress"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 422 {object} ImportReport "Rows have errors; nothing was imported"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /authors/import [post]
+func (h *AuthorHandler) ImportAuthors(c *gin.Context) {
+	options := ImportOptions{
+		Format:   c.Query("format"),
+		Mapping:  c.QueryMap("map"),
+		UpsertBy: c.Query("upsert_by"),
+	}
+	if h.policy != nil {
+		principal, _ := auth.PrincipalFrom(c)
+		options.MayUpdate = func(existing *Author) bool {
+			return h.policy.CanModify(principal, existing.CreatedBy)
+		}
+	}
+	if options.Format == "" {
+		options.Format = FormatFromContentType(c.ContentType())
+	}
+	if _, err := FormatContentType(options.Format); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+	if value, ok := c.GetQuery("dry_run"); ok {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("invalid dry_run: %w", err))
+			return
+		}
+		options.DryRun = dryRun
+	}
+
+	// XLSX files are zip archives, which are read from the end.
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+	report, err := h.service.ImportAuthors(c.Request.Context(), bytes.NewReader(body), int64(len(body)), options)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, report)
+}
+
+// paramInt parses a numeric path parameter, writing a 400 response if it is
+// not a number.
+func paramInt(c *gin.Context, name string) (int, bool) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		httputil.NewError(c, http.StatusBadRequest, err)
+		return 0, false
+	}
+	return value, true
+}
+
+// canModify applies the ownership rule to the author with the given id and
+// writes the error response if the caller may not modify it.
+func (h *AuthorHandler) canModify(c *gin.Context, id int) bool {
+	if h.policy == nil {
+		return true
+	}
+	existing, err := h.service.GetAuthorById(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return false
+	}
+	principal, _ := auth.PrincipalFrom(c)
+	if !h.policy.CanModify(principal, existing.CreatedBy) {
+		httputil.NewError(c, http.StatusForbidden, authz.ErrForbidden)
+		return false
+	}
+	return true
 }
@@ -1,16 +1,27 @@
 package author
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/authz"
 	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+	"github.com/nilemarezz/go-init-template/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockAuthorService struct {
@@ -22,6 +33,11 @@ func (m *MockAuthorService) GetAllAuthors() ([]*Author, error) {
 	return args.Get(0).([]*Author), args.Error(1)
 }
 
+func (m *MockAuthorService) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	args := m.Called(since)
+	return args.Get(0).([]*Author), args.Error(1)
+}
+
 func (m *MockAuthorService) GetAuthorById(id int) (*Author, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -30,16 +46,68 @@ func (m *MockAuthorService) GetAuthorById(id int) (*Author, error) {
 	return args.Get(0).(*Author), args.Error(1)
 }
 
-func (m *MockAuthorService) CreateAuthor(author *Author) error {
-	args := m.Called(author)
+func (m *MockAuthorService) CreateAuthor(ctx context.Context, author *Author) error {
+	args := m.Called(ctx, author)
+	return args.Error(0)
+}
+
+func (m *MockAuthorService) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	args := m.Called(ctx, author, id)
 	return args.Error(0)
 }
 
-func (m *MockAuthorService) UpdateAuthor(author *Author, id int) error {
-	args := m.Called(author, id)
+func (m *MockAuthorService) DeleteAuthor(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthorService) GetAuthorHistory(id int) ([]*Revision, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*Revision), args.Error(1)
+}
+
+func (m *MockAuthorService) GetAuthorRevision(id, revision int) (*Revision, error) {
+	args := m.Called(id, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Revision), args.Error(1)
+}
+
+func (m *MockAuthorService) DiffAuthorRevisions(id, from, to int) ([]FieldChange, error) {
+	args := m.Called(id, from, to)
+	return args.Get(0).([]FieldChange), args.Error(1)
+}
+
+func (m *MockAuthorService) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	args := m.Called(ctx, id, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Author), args.Error(1)
+}
+
+func (m *MockAuthorService) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	args := m.Called(ctx, ops, atomic)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]BatchResult), args.Error(1)
+}
+
+func (m *MockAuthorService) ExportAuthors(ctx context.Context, format string, w io.Writer) error {
+	args := m.Called(ctx, format, w)
 	return args.Error(0)
 }
 
+func (m *MockAuthorService) ImportAuthors(ctx context.Context, file io.ReaderAt, size int64, options ImportOptions) (*ImportReport, error) {
+	args := m.Called(ctx, file, size, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ImportReport), args.Error(1)
+}
+
 func TestGetAllAuthor(t *testing.T) {
 	// Arrange
 	mockService := new(MockAuthorService)
@@ -94,7 +162,8 @@ func TestGetAuthorByID_Success(t *testing.T) {
 	router := gin.Default()
 	router.GET("/authors/:id", handler.GetAuthorByID)
 
-	expectedAuthor := &Author{ID: 1, Name: "John Doe"}
+	stamp := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	expectedAuthor := &Author{ID: 1, Name: "John Doe", CreatedAt: stamp, UpdatedAt: stamp}
 	mockService.On("GetAuthorById", 1).Return(expectedAuthor, nil)
 
 	req, _ := http.NewRequest("GET", "/authors/1", nil)
@@ -105,7 +174,7 @@ func TestGetAuthorByID_Success(t *testing.T) {
 
 	// Assert
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.JSONEq(t, `{"id":1,"name":"John Doe"}`, w.Body.String())
+	assert.JSONEq(t, `{"id":1,"name":"John Doe","created_at":"2024-01-02T15:04:05Z","updated_at":"2024-01-02T15:04:05Z"}`, w.Body.String())
 }
 
 func TestGetAuthorByID_NotFound(t *testing.T) {
@@ -141,7 +210,8 @@ func TestGetAuthorByID_InvalidID(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	// Assert
-	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetAuthorById", mock.Anything)
 }
 
 func TestGetAuthorByID_InternalServerError(t *testing.T) {
@@ -162,3 +232,661 @@ func TestGetAuthorByID_InternalServerError(t *testing.T) {
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestCreateAuthor_Conflict(t *testing.T) {
+	// Arrange
+	mockService := new(MockAuthorService)
+	handler := NewAuthorHandler(mockService)
+	router := gin.Default()
+	router.POST("/authors", handler.CreateAuthor)
+
+	author := &Author{Name: "John Doe"}
+	mockService.On("CreateAuthor", mock.Anything, author).Return(errs.NewConflictError("Author", "authors_name_key", "name"))
+
+	req, _ := http.NewRequest("POST", "/authors", strings.NewReader(`{"name":"John Doe"}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code":409,"message":"Author already exists (constraint authors_name_key)"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateAuthor_InvalidBody(t *testing.T) {
+	// Arrange
+	mockService := new(MockAuthorService)
+	handler := NewAuthorHandler(mockService)
+	router := gin.Default()
+	router.POST("/authors", handler.CreateAuthor)
+
+	req, _ := http.NewRequest("POST", "/authors", strings.NewReader(`{"name":`))
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "CreateAuthor", mock.Anything)
+}
+
+// headerAuthenticator trusts "Authorization: Test <subject>[:<role>]" headers.
+// Without an explicit role the role is named like the subject.
+type headerAuthenticator struct{}
+
+func (headerAuthenticator) Scheme() string { return "Test" }
+
+func (headerAuthenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	subject, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Test ")
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	subject, role, ok := strings.Cut(subject, ":")
+	if !ok {
+		role = subject
+	}
+	return &auth.Principal{Subject: subject, Method: "test", Roles: []string{role}}, nil
+}
+
+func TestSetupRouter_MemoryRepository(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+
+	create := httptest.NewRecorder()
+	createReq, _ := http.NewRequest("POST", "/authors/", strings.NewReader(`{"name":"John Doe"}`))
+	createReq.Header.Set("Authorization", "Test editor")
+	get := httptest.NewRecorder()
+	getReq, _ := http.NewRequest("GET", "/authors/1", nil)
+
+	// Act
+	router.ServeHTTP(create, createReq)
+	router.ServeHTTP(get, getReq)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, create.Code)
+	assert.Equal(t, "/authors/1", create.Header().Get("Location"))
+	assert.Equal(t, http.StatusOK, get.Code)
+	assert.JSONEq(t, `{"id":1,"name":"John Doe","created_by":"test:editor","updated_by":"test:editor"}`, withoutTimestamps(t, get.Body.String()))
+	assert.JSONEq(t, get.Body.String(), create.Body.String())
+	assert.Equal(t, create.Header().Get("ETag"), get.Header().Get("ETag"))
+}
+
+// getWith sends an anonymous GET with the given header.
+func getWith(router *gin.Engine, path, header, value string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set(header, value)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupRouter_ConditionalGet(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+	first := serveAs(router, "", "GET", "/authors/1", "")
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+
+	// Act
+	cached := getWith(router, "/authors/1", "If-None-Match", etag)
+	weak := getWith(router, "/authors/1", "If-None-Match", `"other", W/`+etag)
+	stale := getWith(router, "/authors/1", "If-None-Match", `"other"`)
+	notModifiedSince := getWith(router, "/authors/1", "If-Modified-Since", lastModified)
+	modifiedSince := getWith(router, "/authors/1", "If-Modified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	list := getWith(router, "/authors/", "If-Modified-Since", lastModified)
+	require.Equal(t, http.StatusOK, patchAs(router, "editor", "application/merge-patch+json", "/authors/1", `{"nationality":"GB"}`).Code)
+	updated := getWith(router, "/authors/1", "If-None-Match", etag)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, cached.Code)
+	assert.Empty(t, cached.Body.String())
+	assert.Equal(t, etag, cached.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, weak.Code)
+	assert.Equal(t, http.StatusOK, stale.Code)
+	assert.Equal(t, http.StatusNotModified, notModifiedSince.Code)
+	assert.Equal(t, http.StatusOK, modifiedSince.Code)
+	assert.Equal(t, http.StatusOK, list.Code, "lists have no Last-Modified to compare against")
+	assert.Empty(t, list.Header().Get("Last-Modified"))
+	assert.Equal(t, http.StatusOK, updated.Code)
+	assert.NotEqual(t, etag, updated.Header().Get("ETag"))
+}
+
+func TestSetupRouter_WritesRequireAuthentication(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+
+	req, _ := http.NewRequest("POST", "/authors/", strings.NewReader(`{"name":"John Doe"}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Test", w.Header().Get("WWW-Authenticate"))
+}
+
+// withoutTimestamps checks that the author document in body has both
+// timestamps and returns it without them, for comparison with JSONEq.
+func withoutTimestamps(t *testing.T, body string) string {
+	t.Helper()
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	for _, field := range []string{"created_at", "updated_at"} {
+		stamp, _ := doc[field].(string)
+		_, err := time.Parse(time.RFC3339Nano, stamp)
+		assert.NoError(t, err, "%s must be an RFC 3339 timestamp", field)
+		delete(doc, field)
+	}
+	stripped, _ := json.Marshal(doc)
+	return string(stripped)
+}
+
+// serveAs sends a request authenticated as subject, or anonymously if subject is empty.
+func serveAs(router *gin.Engine, subject, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if subject != "" {
+		req.Header.Set("Authorization", "Test "+subject)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupRouter_ReaderCannotWrite(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+
+	// Act
+	read := serveAs(router, "reader", "GET", "/authors/", "")
+	write := serveAs(router, "reader", "POST", "/authors/", `{"name":"John Doe"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, read.Code)
+	assert.Equal(t, http.StatusForbidden, write.Code)
+	assert.JSONEq(t, `{"code":403,"message":"permission denied"}`, write.Body.String())
+}
+
+func TestSetupRouter_OwnershipRule(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	policy := authz.NewPolicyFromConfig(&config.Config{Authz: config.AuthzConfig{Ownership: true}})
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), policy)
+	require.Equal(t, http.StatusCreated, serveAs(router, "alice:editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+
+	// Act
+	byOtherEditor := serveAs(router, "bob:editor", "PUT", "/authors/1", `{"name":"Bobby Doe"}`)
+	byCreator := serveAs(router, "alice:editor", "PUT", "/authors/1", `{"name":"Johnny Doe"}`)
+	byAdmin := serveAs(router, "admin", "PUT", "/authors/1", `{"name":"J. Doe"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, byOtherEditor.Code)
+	assert.Equal(t, http.StatusOK, byCreator.Code)
+	assert.Equal(t, http.StatusOK, byAdmin.Code)
+}
+
+func TestDeleteAuthor_InUseIsConflict(t *testing.T) {
+	// Arrange
+	mockService := new(MockAuthorService)
+	handler := NewAuthorHandler(mockService)
+	router := gin.Default()
+	router.DELETE("/authors/:id", handler.DeleteAuthor)
+	mockService.On("DeleteAuthor", mock.Anything, 1).Return(errs.NewInUseError("Author", "book_authors_author_id_fkey"))
+
+	req, _ := http.NewRequest("DELETE", "/authors/1", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSetupRouter_DeleteAuthor(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+
+	// Act
+	byReader := serveAs(router, "reader", "DELETE", "/authors/1", "")
+	deleted := serveAs(router, "editor", "DELETE", "/authors/1", "")
+	again := serveAs(router, "editor", "DELETE", "/authors/1", "")
+	invalid := serveAs(router, "editor", "DELETE", "/authors/abc", "")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, byReader.Code)
+	assert.Equal(t, http.StatusNoContent, deleted.Code)
+	assert.Equal(t, http.StatusNotFound, again.Code)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	assert.Equal(t, http.StatusNotFound, serveAs(router, "", "GET", "/authors/1", "").Code)
+}
+
+func TestSetupRouter_PutReplacesAuthor(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/",
+		`{"name":"Ursula K. Le Guin","nationality":"us","links":[{"url":"https://www.ursulakleguin.com"}],"identifiers":{"wikidata":"Q181659"}}`).Code)
+
+	// Act
+	replaced := serveAs(router, "editor", "PUT", "/authors/1", `{"name":"Ursula Le Guin","identifiers":{"viaf":"93920661"}}`)
+	after := serveAs(router, "", "GET", "/authors/1", "")
+	otherID := serveAs(router, "editor", "PUT", "/authors/1", `{"id":2,"name":"Ursula Le Guin"}`)
+	noName := serveAs(router, "editor", "PUT", "/authors/1", `{"nationality":"US"}`)
+	invalid := serveAs(router, "editor", "PUT", "/authors/1", `{"name":"Ursula Le Guin","birth_date":"1929-13"}`)
+	missing := serveAs(router, "editor", "PUT", "/authors/2", `{"name":"Nobody"}`)
+	legacy := serveAs(router, "editor", "PUT", "/authors/", `{"id":1,"name":"Ursula Le Guin"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, replaced.Code)
+	want := `{"id":1,"name":"Ursula Le Guin","identifiers":{"viaf":"93920661"},"created_by":"test:editor","updated_by":"test:editor"}`
+	assert.JSONEq(t, want, withoutTimestamps(t, replaced.Body.String()))
+	assert.JSONEq(t, want, withoutTimestamps(t, after.Body.String()))
+	assert.Equal(t, http.StatusBadRequest, otherID.Code)
+	assert.Equal(t, http.StatusBadRequest, noName.Code)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Equal(t, http.StatusNotFound, legacy.Code)
+}
+
+func patchAs(router *gin.Engine, subject, contentType, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PATCH", path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Test "+subject)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupRouter_MergePatch(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/",
+		`{"name":"Ursula K. Le Guin","nationality":"US","biography":"Writer.","identifiers":{"wikidata":"Q181659"}}`).Code)
+	const mergePatch = "application/merge-patch+json"
+
+	// Act
+	patched := patchAs(router, "editor", mergePatch, "/authors/1", `{"biography":null,"identifiers":{"viaf":"93920661"},"created_by":"mallory"}`)
+	noName := patchAs(router, "editor", mergePatch, "/authors/1", `{"name":null}`)
+	invalid := patchAs(router, "editor", mergePatch+"; charset=utf-8", "/authors/1", `{"nationality":"XX"}`)
+	malformed := patchAs(router, "editor", mergePatch, "/authors/1", `{"name":`)
+	otherID := patchAs(router, "editor", mergePatch, "/authors/1", `{"id":2}`)
+	wrongType := patchAs(router, "editor", mergePatch, "/authors/1", `{"name":5}`)
+	plainJSON := patchAs(router, "editor", "application/json", "/authors/1", `{"name":"X"}`)
+	byReader := patchAs(router, "reader", mergePatch, "/authors/1", `{"name":"X"}`)
+	missing := patchAs(router, "editor", mergePatch, "/authors/2", `{"name":"X"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, patched.Code)
+	assert.JSONEq(t, `{"id":1,"name":"Ursula K. Le Guin","nationality":"US","identifiers":{"wikidata":"Q181659","viaf":"93920661"},
+		"created_by":"test:editor","updated_by":"test:editor"}`, withoutTimestamps(t, patched.Body.String()))
+	assert.Equal(t, http.StatusBadRequest, noName.Code)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	assert.Equal(t, http.StatusBadRequest, malformed.Code)
+	assert.Equal(t, http.StatusBadRequest, otherID.Code)
+	assert.Equal(t, http.StatusBadRequest, wrongType.Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, plainJSON.Code)
+	assert.Equal(t, "application/merge-patch+json, application/json-patch+json", plainJSON.Header().Get("Accept-Patch"))
+	assert.Equal(t, http.StatusForbidden, byReader.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.JSONEq(t, withoutTimestamps(t, patched.Body.String()), withoutTimestamps(t, serveAs(router, "", "GET", "/authors/1", "").Body.String()))
+}
+
+func TestSetupRouter_JSONPatch(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/",
+		`{"name":"Ursula K. Le Guin","links":[{"url":"https://www.ursulakleguin.com"}]}`).Code)
+	const jsonPatch = "application/json-patch+json"
+
+	// Act
+	patched := patchAs(router, "editor", jsonPatch, "/authors/1", `[
+		{"op":"test","path":"/name","value":"Ursula K. Le Guin"},
+		{"op":"replace","path":"/name","value":"Ursula Le Guin"},
+		{"op":"add","path":"/links/-","value":{"url":"https://en.wikipedia.org/wiki/Ursula_K._Le_Guin","label":"Wikipedia"}},
+		{"op":"add","path":"/nationality","value":"us"}
+	]`)
+	staleTest := patchAs(router, "editor", jsonPatch, "/authors/1", `[
+		{"op":"add","path":"/biography","value":"Writer."},
+		{"op":"test","path":"/name","value":"Ursula K. Le Guin"}
+	]`)
+	missingPath := patchAs(router, "editor", jsonPatch, "/authors/1", `[{"op":"remove","path":"/biography"}]`)
+	invalidResult := patchAs(router, "editor", jsonPatch, "/authors/1", `[{"op":"replace","path":"/links/0/url","value":"ftp://example.com"}]`)
+	malformed := patchAs(router, "editor", jsonPatch, "/authors/1", `{"op":"remove","path":"/name"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, patched.Code)
+	assert.JSONEq(t, `{"id":1,"name":"Ursula Le Guin","nationality":"US","links":[{"url":"https://www.ursulakleguin.com"},
+		{"url":"https://en.wikipedia.org/wiki/Ursula_K._Le_Guin","label":"Wikipedia"}],"created_by":"test:editor","updated_by":"test:editor"}`,
+		withoutTimestamps(t, patched.Body.String()))
+	assert.Equal(t, http.StatusConflict, staleTest.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, missingPath.Code)
+	assert.Equal(t, http.StatusBadRequest, invalidResult.Code)
+	assert.Equal(t, http.StatusBadRequest, malformed.Code)
+	assert.JSONEq(t, withoutTimestamps(t, patched.Body.String()), withoutTimestamps(t, serveAs(router, "", "GET", "/authors/1", "").Body.String()))
+}
+
+func TestSetupRouter_UpdatedSince(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+	var first Author
+	require.NoError(t, json.Unmarshal(serveAs(router, "", "GET", "/authors/1", "").Body.Bytes(), &first))
+	time.Sleep(2 * time.Millisecond)
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"Jane Smith"}`).Code)
+	since := first.UpdatedAt.Add(time.Microsecond).Format(time.RFC3339Nano)
+
+	// Act
+	delta := serveAs(router, "", "GET", "/authors/?updated_since="+since, "")
+	invalid := serveAs(router, "", "GET", "/authors/?updated_since=yesterday", "")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, delta.Code)
+	var authors []Author
+	require.NoError(t, json.Unmarshal(delta.Body.Bytes(), &authors))
+	require.Len(t, authors, 1)
+	assert.Equal(t, "Jane Smith", authors[0].Name)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+}
+
+func TestSetupRouter_HistoryAndRevert(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+	require.Equal(t, http.StatusOK, serveAs(router, "editor", "PUT", "/authors/1", `{"name":"Johnny Doe"}`).Code)
+
+	// Act
+	byReader := serveAs(router, "reader", "POST", "/authors/1/revert/1", "")
+	reverted := serveAs(router, "editor", "POST", "/authors/1/revert/1", "")
+	history := serveAs(router, "", "GET", "/authors/1/history", "")
+	revision := serveAs(router, "", "GET", "/authors/1/history/3", "")
+	diff := serveAs(router, "", "GET", "/authors/1/history/diff?from=2&to=3", "")
+	badDiff := serveAs(router, "", "GET", "/authors/1/history/diff?from=2", "")
+	missing := serveAs(router, "", "GET", "/authors/7/history", "")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, byReader.Code)
+	assert.Equal(t, http.StatusOK, reverted.Code)
+	assert.Contains(t, reverted.Body.String(), `"name":"John Doe"`)
+	var revisions []Revision
+	require.NoError(t, json.Unmarshal(history.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 3)
+	assert.Equal(t, OpRevert, revisions[2].Op)
+	assert.Equal(t, "test:editor", revisions[2].Actor)
+	assert.Equal(t, reverted.Header().Get(middleware.RequestIDHeader), revisions[2].RequestID)
+	assert.Equal(t, http.StatusOK, revision.Code)
+	assert.Contains(t, revision.Body.String(), `"op":"revert"`)
+	assert.JSONEq(t, `[{"field":"name","from":"Johnny Doe","to":"John Doe"}]`, diff.Body.String())
+	assert.Equal(t, http.StatusBadRequest, badDiff.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+// batchStatuses returns the status of every result of a batch response.
+func batchStatuses(t *testing.T, w *httptest.ResponseRecorder) []int {
+	t.Helper()
+	var response BatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	statuses := make([]int, len(response.Results))
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestSetupRouter_BatchIndependent(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"Jane Smith"}`).Code)
+
+	// Act
+	w := serveAs(router, "editor", "POST", "/authors:batch", `{"operations":[
+		{"op":"create","author":{"name":"Ursula K. Le Guin"}},
+		{"op":"update","id":1,"author":{"name":"Johnny Doe"}},
+		{"op":"delete","id":42},
+		{"op":"create","author":{"name":""}},
+		{"op":"delete","id":2},
+		{"op":"rename","id":1}
+	]}`)
+	all := serveAs(router, "", "GET", "/authors/", "")
+
+	// Assert
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, []int{201, 200, 404, 400, 204, 400}, batchStatuses(t, w))
+	assert.Contains(t, w.Body.String(), `"author":{"id":3,"name":"Ursula K. Le Guin"`)
+	var authors []Author
+	require.NoError(t, json.Unmarshal(all.Body.Bytes(), &authors))
+	require.Len(t, authors, 2)
+	assert.Equal(t, "Johnny Doe", authors[0].Name)
+	assert.Equal(t, "Ursula K. Le Guin", authors[1].Name)
+}
+
+func TestSetupRouter_BatchAtomic(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+
+	// Act
+	failed := serveAs(router, "editor", "POST", "/authors:batch", `{"atomic":true,"operations":[
+		{"op":"create","author":{"name":"Ursula K. Le Guin"}},
+		{"op":"update","id":1,"author":{"name":"Johnny Doe"}},
+		{"op":"delete","id":42}
+	]}`)
+	afterFailure := serveAs(router, "", "GET", "/authors/", "")
+	applied := serveAs(router, "editor", "POST", "/authors:batch", `{"atomic":true,"operations":[
+		{"op":"create","author":{"name":"Ursula K. Le Guin"}},
+		{"op":"update","id":1,"author":{"name":"Johnny Doe"}}
+	]}`)
+	duplicate := serveAs(router, "editor", "POST", "/authors:batch", `{"atomic":true,"operations":[
+		{"op":"update","id":1,"author":{"name":"J. Doe"}},
+		{"op":"delete","id":1}
+	]}`)
+	empty := serveAs(router, "editor", "POST", "/authors:batch", `{"operations":[]}`)
+	unknown := serveAs(router, "editor", "POST", "/authors:purge", `{}`)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, failed.Code)
+	assert.Equal(t, []int{424, 424, 404}, batchStatuses(t, failed))
+	assert.Contains(t, afterFailure.Body.String(), `"name":"John Doe"`)
+	assert.NotContains(t, afterFailure.Body.String(), "Ursula")
+	assert.Equal(t, http.StatusOK, applied.Code)
+	assert.Equal(t, []int{201, 200}, batchStatuses(t, applied))
+	assert.Equal(t, http.StatusBadRequest, duplicate.Code)
+	assert.Equal(t, []int{424, 400}, batchStatuses(t, duplicate))
+	assert.Equal(t, http.StatusBadRequest, empty.Code)
+	assert.Equal(t, http.StatusNotFound, unknown.Code)
+}
+
+func TestSetupRouter_BatchOwnershipRule(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	policy := authz.NewPolicyFromConfig(&config.Config{Authz: config.AuthzConfig{Ownership: true}})
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), policy)
+	require.Equal(t, http.StatusCreated, serveAs(router, "alice:editor", "POST", "/authors/", `{"name":"John Doe"}`).Code)
+	require.Equal(t, http.StatusCreated, serveAs(router, "bob:editor", "POST", "/authors/", `{"name":"Jane Smith"}`).Code)
+	const body = `{"atomic":%t,"operations":[
+		{"op":"update","id":1,"author":{"name":"Johnny Doe"}},
+		{"op":"delete","id":2}
+	]}`
+
+	// Act
+	atomic := serveAs(router, "bob:editor", "POST", "/authors:batch", fmt.Sprintf(body, true))
+	independent := serveAs(router, "bob:editor", "POST", "/authors:batch", fmt.Sprintf(body, false))
+	byAdmin := serveAs(router, "admin", "POST", "/authors:batch", `{"operations":[{"op":"delete","id":1}]}`)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, atomic.Code)
+	assert.Equal(t, []int{403, 424}, batchStatuses(t, atomic))
+	assert.Equal(t, http.StatusMultiStatus, independent.Code)
+	assert.Equal(t, []int{403, 204}, batchStatuses(t, independent))
+	assert.Equal(t, []int{204}, batchStatuses(t, byAdmin))
+}
+
+// importAs posts an import file authenticated as subject.
+func importAs(router *gin.Engine, subject, query, contentType string, file []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/authors/import"+query, bytes.NewReader(file))
+	req.Header.Set("Authorization", "Test "+subject)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// importReport decodes the report of an import response.
+func importReport(t *testing.T, w *httptest.ResponseRecorder) ImportReport {
+	t.Helper()
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report), w.Body.String())
+	return report
+}
+
+// listAuthors returns the authors the router lists, without the fields the
+// repository sets.
+func listAuthors(t *testing.T, router *gin.Engine) []Author {
+	t.Helper()
+	w := serveAs(router, "", "GET", "/authors/", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var authors []Author
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &authors))
+	for i := range authors {
+		authors[i].CreatedAt, authors[i].UpdatedAt = time.Time{}, time.Time{}
+		authors[i].CreatedBy, authors[i].UpdatedBy = "", ""
+	}
+	return authors
+}
+
+func TestSetupRouter_ExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON, FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			// Arrange
+			source := gin.New()
+			SetupRouter(source, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+			require.Equal(t, http.StatusCreated, serveAs(source, "editor", "POST", "/authors/", `{"name":"Ursula K. Le Guin",
+				"birth_date":"1929-10-21","nationality":"US","biography":"Wrote \"Earthsea\",\nand more",
+				"links":[{"url":"https://www.ursulakleguin.com","label":"Website"}],"identifiers":{"wikidata":"Q181659"}}`).Code)
+			require.Equal(t, http.StatusCreated, serveAs(source, "editor", "POST", "/authors/", `{"name":"Octavia E. Butler","death_date":"2006"}`).Code)
+			target := gin.New()
+			SetupRouter(target, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+
+			// Act
+			export := serveAs(source, "", "GET", "/authors/export?format="+format, "")
+			imported := importAs(target, "editor", "", export.Header().Get("Content-Type"), export.Body.Bytes())
+
+			// Assert
+			require.Equal(t, http.StatusOK, export.Code)
+			assert.Equal(t, `attachment; filename="authors.`+format+`"`, export.Header().Get("Content-Disposition"))
+			require.Equal(t, http.StatusOK, imported.Code, imported.Body.String())
+			assert.Equal(t, ImportReport{Applied: true, Rows: 2, Created: 2}, importReport(t, imported))
+			assert.Equal(t, listAuthors(t, source), listAuthors(t, target))
+		})
+	}
+}
+
+func TestSetupRouter_ExportCSV(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"John Doe","identifiers":{"orcid":"0000-0002-1825-0097"}}`).Code)
+
+	// Act
+	export := serveAs(router, "", "GET", "/authors/export", "")
+	unknown := serveAs(router, "", "GET", "/authors/export?format=pdf", "")
+
+	// Assert
+	require.Equal(t, http.StatusOK, export.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", export.Header().Get("Content-Type"))
+	lines := strings.Split(export.Body.String(), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, strings.Join(exportColumns, ","), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "1,John Doe,,,,,,,,,"), lines[1])
+	assert.Contains(t, lines[1], ",0000-0002-1825-0097,")
+	assert.Contains(t, lines[1], ",test:editor,test:editor")
+	assert.Equal(t, http.StatusBadRequest, unknown.Code)
+}
+
+func TestSetupRouter_ImportValidation(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), authz.NewPolicyFromConfig(&config.Config{}))
+	file := []byte("Full Name,Born,notes\nJohn Doe,1950-02-30,x\n,1950,\nJane Smith,1950-02-03,\n")
+	const mapping = "&map[name]=Full+Name&map[birth_date]=Born"
+
+	// Act
+	failed := importAs(router, "editor", "?format=csv"+mapping, "", file)
+	dryRun := importAs(router, "editor", "?format=csv&dry_run=true"+mapping, "", []byte("Full Name,Born\nJohn Doe,1950\n"))
+	unmapped := importAs(router, "editor", "?format=csv", "", file)
+	badMapping := importAs(router, "editor", "?format=csv&map[name]=Name", "", file)
+	noFormat := importAs(router, "editor", "", "application/octet-stream", file)
+	badDryRun := importAs(router, "editor", "?format=csv&dry_run=maybe", "", file)
+	byReader := importAs(router, "reader", "?format=csv", "", file)
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, failed.Code)
+	assert.Equal(t, ImportReport{Rows: 3, Created: 1, IgnoredColumns: []string{"notes"}, Errors: []ImportError{
+		{Row: 2, Field: "birth_date", Message: `"1950-02-30" is not a valid date`},
+		{Row: 3, Field: "name", Message: "must not be empty"},
+	}}, importReport(t, failed))
+	assert.Equal(t, http.StatusOK, dryRun.Code)
+	assert.Equal(t, ImportReport{DryRun: true, Rows: 1, Created: 1}, importReport(t, dryRun))
+	assert.Equal(t, http.StatusUnprocessableEntity, unmapped.Code)
+	assert.Equal(t, http.StatusBadRequest, badMapping.Code)
+	assert.Equal(t, http.StatusBadRequest, noFormat.Code)
+	assert.Equal(t, http.StatusBadRequest, badDryRun.Code)
+	assert.Equal(t, http.StatusForbidden, byReader.Code)
+	assert.Empty(t, listAuthors(t, router))
+}
+
+func TestSetupRouter_ImportUpsert(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	policy := authz.NewPolicyFromConfig(&config.Config{Authz: config.AuthzConfig{Ownership: true}})
+	SetupRouter(router, NewMemoryAuthorRepository(), auth.NewAuthentication(headerAuthenticator{}), policy)
+	require.Equal(t, http.StatusCreated, serveAs(router, "alice:editor", "POST", "/authors/", `{"name":"John Doe","nationality":"US","identifiers":{"wikidata":"Q1"}}`).Code)
+	file := []byte(`{"name":"Johnny Doe","identifiers":{"wikidata":"Q1"}}
+{"name":"Jane Smith","identifiers.wikidata":"Q2"}
+`)
+
+	// Act
+	byOtherEditor := importAs(router, "bob:editor", "?upsert_by=wikidata", "application/x-ndjson", file)
+	duplicate := importAs(router, "alice:editor", "?upsert_by=wikidata", "application/x-ndjson", append(file, file...))
+	badScheme := importAs(router, "alice:editor", "?upsert_by=isbn", "application/x-ndjson", file)
+	byCreator := importAs(router, "alice:editor", "?upsert_by=wikidata", "application/x-ndjson", file)
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, byOtherEditor.Code)
+	assert.Equal(t, []ImportError{{Row: 1, Message: errImportForbidden.Error()}}, importReport(t, byOtherEditor).Errors)
+	assert.Equal(t, http.StatusUnprocessableEntity, duplicate.Code)
+	assert.Equal(t, []ImportError{
+		{Row: 3, Field: "identifiers.wikidata", Message: `"Q1" is already in row 1`},
+		{Row: 4, Field: "identifiers.wikidata", Message: `"Q2" is already in row 2`},
+	}, importReport(t, duplicate).Errors)
+	assert.Equal(t, http.StatusBadRequest, badScheme.Code)
+	assert.Equal(t, http.StatusOK, byCreator.Code)
+	assert.Equal(t, ImportReport{Applied: true, Rows: 2, Created: 1, Updated: 1}, importReport(t, byCreator))
+	assert.Equal(t, []Author{
+		{ID: 1, Name: "Johnny Doe", Nationality: "US", Identifiers: Identifiers{"wikidata": "Q1"}},
+		{ID: 2, Name: "Jane Smith", Identifiers: Identifiers{"wikidata": "Q2"}},
+	}, listAuthors(t, router))
+}
@@ -0,0 +1,244 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryAuthorRepository struct {
+	mu        sync.RWMutex
+	authors   map[int]Author
+	revisions map[int][]Revision
+	nextID    int
+}
+
+// NewMemoryAuthorRepository returns a thread-safe AuthorRepository that keeps
+// authors in process memory. It is intended for local development and tests.
+func NewMemoryAuthorRepository() AuthorRepository {
+	return &memoryAuthorRepository{authors: make(map[int]Author), revisions: make(map[int][]Revision), nextID: 1}
+}
+
+func (m *memoryAuthorRepository) GetAllAuthors() ([]*Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	authors := make([]*Author, 0, len(m.authors))
+	for _, author := range m.authors {
+		author := author.clone()
+		authors = append(authors, &author)
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+	return authors, nil
+}
+
+func (m *memoryAuthorRepository) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	all, _ := m.GetAllAuthors()
+	authors := make([]*Author, 0, len(all))
+	for _, author := range all {
+		if !author.UpdatedAt.Before(since) {
+			authors = append(authors, author)
+		}
+	}
+	sort.SliceStable(authors, func(i, j int) bool { return authors[i].UpdatedAt.Before(authors[j].UpdatedAt) })
+	return authors, nil
+}
+
+func (m *memoryAuthorRepository) GetAuthorById(id int) (*Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	author, ok := m.authors[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	author = author.clone()
+	return &author, nil
+}
+
+func (m *memoryAuthorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.create(ctx, author)
+	return nil
+}
+
+// create stores a new author and records the revision. Callers hold mu.
+func (m *memoryAuthorRepository) create(ctx context.Context, author *Author) {
+	author.CreatedAt = now()
+	author.UpdatedAt = author.CreatedAt
+	author.CreatedBy = actor(ctx)
+	author.UpdatedBy = author.CreatedBy
+	author.ID = m.nextID
+	m.nextID++
+	m.authors[author.ID] = author.clone()
+	m.record(newRevision(ctx, OpCreate, author.ID, nil, author, author.UpdatedAt))
+}
+
+func (m *memoryAuthorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(ctx, OpUpdate, author, id)
+}
+
+// update stores author under id and records the revision. Callers hold mu.
+func (m *memoryAuthorRepository) update(ctx context.Context, op string, author *Author, id int) error {
+	existing, ok := m.authors[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	author.ID = id
+	author.CreatedAt = existing.CreatedAt
+	author.CreatedBy = existing.CreatedBy
+	author.UpdatedAt = now()
+	author.UpdatedBy = actor(ctx)
+	m.authors[id] = author.clone()
+	m.record(newRevision(ctx, op, id, &existing, author, author.UpdatedAt))
+	return nil
+}
+
+func (m *memoryAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.remove(ctx, id)
+}
+
+// remove deletes the author and records the revision. Callers hold mu.
+func (m *memoryAuthorRepository) remove(ctx context.Context, id int) error {
+	existing, ok := m.authors[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.authors, id)
+	m.record(newRevision(ctx, OpDelete, id, &existing, nil, now()))
+	return nil
+}
+
+func (m *memoryAuthorRepository) GetAuthorRevisions(id int) ([]*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := make([]*Revision, 0, len(m.revisions[id]))
+	for _, rev := range m.revisions[id] {
+		rev := rev.clone()
+		revisions = append(revisions, &rev)
+	}
+	return revisions, nil
+}
+
+func (m *memoryAuthorRepository) GetAuthorRevision(id, revision int) (*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := m.revisions[id]
+	if revision < 1 || revision > len(revisions) {
+		return nil, sql.ErrNoRows
+	}
+	rev := revisions[revision-1].clone()
+	return &rev, nil
+}
+
+func (m *memoryAuthorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.revisions[id]
+	if revision < 1 || revision > len(revisions) {
+		return nil, sql.ErrNoRows
+	}
+	author, err := revisions[revision-1].restore()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.update(ctx, OpRevert, author, id); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+func (m *memoryAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Check every operation before changing anything, so a failed batch
+	// leaves no trace.
+	found := make(map[int]*Author)
+	for _, op := range ops {
+		if existing, ok := m.authors[op.ID]; ok {
+			found[op.ID] = &existing
+		}
+	}
+	if err := missingAuthor(ops, found); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		switch op.Op {
+		case BatchCreate:
+			m.create(ctx, op.Author)
+		case BatchUpdate:
+			m.update(ctx, OpUpdate, op.Author, op.ID)
+		case BatchDelete:
+			m.remove(ctx, op.ID)
+		}
+	}
+	return nil
+}
+
+func (m *memoryAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]error, len(ops))
+	for i, op := range ops {
+		switch op.Op {
+		case BatchCreate:
+			m.create(ctx, op.Author)
+		case BatchUpdate:
+			results[i] = m.update(ctx, OpUpdate, op.Author, op.ID)
+		case BatchDelete:
+			results[i] = m.remove(ctx, op.ID)
+		}
+	}
+	return results, nil
+}
+
+func (m *memoryAuthorRepository) StreamAuthors(ctx context.Context, fn func(*Author) error) error {
+	// The authors are copied first so fn runs without holding the lock.
+	authors, _ := m.GetAllAuthors()
+	for _, author := range authors {
+		if err := fn(author); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryAuthorRepository) FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error) {
+	wanted := make(map[string]bool, len(values))
+	for _, value := range values {
+		wanted[value] = true
+	}
+	all, _ := m.GetAllAuthors()
+	authors := make([]*Author, 0, len(values))
+	for _, author := range all {
+		if value, ok := author.Identifiers[scheme]; ok && wanted[value] {
+			authors = append(authors, author)
+		}
+	}
+	return authors, nil
+}
+
+func (m *memoryAuthorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	return m.ApplyAuthorBatch(ctx, ops)
+}
+
+// record appends rev to its author's history. Callers hold mu.
+func (m *memoryAuthorRepository) record(rev *Revision) {
+	rev.Revision = len(m.revisions[rev.AuthorID]) + 1
+	m.revisions[rev.AuthorID] = append(m.revisions[rev.AuthorID], *rev)
+}
@@ -0,0 +1,50 @@
+//go:build integration
+
+package author_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/author/authortest"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultTestDSN matches the Postgres started by `make postgres-up`.
+const defaultTestDSN = "host=localhost port=5432 user=postgres password=mysecretpassword sslmode=disable"
+
+// newEphemeralPostgres creates a throwaway database on the server named by
+// POSTGRES_TEST_DSN, applies the migrations and drops it when the test ends.
+func newEphemeralPostgres(t *testing.T) *sqlx.DB {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		dsn = defaultTestDSN
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("postgres not available: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("author_contract_%d", time.Now().UnixNano())
+	admin.MustExec("CREATE DATABASE " + name)
+	t.Cleanup(func() { admin.MustExec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)") })
+
+	db, err := sqlx.Connect("postgres", dsn+" dbname="+name)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.Migrate(db))
+	return db
+}
+
+func TestPostgresAuthorRepository_Contract(t *testing.T) {
+	authortest.RunRepositoryContract(t, func(t *testing.T) author.AuthorRepository {
+		return author.NewAuthorRepository(newEphemeralPostgres(t))
+	})
+}
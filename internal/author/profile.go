@@ -0,0 +1,238 @@
+package author
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+const (
+	maxBiographyLength = 10000
+	maxLinks           = 20
+)
+
+// PartialDate is a date of which only the year, or the year and month, may be
+// known: "1929", "1929-10" or "1929-10-21". The format sorts like the dates
+// it stands for.
+type PartialDate string
+
+var partialDateLayouts = map[int]string{
+	len("2006"):       "2006",
+	len("2006-01"):    "2006-01",
+	len("2006-01-02"): "2006-01-02",
+}
+
+// Validate reports whether d is empty or a valid partial date.
+func (d PartialDate) Validate() error {
+	if d == "" {
+		return nil
+	}
+	layout, ok := partialDateLayouts[len(d)]
+	if !ok {
+		return fmt.Errorf("%q is not a date of the form YYYY, YYYY-MM or YYYY-MM-DD", string(d))
+	}
+	if _, err := time.Parse(layout, string(d)); err != nil {
+		return fmt.Errorf("%q is not a valid date", string(d))
+	}
+	return nil
+}
+
+// Before reports whether d certainly lies before other, comparing only the
+// precision both dates have.
+func (d PartialDate) Before(other PartialDate) bool {
+	n := len(d)
+	if len(other) < n {
+		n = len(other)
+	}
+	return d[:n] < other[:n]
+}
+
+// Links are stored as a JSON array.
+type Links []Link
+
+// Scan implements sql.Scanner. Empty arrays scan as nil.
+func (l *Links) Scan(value interface{}) error {
+	if err := scanJSON(value, l); err != nil {
+		return err
+	}
+	if len(*l) == 0 {
+		*l = nil
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (l Links) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Identifiers are stored as a JSON object.
+type Identifiers map[string]string
+
+// Scan implements sql.Scanner. Empty objects scan as nil.
+func (i *Identifiers) Scan(value interface{}) error {
+	if err := scanJSON(value, i); err != nil {
+		return err
+	}
+	if len(*i) == 0 {
+		*i = nil
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (i Identifiers) Value() (driver.Value, error) {
+	if i == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(i)
+	return string(b), err
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+	return fmt.Errorf("cannot scan %T as JSON", value)
+}
+
+// identifierSchemes validate and normalize an identifier of each supported
+// scheme.
+var identifierSchemes = map[string]func(string) (string, bool){
+	"isni":        normalizeISNI,
+	"orcid":       normalizeORCID,
+	"viaf":        matching(regexp.MustCompile(`^[1-9][0-9]{0,21}$`)),
+	"wikidata":    matching(regexp.MustCompile(`^Q[1-9][0-9]*$`)),
+	"lccn":        matching(regexp.MustCompile(`^[a-z]{1,3}[0-9]{8,10}$`)),
+	"openlibrary": matching(regexp.MustCompile(`^OL[1-9][0-9]*A$`)),
+}
+
+func matching(pattern *regexp.Regexp) func(string) (string, bool) {
+	return func(value string) (string, bool) {
+		return value, pattern.MatchString(value)
+	}
+}
+
+// normalizeISNI accepts an ISNI with or without spaces and returns it
+// without them.
+func normalizeISNI(value string) (string, bool) {
+	digits := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	return digits, validMod112(digits)
+}
+
+// normalizeORCID accepts an ORCID iD, bare or as an https://orcid.org URL,
+// and returns it in its hyphenated form.
+func normalizeORCID(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "https://orcid.org/"), "http://orcid.org/")
+	digits := strings.ToUpper(strings.ReplaceAll(value, "-", ""))
+	if !validMod112(digits) {
+		return "", false
+	}
+	return digits[0:4] + "-" + digits[4:8] + "-" + digits[8:12] + "-" + digits[12:16], true
+}
+
+// validMod112 checks a 16 character ISNI or ORCID against its ISO 7064
+// MOD 11-2 check character.
+func validMod112(digits string) bool {
+	if len(digits) != 16 {
+		return false
+	}
+	total := 0
+	for i := 0; i < 15; i++ {
+		c := digits[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		total = (total + int(c-'0')) * 2
+	}
+	check := (12 - total%11) % 11
+	want := byte('0' + check)
+	if check == 10 {
+		want = 'X'
+	}
+	return digits[15] == want
+}
+
+// validateProfile checks that author has a name, and normalizes the optional
+// profile fields in place and checks them.
+func validateProfile(author *Author) error {
+	if strings.TrimSpace(author.Name) == "" {
+		return errs.NewValidationError(resourceName, "name", "must not be empty")
+	}
+	author.SortName = strings.TrimSpace(author.SortName)
+	author.GivenName = strings.TrimSpace(author.GivenName)
+	author.FamilyName = strings.TrimSpace(author.FamilyName)
+	author.Biography = strings.TrimSpace(author.Biography)
+
+	for field, date := range map[string]PartialDate{"birth_date": author.BirthDate, "death_date": author.DeathDate} {
+		if err := date.Validate(); err != nil {
+			return errs.NewValidationError(resourceName, field, err.Error())
+		}
+	}
+	if author.BirthDate != "" && author.DeathDate != "" && author.DeathDate.Before(author.BirthDate) {
+		return errs.NewValidationError(resourceName, "death_date", "must not be before birth_date")
+	}
+
+	if author.Nationality != "" {
+		author.Nationality = strings.ToUpper(strings.TrimSpace(author.Nationality))
+		if !countryCodes[author.Nationality] {
+			return errs.NewValidationError(resourceName, "nationality", "must be an ISO 3166-1 alpha-2 country code")
+		}
+	}
+
+	if utf8.RuneCountInString(author.Biography) > maxBiographyLength {
+		return errs.NewValidationError(resourceName, "biography", fmt.Sprintf("must be at most %d characters", maxBiographyLength))
+	}
+
+	if len(author.Links) > maxLinks {
+		return errs.NewValidationError(resourceName, "links", fmt.Sprintf("must have at most %d entries", maxLinks))
+	}
+	for i := range author.Links {
+		link := &author.Links[i]
+		link.URL = strings.TrimSpace(link.URL)
+		link.Label = strings.TrimSpace(link.Label)
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errs.NewValidationError(resourceName, "links", fmt.Sprintf("%q is not an http or https URL", link.URL))
+		}
+	}
+
+	for scheme, value := range author.Identifiers {
+		normalize, ok := identifierSchemes[scheme]
+		if !ok {
+			return errs.NewValidationError(resourceName, "identifiers", fmt.Sprintf("unknown scheme %q, expected one of %s", scheme, strings.Join(supportedSchemes(), ", ")))
+		}
+		normalized, ok := normalize(strings.TrimSpace(value))
+		if !ok {
+			return errs.NewValidationError(resourceName, "identifiers", fmt.Sprintf("%q is not a valid %s identifier", value, scheme))
+		}
+		author.Identifiers[scheme] = normalized
+	}
+	return nil
+}
+
+func supportedSchemes() []string {
+	schemes := make([]string, 0, len(identifierSchemes))
+	for scheme := range identifierSchemes {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
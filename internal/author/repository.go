@@ -1,17 +1,78 @@
 package author
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/database"
 	"github.com/nilemarezz/go-init-template/pkg/logger"
 )
 
 type AuthorRepository interface {
 	GetAllAuthors() ([]*Author, error)
+	// GetAuthorsUpdatedSince returns the authors created or updated at or
+	// after since, oldest change first.
+	GetAuthorsUpdatedSince(since time.Time) ([]*Author, error)
 	GetAuthorById(id int) (*Author, error)
-	CreateAuthor(author *Author) error
-	UpdateAuthor(author *Author, id int) error
+	// CreateAuthor and UpdateAuthor stamp the audit fields of author with
+	// the current time and the principal in ctx, ignoring any values the
+	// caller set.
+	//
+	// CreateAuthor, UpdateAuthor, DeleteAuthor and RevertAuthor record a
+	// Revision in the same transaction as the change.
+	CreateAuthor(ctx context.Context, author *Author) error
+	UpdateAuthor(ctx context.Context, author *Author, id int) error
+	// DeleteAuthor removes an author, or returns an *errs.InUseError if other
+	// records still reference it.
+	DeleteAuthor(ctx context.Context, id int) error
+	// GetAuthorRevisions returns the revisions of an author, oldest first.
+	// It returns an empty slice for authors without history.
+	GetAuthorRevisions(id int) ([]*Revision, error)
+	GetAuthorRevision(id, revision int) (*Revision, error)
+	// RevertAuthor sets the author's fields back to those recorded after the
+	// given revision and returns the result. It returns sql.ErrNoRows if the
+	// author or the revision does not exist.
+	RevertAuthor(ctx context.Context, id, revision int) (*Author, error)
+	// ApplyAuthorBatch applies ops in one transaction, all of them or none.
+	// A failure that can be traced to one operation, such as an update of a
+	// missing author (sql.ErrNoRows), is returned as a *BatchError. Creates
+	// and updates fill in the authors of their operations like CreateAuthor
+	// and UpdateAuthor, and every operation records a Revision. ops must not
+	// update or delete the same author twice.
+	ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error
+	// ApplyAuthorOperations applies each of ops on its own, in one
+	// transaction: an operation that fails is rolled back to a savepoint
+	// taken before it, and the others are still applied. It returns the
+	// error of each operation, nil for those applied, as CreateAuthor,
+	// UpdateAuthor and DeleteAuthor would have returned it. The error is for
+	// failures that roll back the whole transaction. ops must not update or
+	// delete the same author twice.
+	ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error)
+	// StreamAuthors calls fn with each author, in id order, as the authors
+	// are read rather than after loading them all. It stops at the first
+	// error fn returns and returns it.
+	StreamAuthors(ctx context.Context, fn func(*Author) error) error
+	// FindAuthorsByIdentifier returns the authors whose identifier in scheme
+	// is one of values, in id order.
+	FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error)
+	// ImportAuthors applies the creates and updates in ops like
+	// ApplyAuthorBatch, all of them or none, but takes any number of them.
+	ImportAuthors(ctx context.Context, ops []BatchOperation) error
 }
 
+// authorColumns are selected for every author read.
+const authorColumns = `id, name, sort_name, given_name, family_name, birth_date, death_date,
+	nationality, biography, links, identifiers, created_at, updated_at, created_by, updated_by`
+
+// revisionColumns are selected for every revision read.
+const revisionColumns = `author_id, revision, op, before_snapshot, after_snapshot, actor, request_id, created_at`
+
 type authorRepository struct {
 	db *sqlx.DB
 }
@@ -20,33 +81,501 @@ func NewAuthorRepository(db *sqlx.DB) AuthorRepository {
 	return &authorRepository{db: db}
 }
 
+// NewRepository returns the AuthorRepository implementation for the configured
+// database driver. db is unused, and may be nil, for the memory driver.
+func NewRepository(driver string, db *sqlx.DB) (AuthorRepository, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewAuthorRepository(db), nil
+	case database.DriverSQLite:
+		return NewSQLiteAuthorRepository(db)
+	case database.DriverMemory:
+		return NewMemoryAuthorRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
 func (a authorRepository) GetAllAuthors() ([]*Author, error) {
-	var authors []*Author
+	authors := []*Author{}
 	logger.Info("query get all loggers")
-	err := a.db.Select(&authors, "SELECT id, name FROM authors")
-	return authors, err
+	err := a.db.Select(&authors, "SELECT "+authorColumns+" FROM authors ORDER BY id")
+	return authors, errs.FromPostgres(err, resourceName)
+}
+
+func (a authorRepository) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	authors := []*Author{}
+	err := a.db.Select(&authors, "SELECT "+authorColumns+" FROM authors WHERE updated_at >= $1 ORDER BY updated_at, id", since)
+	return authors, errs.FromPostgres(err, resourceName)
 }
 
 func (a authorRepository) GetAuthorById(id int) (*Author, error) {
 	var author Author
-	err := a.db.Get(&author, "SELECT * FROM authors WHERE id = $1", id)
-	return &author, err
+	err := a.db.Get(&author, "SELECT "+authorColumns+" FROM authors WHERE id = $1", id)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &author, nil
 }
 
-func (a authorRepository) CreateAuthor(author *Author) error {
-	// Insert the new author into the database
-	_, err := a.db.Exec("INSERT INTO authors (name) VALUES ($1)", author.Name)
+func (a authorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return createAuthor(ctx, tx, author)
+	})
+	return errs.FromPostgres(err, resourceName)
+}
+
+// createAuthor inserts the author and records the revision. The database
+// clock stamps both timestamps.
+func createAuthor(ctx context.Context, tx *sqlx.Tx, author *Author) error {
+	author.CreatedBy = actor(ctx)
+	author.UpdatedBy = author.CreatedBy
+	err := tx.QueryRowxContext(ctx, `INSERT INTO authors (name, sort_name, given_name, family_name, birth_date, death_date,
+		nationality, biography, links, identifiers, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11) RETURNING id, created_at, updated_at`,
+		author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate, author.DeathDate,
+		author.Nationality, author.Biography, author.Links, author.Identifiers, author.CreatedBy,
+	).Scan(&author.ID, &author.CreatedAt, &author.UpdatedAt)
 	if err != nil {
 		return err
 	}
-	return nil
+	return insertRevision(ctx, tx, newRevision(ctx, OpCreate, author.ID, nil, author, author.UpdatedAt))
 }
 
-func (a authorRepository) UpdateAuthor(author *Author, id int) error {
-	// Update the author in the database
-	_, err := a.db.Exec("UPDATE authors SET name = $1 WHERE id = $2", author.Name, id)
+func (a authorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return updateAuthor(ctx, tx, OpUpdate, author, id)
+	})
+	return errs.FromPostgres(err, resourceName)
+}
+
+// updateAuthor updates the author in the database and records the revision.
+// The creation fields are read back so author describes the stored row.
+func updateAuthor(ctx context.Context, tx *sqlx.Tx, op string, author *Author, id int) error {
+	var before Author
+	if err := tx.GetContext(ctx, &before, "SELECT "+authorColumns+" FROM authors WHERE id = $1 FOR UPDATE", id); err != nil {
+		return err
+	}
+	author.ID = id
+	author.UpdatedBy = actor(ctx)
+	err := tx.QueryRowxContext(ctx, `UPDATE authors SET name = $1, sort_name = $2, given_name = $3, family_name = $4,
+		birth_date = $5, death_date = $6, nationality = $7, biography = $8, links = $9, identifiers = $10,
+		updated_at = now(), updated_by = $11
+		WHERE id = $12 RETURNING created_at, updated_at, created_by`,
+		author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate, author.DeathDate,
+		author.Nationality, author.Biography, author.Links, author.Identifiers, author.UpdatedBy, id,
+	).Scan(&author.CreatedAt, &author.UpdatedAt, &author.CreatedBy)
 	if err != nil {
 		return err
 	}
+	return insertRevision(ctx, tx, newRevision(ctx, op, id, &before, author, author.UpdatedAt))
+}
+
+func (a authorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteAuthor(ctx, tx, id)
+	})
+	return errs.FromPostgresDelete(err, resourceName)
+}
+
+// deleteAuthor deletes the author and records the revision.
+func deleteAuthor(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var before Author
+	if err := tx.GetContext(ctx, &before, "SELECT "+authorColumns+" FROM authors WHERE id = $1 FOR UPDATE", id); err != nil {
+		return err
+	}
+	var deletedAt time.Time
+	if err := tx.GetContext(ctx, &deletedAt, "DELETE FROM authors WHERE id = $1 RETURNING now()", id); err != nil {
+		return err
+	}
+	return insertRevision(ctx, tx, newRevision(ctx, OpDelete, id, &before, nil, deletedAt))
+}
+
+func (a authorRepository) GetAuthorRevisions(id int) ([]*Revision, error) {
+	revisions := []*Revision{}
+	err := a.db.Select(&revisions, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = $1 ORDER BY revision", id)
+	return revisions, errs.FromPostgres(err, resourceName)
+}
+
+func (a authorRepository) GetAuthorRevision(id, revision int) (*Revision, error) {
+	var rev Revision
+	err := a.db.Get(&rev, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = $1 AND revision = $2", id, revision)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &rev, nil
+}
+
+func (a authorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	var author *Author
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		var rev Revision
+		err := tx.GetContext(ctx, &rev, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = $1 AND revision = $2", id, revision)
+		if err != nil {
+			return err
+		}
+		if author, err = rev.restore(); err != nil {
+			return err
+		}
+		return updateAuthor(ctx, tx, OpRevert, author, id)
+	})
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return author, nil
+}
+
+func (a authorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	plan := planBatch(ops)
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		// now() is the start of the transaction, so every change in the
+		// batch gets the same timestamp.
+		var at time.Time
+		if err := tx.GetContext(ctx, &at, "SELECT now()"); err != nil {
+			return err
+		}
+
+		before, latest, err := lockBatchAuthors(ctx, tx, ops, plan.existing)
+		if err != nil {
+			return err
+		}
+		revisions := &batchRevisions{ctx: ctx, at: at, latest: latest}
+
+		if len(plan.deletes) > 0 {
+			ids := make([]int, len(plan.deletes))
+			for i, index := range plan.deletes {
+				ids[i] = ops[index].ID
+				revisions.add(OpDelete, ids[i], before[ids[i]], nil)
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM authors WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+				return errs.FromPostgresDelete(err, resourceName)
+			}
+		}
+
+		if len(plan.updates) > 0 {
+			args := make([]interface{}, 0, len(plan.updates)*13)
+			for _, index := range plan.updates {
+				author, id := ops[index].Author, ops[index].ID
+				author.ID = id
+				author.CreatedAt = before[id].CreatedAt
+				author.CreatedBy = before[id].CreatedBy
+				author.UpdatedAt = at
+				author.UpdatedBy = actor(ctx)
+				args = append(args, id, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+					author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers, at, author.UpdatedBy)
+				revisions.add(OpUpdate, id, before[id], author)
+			}
+			_, err := tx.ExecContext(ctx, `UPDATE authors AS a SET name = v.name, sort_name = v.sort_name,
+				given_name = v.given_name, family_name = v.family_name, birth_date = v.birth_date, death_date = v.death_date,
+				nationality = v.nationality, biography = v.biography, links = v.links, identifiers = v.identifiers,
+				updated_at = v.updated_at, updated_by = v.updated_by
+				FROM (VALUES `+postgresValues(len(plan.updates), 13, "integer", "text", "text", "text", "text", "text",
+				"text", "text", "text", "jsonb", "jsonb", "timestamptz", "text")+`)
+				AS v(id, name, sort_name, given_name, family_name, birth_date, death_date, nationality, biography,
+					links, identifiers, updated_at, updated_by)
+				WHERE a.id = v.id`, args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plan.creates) > 0 {
+			// Take the ids up front so each row of the insert is known to
+			// belong to its operation.
+			var ids []int
+			err := tx.SelectContext(ctx, &ids, "SELECT nextval(pg_get_serial_sequence('authors', 'id')) FROM generate_series(1, $1)",
+				len(plan.creates))
+			if err != nil {
+				return err
+			}
+			args := make([]interface{}, 0, len(plan.creates)*15)
+			for i, index := range plan.creates {
+				author := ops[index].Author
+				author.ID = ids[i]
+				author.CreatedAt, author.UpdatedAt = at, at
+				author.CreatedBy = actor(ctx)
+				author.UpdatedBy = author.CreatedBy
+				args = append(args, author.ID, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+					author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers,
+					at, at, author.CreatedBy, author.UpdatedBy)
+				revisions.add(OpCreate, author.ID, nil, author)
+			}
+			_, err = tx.ExecContext(ctx, `INSERT INTO authors (id, name, sort_name, given_name, family_name, birth_date,
+				death_date, nationality, biography, links, identifiers, created_at, updated_at, created_by, updated_by)
+				VALUES `+postgresValues(len(plan.creates), 15), args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		args := make([]interface{}, 0, len(revisions.revisions)*8)
+		for _, rev := range revisions.revisions {
+			args = append(args, rev.AuthorID, rev.Revision, rev.Op, rev.Before, rev.After, rev.Actor, rev.RequestID, rev.CreatedAt)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO author_revisions
+			(author_id, revision, op, before_snapshot, after_snapshot, actor, request_id, created_at)
+			VALUES `+postgresValues(len(revisions.revisions), 8), args...)
+		return err
+	})
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (a authorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	var results []error
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		// Lock the authors up front, in id order, so that concurrent
+		// batches cannot deadlock.
+		if ids := planBatch(ops).existing; len(ids) > 0 {
+			_, err := tx.ExecContext(ctx, "SELECT id FROM authors WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(ids))
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		results, err = applyEach(ctx, tx, ops, func(op BatchOperation) error {
+			switch op.Op {
+			case BatchCreate:
+				return errs.FromPostgres(createAuthor(ctx, tx, op.Author), resourceName)
+			case BatchUpdate:
+				return errs.FromPostgres(updateAuthor(ctx, tx, OpUpdate, op.Author, op.ID), resourceName)
+			}
+			return errs.FromPostgresDelete(deleteAuthor(ctx, tx, op.ID), resourceName)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return results, nil
+}
+
+// lockBatchAuthors locks the authors with the given ids, in id order so that
+// concurrent batches cannot deadlock, and returns them with their last
+// revision numbers. It returns a *BatchError if any of them is missing.
+func lockBatchAuthors(ctx context.Context, tx *sqlx.Tx, ops []BatchOperation, ids []int) (map[int]*Author, map[int]int, error) {
+	var existing []*Author
+	err := tx.SelectContext(ctx, &existing, "SELECT "+authorColumns+" FROM authors WHERE id = ANY($1) ORDER BY id FOR UPDATE",
+		pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	before := make(map[int]*Author, len(existing))
+	for _, author := range existing {
+		before[author.ID] = author
+	}
+	if err := missingAuthor(ops, before); err != nil {
+		return nil, nil, err
+	}
+	var latest []struct {
+		AuthorID int `db:"author_id"`
+		Revision int `db:"revision"`
+	}
+	err = tx.SelectContext(ctx, &latest, "SELECT author_id, MAX(revision) AS revision FROM author_revisions WHERE author_id = ANY($1) GROUP BY author_id",
+		pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	latestRevision := make(map[int]int, len(latest))
+	for _, l := range latest {
+		latestRevision[l.AuthorID] = l.Revision
+	}
+	return before, latestRevision, nil
+}
+
+func (a authorRepository) StreamAuthors(ctx context.Context, fn func(*Author) error) error {
+	err := streamAuthors(ctx, a.db, "SELECT "+authorColumns+" FROM authors ORDER BY id", fn)
+	return errs.FromPostgres(err, resourceName)
+}
+
+// streamAuthors runs query and calls fn with each author it returns.
+func streamAuthors(ctx context.Context, db *sqlx.DB, query string, fn func(*Author) error) error {
+	rows, err := db.QueryxContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author Author
+		if err := rows.StructScan(&author); err != nil {
+			return err
+		}
+		if err := fn(&author); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (a authorRepository) FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error) {
+	authors := []*Author{}
+	err := a.db.Select(&authors, "SELECT "+authorColumns+" FROM authors WHERE identifiers ->> $1 = ANY($2) ORDER BY id",
+		scheme, pq.Array(values))
+	return authors, errs.FromPostgres(err, resourceName)
+}
+
+// importUpdateColumns are staged in a temporary table by ImportAuthors.
+var importUpdateColumns = []string{"id", "name", "sort_name", "given_name", "family_name", "birth_date", "death_date",
+	"nationality", "biography", "links", "identifiers", "updated_at", "updated_by"}
+
+// ImportAuthors loads the rows with COPY rather than with INSERT statements,
+// which is much faster for large imports and has no limit on bind
+// parameters. Updates are copied into a temporary table and applied from it
+// with one UPDATE.
+func (a authorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	plan := planBatch(ops)
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		var at time.Time
+		if err := tx.GetContext(ctx, &at, "SELECT now()"); err != nil {
+			return err
+		}
+		before, latest, err := lockBatchAuthors(ctx, tx, ops, plan.existing)
+		if err != nil {
+			return err
+		}
+		revisions := &batchRevisions{ctx: ctx, at: at, latest: latest}
+
+		if len(plan.updates) > 0 {
+			rows := make([][]interface{}, len(plan.updates))
+			for i, index := range plan.updates {
+				author, id := ops[index].Author, ops[index].ID
+				author.ID = id
+				author.CreatedAt = before[id].CreatedAt
+				author.CreatedBy = before[id].CreatedBy
+				author.UpdatedAt = at
+				author.UpdatedBy = actor(ctx)
+				rows[i] = []interface{}{id, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+					author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers, at, author.UpdatedBy}
+				revisions.add(OpUpdate, id, before[id], author)
+			}
+			_, err := tx.ExecContext(ctx, `CREATE TEMPORARY TABLE author_import (id integer PRIMARY KEY, name text, sort_name text,
+				given_name text, family_name text, birth_date text, death_date text, nationality text, biography text,
+				links jsonb, identifiers jsonb, updated_at timestamptz, updated_by text) ON COMMIT DROP`)
+			if err != nil {
+				return err
+			}
+			if err := copyIn(ctx, tx, "author_import", importUpdateColumns, rows); err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE authors AS a SET name = v.name, sort_name = v.sort_name,
+				given_name = v.given_name, family_name = v.family_name, birth_date = v.birth_date, death_date = v.death_date,
+				nationality = v.nationality, biography = v.biography, links = v.links, identifiers = v.identifiers,
+				updated_at = v.updated_at, updated_by = v.updated_by
+				FROM author_import AS v WHERE a.id = v.id`)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plan.creates) > 0 {
+			var ids []int
+			err := tx.SelectContext(ctx, &ids, "SELECT nextval(pg_get_serial_sequence('authors', 'id')) FROM generate_series(1, $1)",
+				len(plan.creates))
+			if err != nil {
+				return err
+			}
+			rows := make([][]interface{}, len(plan.creates))
+			for i, index := range plan.creates {
+				author := ops[index].Author
+				author.ID = ids[i]
+				author.CreatedAt, author.UpdatedAt = at, at
+				author.CreatedBy = actor(ctx)
+				author.UpdatedBy = author.CreatedBy
+				rows[i] = []interface{}{author.ID, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+					author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers,
+					at, at, author.CreatedBy, author.UpdatedBy}
+				revisions.add(OpCreate, author.ID, nil, author)
+			}
+			err = copyIn(ctx, tx, "authors", []string{"id", "name", "sort_name", "given_name", "family_name", "birth_date",
+				"death_date", "nationality", "biography", "links", "identifiers", "created_at", "updated_at", "created_by", "updated_by"}, rows)
+			if err != nil {
+				return err
+			}
+		}
+
+		rows := make([][]interface{}, len(revisions.revisions))
+		for i, rev := range revisions.revisions {
+			rows[i] = []interface{}{rev.AuthorID, rev.Revision, rev.Op, rev.Before, rev.After, rev.Actor, rev.RequestID, rev.CreatedAt}
+		}
+		return copyIn(ctx, tx, "author_revisions", []string{"author_id", "revision", "op", "before_snapshot", "after_snapshot",
+			"actor", "request_id", "created_at"}, rows)
+	})
+	return errs.FromPostgres(err, resourceName)
+}
+
+// copyIn loads rows into the columns of table with COPY FROM STDIN.
+func copyIn(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	// The final call without values flushes the data and ends the COPY.
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// insertRevision records rev under the next revision number of its author.
+// Callers hold the author's row lock, or have just created the row, so the
+// number cannot be taken concurrently.
+func insertRevision(ctx context.Context, tx *sqlx.Tx, rev *Revision) error {
+	err := tx.GetContext(ctx, &rev.Revision, "SELECT COALESCE(MAX(revision), 0) + 1 FROM author_revisions WHERE author_id = $1", rev.AuthorID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO author_revisions
+		(author_id, revision, op, before_snapshot, after_snapshot, actor, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		rev.AuthorID, rev.Revision, rev.Op, rev.Before, rev.After, rev.Actor, rev.RequestID, rev.CreatedAt)
+	return err
+}
+
+// inTx runs fn in a transaction, leaving error translation to the caller.
+func (a authorRepository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// actor returns the method-qualified subject of the principal in ctx, or ""
+// for anonymous writes.
+func actor(ctx context.Context) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return principal.Owner()
+	}
+	return ""
+}
+
+// now is the clock used by the repositories that stamp the audit fields
+// themselves. Times are kept in UTC at microsecond precision, like Postgres.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// requireRowsAffected returns sql.ErrNoRows when a write statement matched no
+// rows, so every implementation reports a missing author the same way.
+func requireRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
@@ -0,0 +1,39 @@
+package author_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/author/authortest"
+	"github.com/nilemarezz/go-init-template/pkg/cache"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestMemoryAuthorRepository_Contract(t *testing.T) {
+	authortest.RunRepositoryContract(t, func(t *testing.T) author.AuthorRepository {
+		return author.NewMemoryAuthorRepository()
+	})
+}
+
+func TestSQLiteAuthorRepository_Contract(t *testing.T) {
+	authortest.RunRepositoryContract(t, func(t *testing.T) author.AuthorRepository {
+		db, err := sqlx.Connect("sqlite", filepath.Join(t.TempDir(), "authors.db")+"?_pragma=busy_timeout(5000)")
+		require.NoError(t, err)
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+
+		repo, err := author.NewSQLiteAuthorRepository(db)
+		require.NoError(t, err)
+		return repo
+	})
+}
+
+func TestCachedAuthorRepository_Contract(t *testing.T) {
+	authortest.RunRepositoryContract(t, func(t *testing.T) author.AuthorRepository {
+		return author.NewCachedAuthorRepository(author.NewMemoryAuthorRepository(), cache.NewLRU(100), time.Minute, time.Minute)
+	})
+}
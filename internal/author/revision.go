@@ -0,0 +1,156 @@
+package author
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/internal/middleware"
+)
+
+// Revision operations.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpRevert = "revert"
+)
+
+// Revision records one change to an author. Revisions are numbered from 1 per
+// author and are kept after the author is deleted.
+type Revision struct {
+	AuthorID int    `db:"author_id" json:"author_id" example:"1"`
+	Revision int    `db:"revision" json:"revision" example:"2"`
+	Op       string `db:"op" json:"op" enums:"create,update,delete,revert" example:"update"`
+	// Before and After are the author as stored before and after the change.
+	// Before is absent for creates and After for deletes.
+	Before    *Snapshot `db:"before_snapshot" json:"before,omitempty"`
+	After     *Snapshot `db:"after_snapshot" json:"after,omitempty"`
+	Actor     string    `db:"actor" json:"actor,omitempty" example:"user-1"`
+	RequestID string    `db:"request_id" json:"request_id,omitempty" example:"5f2b8c1e9d4a7b3c6e0f1a2b3c4d5e6f"`
+	CreatedAt time.Time `db:"created_at" json:"created_at" example:"2024-01-02T15:04:05Z"`
+}
+
+// Snapshot is an Author as recorded in a revision. It is stored as JSON.
+type Snapshot Author
+
+// Scan implements sql.Scanner.
+func (s *Snapshot) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+// Value implements driver.Valuer.
+func (s *Snapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// snapshot copies author for a revision, or returns nil for a missing one.
+func snapshot(author *Author) *Snapshot {
+	if author == nil {
+		return nil
+	}
+	s := Snapshot(author.clone())
+	return &s
+}
+
+// clone copies the revision and its snapshots.
+func (r *Revision) clone() Revision {
+	c := *r
+	c.Before = snapshot((*Author)(r.Before))
+	c.After = snapshot((*Author)(r.After))
+	return c
+}
+
+// restore returns the author as it stood after the revision, for reverting
+// to it. Revisions that deleted the author cannot be restored.
+func (r *Revision) restore() (*Author, error) {
+	if r.After == nil {
+		return nil, errs.NewValidationError(resourceName, "revision", fmt.Sprintf("revision %d deleted the author and cannot be restored", r.Revision))
+	}
+	author := Author(*r.After)
+	author = author.clone()
+	return &author, nil
+}
+
+// newRevision describes a change made on behalf of the principal and request
+// in ctx. The repository assigns the revision number.
+func newRevision(ctx context.Context, op string, id int, before, after *Author, at time.Time) *Revision {
+	return &Revision{
+		AuthorID:  id,
+		Op:        op,
+		Before:    snapshot(before),
+		After:     snapshot(after),
+		Actor:     actor(ctx),
+		RequestID: middleware.RequestIDFrom(ctx),
+		CreatedAt: at,
+	}
+}
+
+// FieldChange is a field that differs between two revisions. From or To is
+// absent when the field was empty on that side.
+type FieldChange struct {
+	Field string          `json:"field" example:"name"`
+	From  json.RawMessage `json:"from,omitempty" swaggertype:"object"`
+	To    json.RawMessage `json:"to,omitempty" swaggertype:"object"`
+}
+
+// diffIgnoredFields change on every write, and are reported by the revision
+// itself rather than in the diff.
+var diffIgnoredFields = map[string]bool{
+	"id": true, "created_at": true, "updated_at": true, "created_by": true, "updated_by": true,
+}
+
+// DiffRevisions compares the author as it stood after from with the author as
+// it stood after to, field by field, in field name order.
+func DiffRevisions(from, to *Revision) ([]FieldChange, error) {
+	before, err := snapshotFields(from.After)
+	if err != nil {
+		return nil, err
+	}
+	after, err := snapshotFields(to.After)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	changes := []FieldChange{}
+	for name := range names {
+		if diffIgnoredFields[name] || bytes.Equal(before[name], after[name]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, From: before[name], To: after[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// snapshotFields returns the JSON encoding of each non-empty field of s.
+func snapshotFields(s *Snapshot) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if s == nil {
+		return fields, nil
+	}
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
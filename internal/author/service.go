@@ -1,18 +1,41 @@
 package author
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/nilemarezz/go-init-template/internal/errs"
 )
 
 type AuthorService interface {
 	GetAllAuthors() ([]*Author, error)
+	GetAuthorsUpdatedSince(since time.Time) ([]*Author, error)
 	GetAuthorById(id int) (*Author, error)
-	CreateAuthor(author *Author) error
-	UpdateAuthor(author *Author, id int) error
+	CreateAuthor(ctx context.Context, author *Author) error
+	UpdateAuthor(ctx context.Context, author *Author, id int) error
+	DeleteAuthor(ctx context.Context, id int) error
+	GetAuthorHistory(id int) ([]*Revision, error)
+	GetAuthorRevision(id, revision int) (*Revision, error)
+	DiffAuthorRevisions(id, from, to int) ([]FieldChange, error)
+	RevertAuthor(ctx context.Context, id, revision int) (*Author, error)
+	ApplyAuthorBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+	ExportAuthors(ctx context.Context, format string, w io.Writer) error
+	ImportAuthors(ctx context.Context, file io.ReaderAt, size int64, options ImportOptions) (*ImportReport, error)
 }
 
+// errImportForbidden is reported for rows that would update an author the
+// caller may not modify.
+var errImportForbidden = errors.New("the author this row matches may not be updated by the caller")
+
+// revisionResourceName is the name used for author revisions in domain
+// errors.
+const revisionResourceName = "Author revision"
+
 type authorService struct {
 	repo AuthorRepository
 }
@@ -25,6 +48,10 @@ func (a authorService) GetAllAuthors() ([]*Author, error) {
 	return a.repo.GetAllAuthors()
 }
 
+func (a authorService) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	return a.repo.GetAuthorsUpdatedSince(since)
+}
+
 func (a authorService) GetAuthorById(id int) (*Author, error) {
 	author, err := a.repo.GetAuthorById(id)
 
@@ -38,11 +65,18 @@ func (a authorService) GetAuthorById(id int) (*Author, error) {
 	return author, nil
 }
 
-func (a authorService) CreateAuthor(author *Author) error {
-	return a.repo.CreateAuthor(author)
+func (a authorService) CreateAuthor(ctx context.Context, author *Author) error {
+	if err := validateProfile(author); err != nil {
+		return err
+	}
+	return a.repo.CreateAuthor(ctx, author)
 }
 
-func (a authorService) UpdateAuthor(author *Author, id int) error {
+func (a authorService) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	if err := validateProfile(author); err != nil {
+		return err
+	}
+
 	// Check if author exists
 	_, err := a.repo.GetAuthorById(id)
 	if err != nil {
@@ -53,10 +87,343 @@ func (a authorService) UpdateAuthor(author *Author, id int) error {
 	}
 
 	// Update author
-	err = a.repo.UpdateAuthor(author, id)
+	err = a.repo.UpdateAuthor(ctx, author, id)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return errs.NewNotFoundError("Author")
+		}
 		return err
 	}
 
 	return nil
 }
+
+func (a authorService) DeleteAuthor(ctx context.Context, id int) error {
+	err := a.repo.DeleteAuthor(ctx, id)
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError("Author")
+	}
+	return err
+}
+
+// GetAuthorHistory returns the revisions of an author, oldest first. Deleted
+// authors keep their history; authors that never existed are not found.
+func (a authorService) GetAuthorHistory(id int) ([]*Revision, error) {
+	revisions, err := a.repo.GetAuthorRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		// Authors created before history was recorded have none yet.
+		if _, err := a.GetAuthorById(id); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+func (a authorService) GetAuthorRevision(id, revision int) (*Revision, error) {
+	rev, err := a.repo.GetAuthorRevision(id, revision)
+	if err == sql.ErrNoRows {
+		return nil, errs.NewNotFoundError(revisionResourceName)
+	}
+	return rev, err
+}
+
+// DiffAuthorRevisions compares the author as it stood after revision from
+// with the author as it stood after revision to.
+func (a authorService) DiffAuthorRevisions(id, from, to int) ([]FieldChange, error) {
+	fromRev, err := a.GetAuthorRevision(id, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := a.GetAuthorRevision(id, to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffRevisions(fromRev, toRev)
+}
+
+// RevertAuthor restores the author to its state after the given revision,
+// recording the change as a new revision.
+func (a authorService) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	if _, err := a.GetAuthorRevision(id, revision); err != nil {
+		return nil, err
+	}
+	author, err := a.repo.RevertAuthor(ctx, id, revision)
+	if err == sql.ErrNoRows {
+		return nil, errs.NewNotFoundError("Author")
+	}
+	return author, err
+}
+
+// ApplyAuthorBatch validates and applies ops, returning one result per
+// operation. An atomic batch is applied all or none: when an operation fails,
+// the others fail with ErrBatchAborted. Otherwise each operation that can be
+// applied is, and only those that cannot fail. The error is for failures that
+// cannot be put down to one operation.
+func (a authorService) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if err := ValidateBatchSize(len(ops)); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(ops))
+	pending := make([]int, 0, len(ops))
+	seen := make(map[int]bool, len(ops))
+	for i, op := range ops {
+		if err := validateBatchOperation(op, seen); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+	if atomic && len(pending) < len(ops) {
+		return abortBatch(results), nil
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+	batch := make([]BatchOperation, len(pending))
+	for j, i := range pending {
+		batch[j] = ops[i]
+	}
+
+	if !atomic {
+		// Each operation is applied in a savepoint of one transaction, so
+		// one that fails is rolled back without affecting the others.
+		opErrs, err := a.repo.ApplyAuthorOperations(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range pending {
+			if opErrs[j] != nil {
+				results[i].Err = batchOperationError(opErrs[j])
+				continue
+			}
+			results[i].Author = ops[i].Author
+		}
+		return results, nil
+	}
+
+	// The repository reports the first operation that cannot be applied.
+	err := a.repo.ApplyAuthorBatch(ctx, batch)
+	if err == nil {
+		for _, i := range pending {
+			results[i].Author = ops[i].Author
+		}
+		return results, nil
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index < 0 || batchErr.Index >= len(pending) {
+		return nil, err
+	}
+	results[pending[batchErr.Index]].Err = batchOperationError(batchErr.Err)
+	return abortBatch(results), nil
+}
+
+// batchOperationError reports a missing author as not found.
+func batchOperationError(err error) error {
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError("Author")
+	}
+	return err
+}
+
+// validateBatchOperation checks op on its own and against the ids of the
+// operations before it, in seen.
+func validateBatchOperation(op BatchOperation, seen map[int]bool) error {
+	switch op.Op {
+	case BatchCreate:
+		if op.ID != 0 {
+			return errs.NewValidationError(resourceName, "id", "must not be set when creating")
+		}
+	case BatchUpdate, BatchDelete:
+		if op.ID <= 0 {
+			return errs.NewValidationError(resourceName, "id", "is required")
+		}
+		if seen[op.ID] {
+			return errs.NewValidationError(resourceName, "id", "must not appear more than once in a batch")
+		}
+		seen[op.ID] = true
+	default:
+		return errs.NewValidationError(resourceName, "op", "must be one of create, update or delete")
+	}
+	if op.Op == BatchDelete {
+		return nil
+	}
+	if op.Author == nil {
+		return errs.NewValidationError(resourceName, "author", "is required")
+	}
+	return validateProfile(op.Author)
+}
+
+// abortBatch fails every operation of results that has not failed already
+// with ErrBatchAborted.
+func abortBatch(results []BatchResult) []BatchResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BatchResult{Err: ErrBatchAborted}
+		}
+	}
+	return results
+}
+
+// ExportAuthors writes every author to w in format, as the authors are read.
+func (a authorService) ExportAuthors(ctx context.Context, format string, w io.Writer) error {
+	exporter, err := newExporter(format, w)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.StreamAuthors(ctx, exporter.Write); err != nil {
+		return err
+	}
+	return exporter.Close()
+}
+
+// ImportAuthors reads the authors in file, which holds size bytes, and checks
+// every row. Unless a row has an error or options.DryRun is set, it then
+// creates and updates the authors in one transaction. Errors in rows are
+// listed in the report; the error is for files that cannot be read and for
+// failures that cannot be put down to a row.
+func (a authorService) ImportAuthors(ctx context.Context, file io.ReaderAt, size int64, options ImportOptions) (*ImportReport, error) {
+	if options.UpsertBy != "" && identifierSchemes[options.UpsertBy] == nil {
+		return nil, errs.NewValidationError(resourceName, "upsert_by", fmt.Sprintf("must be one of %s", strings.Join(supportedSchemes(), ", ")))
+	}
+	rows, columns, err := readImport(options.Format, file, size)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := mapColumns(options.Mapping, columns)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{DryRun: options.DryRun, Rows: len(rows), IgnoredColumns: mapping.ignored}
+
+	values := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		if row.err != nil {
+			report.addError(row.number, row.err)
+			continue
+		}
+		values[i] = mapping.values(row, columns == nil)
+	}
+	matches, err := a.matchImport(rows, values, options.UpsertBy, report)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]BatchOperation, 0, len(rows))
+	// opRows holds the row number of each operation.
+	opRows := make([]int, 0, len(rows))
+	for i, row := range rows {
+		if values[i] == nil {
+			continue
+		}
+		op := BatchOperation{Op: BatchCreate, Author: &Author{}}
+		if existing := matches[i]; existing != nil {
+			if options.MayUpdate != nil && !options.MayUpdate(existing) {
+				report.addError(row.number, errImportForbidden)
+				continue
+			}
+			author := existing.clone()
+			op = BatchOperation{Op: BatchUpdate, ID: existing.ID, Author: &author}
+		}
+		if err := applyValues(op.Author, values[i]); err != nil {
+			report.addError(row.number, err)
+			continue
+		}
+		if err := validateProfile(op.Author); err != nil {
+			report.addError(row.number, err)
+			continue
+		}
+		if op.Op == BatchCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		ops = append(ops, op)
+		opRows = append(opRows, row.number)
+	}
+	if len(report.Errors) > 0 || options.DryRun {
+		return report, nil
+	}
+
+	err = a.repo.ImportAuthors(ctx, ops)
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) && batchErr.Index >= 0 && batchErr.Index < len(opRows) {
+		// An author matched by the row was deleted in the meantime.
+		rowErr := batchErr.Err
+		if rowErr == sql.ErrNoRows {
+			rowErr = errs.NewNotFoundError("Author")
+		}
+		report.addError(opRows[batchErr.Index], rowErr)
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// matchImport finds the author that each row of an upsert by scheme
+// updates: the author with the row's identifier in scheme, or nil if there
+// is none. Rows that share an identifier, or match more than one author,
+// are reported and their values dropped.
+func (a authorService) matchImport(rows []importRow, values []map[string]string, scheme string, report *ImportReport) ([]*Author, error) {
+	matches := make([]*Author, len(rows))
+	if scheme == "" {
+		return matches, nil
+	}
+	normalize := identifierSchemes[scheme]
+	keys := make([]string, len(rows))
+	firstRow := make(map[string]int)
+	for i, row := range rows {
+		if values[i] == nil {
+			continue
+		}
+		key, ok := normalize(strings.TrimSpace(values[i][identifierColumnPrefix+scheme]))
+		if key == "" || !ok {
+			// Invalid identifiers are reported with the other fields.
+			continue
+		}
+		if first, ok := firstRow[key]; ok {
+			report.addError(row.number, errs.NewValidationError(resourceName, identifierColumnPrefix+scheme,
+				fmt.Sprintf("%q is already in row %d", key, first)))
+			values[i] = nil
+			continue
+		}
+		firstRow[key] = row.number
+		keys[i] = key
+	}
+	if len(firstRow) == 0 {
+		return matches, nil
+	}
+
+	wanted := make([]string, 0, len(firstRow))
+	for key := range firstRow {
+		wanted = append(wanted, key)
+	}
+	found, err := a.repo.FindAuthorsByIdentifier(scheme, wanted)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string][]*Author, len(found))
+	for _, author := range found {
+		key := author.Identifiers[scheme]
+		byKey[key] = append(byKey[key], author)
+	}
+	for i, row := range rows {
+		switch authors := byKey[keys[i]]; {
+		case keys[i] == "" || len(authors) == 0:
+		case len(authors) > 1:
+			report.addError(row.number, errs.NewValidationError(resourceName, identifierColumnPrefix+scheme,
+				fmt.Sprintf("%q matches %d authors", keys[i], len(authors))))
+			values[i] = nil
+		default:
+			matches[i] = authors[0]
+		}
+	}
+	return matches, nil
+}
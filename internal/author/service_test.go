@@ -1,14 +1,20 @@
 package author
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/nilemarezz/go-init-template/internal/errs"
 	"github.com/nilemarezz/go-init-template/pkg/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock repository
@@ -22,6 +28,11 @@ func (m *MockAuthorRepository) GetAllAuthors() ([]*Author, error) {
 	return args.Get(0).([]*Author), args.Error(1)
 }
 
+func (m *MockAuthorRepository) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	args := m.Called(since)
+	return args.Get(0).([]*Author), args.Error(1)
+}
+
 func (m *MockAuthorRepository) GetAuthorById(id int) (*Author, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -30,13 +41,67 @@ func (m *MockAuthorRepository) GetAuthorById(id int) (*Author, error) {
 	return args.Get(0).(*Author), args.Error(1)
 }
 
-func (m *MockAuthorRepository) CreateAuthor(author *Author) error {
-	args := m.Called(author)
+func (m *MockAuthorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	args := m.Called(ctx, author)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	args := m.Called(ctx, author, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) GetAuthorRevisions(id int) ([]*Revision, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*Revision), args.Error(1)
+}
+
+func (m *MockAuthorRepository) GetAuthorRevision(id, revision int) (*Revision, error) {
+	args := m.Called(id, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Revision), args.Error(1)
+}
+
+func (m *MockAuthorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	args := m.Called(ctx, id, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Author), args.Error(1)
+}
+
+func (m *MockAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	args := m.Called(ctx, ops)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	args := m.Called(ctx, ops)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]error), args.Error(1)
+}
+
+func (m *MockAuthorRepository) StreamAuthors(ctx context.Context, fn func(*Author) error) error {
+	args := m.Called(ctx, fn)
 	return args.Error(0)
 }
 
-func (m *MockAuthorRepository) UpdateAuthor(author *Author, id int) error {
-	args := m.Called(author, id)
+func (m *MockAuthorRepository) FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error) {
+	args := m.Called(scheme, values)
+	return args.Get(0).([]*Author), args.Error(1)
+}
+
+func (m *MockAuthorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	args := m.Called(ctx, ops)
 	return args.Error(0)
 }
 
@@ -99,10 +164,10 @@ func TestCreateAuthor(t *testing.T) {
 
 	author := &Author{ID: 1, Name: "John Doe"}
 
-	mockRepo.On("CreateAuthor", author).Return(nil)
+	mockRepo.On("CreateAuthor", mock.Anything, author).Return(nil)
 
 	// Act
-	err := authorSvc.CreateAuthor(author)
+	err := authorSvc.CreateAuthor(context.Background(), author)
 
 	// Assert
 	assert.NoError(t, err)
@@ -117,10 +182,10 @@ func TestUpdateAuthor(t *testing.T) {
 	author := &Author{ID: 1, Name: "John Doe"}
 
 	mockRepo.On("GetAuthorById", 1).Return(author, nil)
-	mockRepo.On("UpdateAuthor", author, 1).Return(nil)
+	mockRepo.On("UpdateAuthor", mock.Anything, author, 1).Return(nil)
 
 	// Act
-	err := authorSvc.UpdateAuthor(author, 1)
+	err := authorSvc.UpdateAuthor(context.Background(), author, 1)
 
 	// Assert
 	assert.NoError(t, err)
@@ -137,7 +202,7 @@ func TestUpdateAuthor_NotFound(t *testing.T) {
 	mockRepo.On("GetAuthorById", 1).Return(nil, sql.ErrNoRows)
 
 	// // Act
-	err := authorSvc.UpdateAuthor(&author, 1)
+	err := authorSvc.UpdateAuthor(context.Background(), &author, 1)
 
 	// Assert
 	assert.Error(t, err)
@@ -153,10 +218,10 @@ func TestUpdateAuthor_RepoError(t *testing.T) {
 	author := &Author{ID: 1, Name: "John Doe"}
 
 	mockRepo.On("GetAuthorById", 1).Return(author, nil)
-	mockRepo.On("UpdateAuthor", author, 1).Return(errors.New("some error"))
+	mockRepo.On("UpdateAuthor", mock.Anything, author, 1).Return(errors.New("some error"))
 
 	// Act
-	err := authorSvc.UpdateAuthor(author, 1)
+	err := authorSvc.UpdateAuthor(context.Background(), author, 1)
 
 	// Assert
 	assert.Error(t, err)
@@ -198,3 +263,209 @@ func TestGetAuthorById_ReturnError(t *testing.T) {
 	assert.Equal(t, expectedError, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestDeleteAuthor_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+
+	mockRepo.On("DeleteAuthor", mock.Anything, 1).Return(sql.ErrNoRows)
+
+	// Act
+	err := authorSvc.DeleteAuthor(context.Background(), 1)
+
+	// Assert
+	assert.EqualError(t, err, "Author not found")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateAuthor_NormalizesProfile(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	author := &Author{
+		Name:        "Josiah Carberry",
+		SortName:    " Carberry, Josiah ",
+		Nationality: "us",
+		Identifiers: Identifiers{"orcid": "https://orcid.org/0000-0002-1825-0097", "isni": "0000 0001 2103 2683"},
+	}
+	mockRepo.On("CreateAuthor", mock.Anything, author).Return(nil)
+
+	// Act
+	err := authorSvc.CreateAuthor(context.Background(), author)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Carberry, Josiah", author.SortName)
+	assert.Equal(t, "US", author.Nationality)
+	assert.Equal(t, Identifiers{"orcid": "0000-0002-1825-0097", "isni": "0000000121032683"}, author.Identifiers)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateAuthor_RequiresName(t *testing.T) {
+	// Arrange
+	authorSvc := NewAuthorService(new(MockAuthorRepository))
+
+	// Act
+	err := authorSvc.CreateAuthor(context.Background(), &Author{Name: "  ", Nationality: "US"})
+
+	// Assert
+	var validation *errs.ValidationError
+	require.ErrorAs(t, err, &validation)
+	assert.Equal(t, "name", validation.Field)
+}
+
+func TestCreateAuthor_ProfileValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		author Author
+		field  string
+	}{
+		{"malformed birth date", Author{BirthDate: "21/10/1929"}, "birth_date"},
+		{"impossible death date", Author{DeathDate: "2018-02-30"}, "death_date"},
+		{"death before birth", Author{BirthDate: "1929-10", DeathDate: "1929-09-30"}, "death_date"},
+		{"unknown country", Author{Nationality: "XX"}, "nationality"},
+		{"long biography", Author{Biography: strings.Repeat("a", maxBiographyLength+1)}, "biography"},
+		{"non-web link", Author{Links: Links{{URL: "ftp://example.com"}}}, "links"},
+		{"unknown scheme", Author{Identifiers: Identifiers{"goodreads": "1"}}, "identifiers"},
+		{"bad orcid checksum", Author{Identifiers: Identifiers{"orcid": "0000-0002-1825-0098"}}, "identifiers"},
+		{"bad wikidata id", Author{Identifiers: Identifiers{"wikidata": "181659"}}, "identifiers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			authorSvc := NewAuthorService(new(MockAuthorRepository))
+			author := tt.author
+			author.Name = "John Doe"
+
+			// Act
+			err := authorSvc.CreateAuthor(context.Background(), &author)
+
+			// Assert
+			var validation *errs.ValidationError
+			require.ErrorAs(t, err, &validation)
+			assert.Equal(t, tt.field, validation.Field)
+		})
+	}
+}
+
+func TestCreateAuthor_PartialDatesOfDifferentPrecision(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	// Born and died in the same year; the death date is only known to the year.
+	author := &Author{Name: "John Doe", BirthDate: "1900-06-15", DeathDate: "1900"}
+	mockRepo.On("CreateAuthor", mock.Anything, author).Return(nil)
+
+	// Act
+	err := authorSvc.CreateAuthor(context.Background(), author)
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestGetAuthorHistory_UnknownAuthor(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	mockRepo.On("GetAuthorRevisions", 7).Return([]*Revision{}, nil)
+	mockRepo.On("GetAuthorById", 7).Return(nil, sql.ErrNoRows)
+
+	// Act
+	_, err := authorSvc.GetAuthorHistory(7)
+
+	// Assert
+	assert.IsType(t, &errs.NotFoundError{}, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDiffAuthorRevisions(t *testing.T) {
+	// Arrange
+	authorSvc := NewAuthorService(NewMemoryAuthorRepository())
+	author := &Author{Name: "John Doe", Nationality: "GB", Links: Links{{URL: "https://example.com"}}}
+	require.NoError(t, authorSvc.CreateAuthor(context.Background(), author))
+	require.NoError(t, authorSvc.UpdateAuthor(context.Background(), &Author{Name: "Johnny Doe", Nationality: "GB", Biography: "Writer."}, author.ID))
+	require.NoError(t, authorSvc.DeleteAuthor(context.Background(), author.ID))
+
+	// Act
+	changes, err := authorSvc.DiffAuthorRevisions(author.ID, 1, 2)
+	toDeletion, deletionErr := authorSvc.DiffAuthorRevisions(author.ID, 2, 3)
+	_, missingErr := authorSvc.DiffAuthorRevisions(author.ID, 1, 9)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Field: "biography", To: json.RawMessage(`"Writer."`)},
+		{Field: "links", From: json.RawMessage(`[{"url":"https://example.com"}]`)},
+		{Field: "name", From: json.RawMessage(`"John Doe"`), To: json.RawMessage(`"Johnny Doe"`)},
+	}, changes)
+	require.NoError(t, deletionErr)
+	assert.Len(t, toDeletion, 3)
+	assert.IsType(t, &errs.NotFoundError{}, missingErr)
+}
+
+func TestApplyAuthorBatch_IndependentAppliesOperationsOnce(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	inUse := errs.NewInUseError("Author", "book_authors_author_id_fkey")
+	ops := []BatchOperation{
+		{Op: BatchDelete, ID: 1},
+		{Op: BatchCreate, Author: &Author{Name: "John Doe"}},
+		{Op: BatchDelete, ID: 2},
+		{Op: BatchDelete, ID: 2},
+	}
+	mockRepo.On("ApplyAuthorOperations", mock.Anything, ops[:3]).Return([]error{sql.ErrNoRows, nil, inUse}, nil).Once()
+
+	// Act
+	results, err := authorSvc.ApplyAuthorBatch(context.Background(), ops, false)
+
+	// Assert
+	require.NoError(t, err)
+	assert.IsType(t, &errs.NotFoundError{}, results[0].Err)
+	assert.Equal(t, BatchResult{Author: ops[1].Author}, results[1])
+	assert.Equal(t, inUse, results[2].Err)
+	assert.IsType(t, &errs.ValidationError{}, results[3].Err)
+	mockRepo.AssertNotCalled(t, "ApplyAuthorBatch", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplyAuthorBatch_Limits(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	tooMany := make([]BatchOperation, MaxBatchOperations+1)
+	for i := range tooMany {
+		tooMany[i] = BatchOperation{Op: BatchCreate, Author: &Author{Name: "John Doe"}}
+	}
+
+	// Act
+	_, emptyErr := authorSvc.ApplyAuthorBatch(context.Background(), nil, true)
+	_, tooManyErr := authorSvc.ApplyAuthorBatch(context.Background(), tooMany, true)
+
+	// Assert
+	assert.IsType(t, &errs.ValidationError{}, emptyErr)
+	assert.IsType(t, &errs.ValidationError{}, tooManyErr)
+	mockRepo.AssertNotCalled(t, "ApplyAuthorBatch", mock.Anything, mock.Anything)
+}
+
+func TestImportAuthors_MatchDeletedDuringImport(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockAuthorRepository)
+	authorSvc := NewAuthorService(mockRepo)
+	file := strings.NewReader("name,identifiers.viaf\nJohn Doe,\nJane Smith,102333412\n")
+	existing := &Author{ID: 7, Name: "Jane Smith", Identifiers: Identifiers{"viaf": "102333412"}}
+	mockRepo.On("FindAuthorsByIdentifier", "viaf", []string{"102333412"}).Return([]*Author{existing}, nil)
+	mockRepo.On("ImportAuthors", mock.Anything, mock.MatchedBy(func(ops []BatchOperation) bool {
+		return len(ops) == 2 && ops[0].Op == BatchCreate && ops[1].Op == BatchUpdate && ops[1].ID == 7
+	})).Return(&BatchError{Index: 1, Err: sql.ErrNoRows})
+
+	// Act
+	report, err := authorSvc.ImportAuthors(context.Background(), file, file.Size(), ImportOptions{Format: FormatCSV, UpsertBy: "viaf"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, report.Applied)
+	assert.Equal(t, []ImportError{{Row: 3, Message: errs.NewNotFoundError("Author").Error()}}, report.Errors)
+	mockRepo.AssertExpectations(t)
+}
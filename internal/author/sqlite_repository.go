@@ -0,0 +1,448 @@
+package author
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+// sqliteMigrations are applied in order, tracked with PRAGMA user_version.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS authors (
+		id   INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL
+	)`,
+	`ALTER TABLE authors ADD COLUMN created_by TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE authors ADD COLUMN sort_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN given_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN family_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN birth_date TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN death_date TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN nationality TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN biography TEXT NOT NULL DEFAULT '';
+	ALTER TABLE authors ADD COLUMN links TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE authors ADD COLUMN identifiers TEXT NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE authors ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01T00:00:00.000000Z';
+	ALTER TABLE authors ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01T00:00:00.000000Z';
+	ALTER TABLE authors ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+	UPDATE authors SET created_at = strftime('%Y-%m-%dT%H:%M:%f000Z', 'now'), updated_at = created_at, updated_by = created_by;
+	CREATE INDEX IF NOT EXISTS authors_updated_at_idx ON authors (updated_at)`,
+	`CREATE TABLE IF NOT EXISTS author_revisions (
+		author_id       INTEGER   NOT NULL,
+		revision        INTEGER   NOT NULL,
+		op              TEXT      NOT NULL,
+		before_snapshot TEXT,
+		after_snapshot  TEXT,
+		actor           TEXT      NOT NULL DEFAULT '',
+		request_id      TEXT      NOT NULL DEFAULT '',
+		created_at      TIMESTAMP NOT NULL,
+		PRIMARY KEY (author_id, revision)
+	)`,
+}
+
+// sqliteTimeLayout has a fixed width so timestamps compare correctly as
+// text.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+type sqliteAuthorRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteAuthorRepository returns an AuthorRepository backed by SQLite and
+// brings the authors table up to date.
+func NewSQLiteAuthorRepository(db *sqlx.DB) (AuthorRepository, error) {
+	if err := migrateSQLite(db); err != nil {
+		return nil, err
+	}
+	return &sqliteAuthorRepository{db: db}, nil
+}
+
+func migrateSQLite(db *sqlx.DB) error {
+	var version int
+	if err := db.Get(&version, "PRAGMA user_version"); err != nil {
+		return err
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// PRAGMA does not accept bound parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a sqliteAuthorRepository) GetAllAuthors() ([]*Author, error) {
+	authors := []*Author{}
+	err := a.db.Select(&authors, "SELECT "+authorColumns+" FROM authors ORDER BY id")
+	return authors, errs.FromSQLite(err, resourceName)
+}
+
+func (a sqliteAuthorRepository) GetAuthorsUpdatedSince(since time.Time) ([]*Author, error) {
+	authors := []*Author{}
+	err := a.db.Select(&authors, "SELECT "+authorColumns+" FROM authors WHERE updated_at >= ? ORDER BY updated_at, id",
+		since.UTC().Format(sqliteTimeLayout))
+	return authors, errs.FromSQLite(err, resourceName)
+}
+
+func (a sqliteAuthorRepository) GetAuthorById(id int) (*Author, error) {
+	var author Author
+	err := a.db.Get(&author, "SELECT "+authorColumns+" FROM authors WHERE id = ?", id)
+	if err != nil {
+		return nil, errs.FromSQLite(err, resourceName)
+	}
+	return &author, nil
+}
+
+func (a sqliteAuthorRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return createSQLiteAuthor(ctx, tx, author)
+	})
+	return errs.FromSQLite(err, resourceName)
+}
+
+// createSQLiteAuthor inserts the author and records the revision.
+func createSQLiteAuthor(ctx context.Context, tx *sqlx.Tx, author *Author) error {
+	author.CreatedAt = now()
+	author.UpdatedAt = author.CreatedAt
+	author.CreatedBy = actor(ctx)
+	author.UpdatedBy = author.CreatedBy
+	stamp := author.CreatedAt.Format(sqliteTimeLayout)
+	res, err := tx.ExecContext(ctx, `INSERT INTO authors (name, sort_name, given_name, family_name, birth_date, death_date,
+		nationality, biography, links, identifiers, created_at, updated_at, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate, author.DeathDate,
+		author.Nationality, author.Biography, author.Links, author.Identifiers, stamp, stamp, author.CreatedBy, author.UpdatedBy)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	author.ID = int(id)
+	return insertSQLiteRevision(ctx, tx, newRevision(ctx, OpCreate, author.ID, nil, author, author.UpdatedAt))
+}
+
+func (a sqliteAuthorRepository) UpdateAuthor(ctx context.Context, author *Author, id int) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return updateSQLiteAuthor(ctx, tx, OpUpdate, author, id)
+	})
+	return errs.FromSQLite(err, resourceName)
+}
+
+// updateSQLiteAuthor updates the author and records the revision. The
+// creation fields are read back so author describes the stored row.
+func updateSQLiteAuthor(ctx context.Context, tx *sqlx.Tx, op string, author *Author, id int) error {
+	var before Author
+	if err := tx.GetContext(ctx, &before, "SELECT "+authorColumns+" FROM authors WHERE id = ?", id); err != nil {
+		return err
+	}
+	author.ID = id
+	author.CreatedAt = before.CreatedAt
+	author.CreatedBy = before.CreatedBy
+	author.UpdatedAt = now()
+	author.UpdatedBy = actor(ctx)
+	_, err := tx.ExecContext(ctx, `UPDATE authors SET name = ?, sort_name = ?, given_name = ?, family_name = ?,
+		birth_date = ?, death_date = ?, nationality = ?, biography = ?, links = ?, identifiers = ?,
+		updated_at = ?, updated_by = ?
+		WHERE id = ?`,
+		author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate, author.DeathDate,
+		author.Nationality, author.Biography, author.Links, author.Identifiers,
+		author.UpdatedAt.Format(sqliteTimeLayout), author.UpdatedBy, id)
+	if err != nil {
+		return err
+	}
+	return insertSQLiteRevision(ctx, tx, newRevision(ctx, op, id, &before, author, author.UpdatedAt))
+}
+
+func (a sqliteAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteSQLiteAuthor(ctx, tx, id)
+	})
+	return errs.FromSQLiteDelete(err, resourceName)
+}
+
+// deleteSQLiteAuthor deletes the author and records the revision.
+func deleteSQLiteAuthor(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var before Author
+	if err := tx.GetContext(ctx, &before, "SELECT "+authorColumns+" FROM authors WHERE id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM authors WHERE id = ?", id); err != nil {
+		return err
+	}
+	return insertSQLiteRevision(ctx, tx, newRevision(ctx, OpDelete, id, &before, nil, now()))
+}
+
+func (a sqliteAuthorRepository) GetAuthorRevisions(id int) ([]*Revision, error) {
+	revisions := []*Revision{}
+	err := a.db.Select(&revisions, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = ? ORDER BY revision", id)
+	return revisions, errs.FromSQLite(err, resourceName)
+}
+
+func (a sqliteAuthorRepository) GetAuthorRevision(id, revision int) (*Revision, error) {
+	var rev Revision
+	err := a.db.Get(&rev, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = ? AND revision = ?", id, revision)
+	if err != nil {
+		return nil, errs.FromSQLite(err, resourceName)
+	}
+	return &rev, nil
+}
+
+func (a sqliteAuthorRepository) RevertAuthor(ctx context.Context, id, revision int) (*Author, error) {
+	var author *Author
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		var rev Revision
+		err := tx.GetContext(ctx, &rev, "SELECT "+revisionColumns+" FROM author_revisions WHERE author_id = ? AND revision = ?", id, revision)
+		if err != nil {
+			return err
+		}
+		if author, err = rev.restore(); err != nil {
+			return err
+		}
+		return updateSQLiteAuthor(ctx, tx, OpRevert, author, id)
+	})
+	if err != nil {
+		return nil, errs.FromSQLite(err, resourceName)
+	}
+	return author, nil
+}
+
+func (a sqliteAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []BatchOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	at := now()
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		return applySQLiteBatch(ctx, tx, ops, at)
+	})
+	return errs.FromSQLite(err, resourceName)
+}
+
+func (a sqliteAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []BatchOperation) ([]error, error) {
+	var results []error
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		results, err = applyEach(ctx, tx, ops, func(op BatchOperation) error {
+			switch op.Op {
+			case BatchCreate:
+				return errs.FromSQLite(createSQLiteAuthor(ctx, tx, op.Author), resourceName)
+			case BatchUpdate:
+				return errs.FromSQLite(updateSQLiteAuthor(ctx, tx, OpUpdate, op.Author, op.ID), resourceName)
+			}
+			return errs.FromSQLiteDelete(deleteSQLiteAuthor(ctx, tx, op.ID), resourceName)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errs.FromSQLite(err, resourceName)
+	}
+	return results, nil
+}
+
+// applySQLiteBatch applies ops as ApplyAuthorBatch describes, stamping
+// every change with at.
+func applySQLiteBatch(ctx context.Context, tx *sqlx.Tx, ops []BatchOperation, at time.Time) error {
+	plan := planBatch(ops)
+	stamp := at.Format(sqliteTimeLayout)
+	before := make(map[int]*Author, len(plan.existing))
+	latestRevision := make(map[int]int, len(plan.existing))
+	if len(plan.existing) > 0 {
+		query, args, err := sqlx.In("SELECT "+authorColumns+" FROM authors WHERE id IN (?)", plan.existing)
+		if err != nil {
+			return err
+		}
+		var existing []*Author
+		if err := tx.SelectContext(ctx, &existing, query, args...); err != nil {
+			return err
+		}
+		for _, author := range existing {
+			before[author.ID] = author
+		}
+		if err := missingAuthor(ops, before); err != nil {
+			return err
+		}
+		query, args, err = sqlx.In("SELECT author_id, MAX(revision) AS revision FROM author_revisions WHERE author_id IN (?) GROUP BY author_id",
+			plan.existing)
+		if err != nil {
+			return err
+		}
+		var latest []struct {
+			AuthorID int `db:"author_id"`
+			Revision int `db:"revision"`
+		}
+		if err := tx.SelectContext(ctx, &latest, query, args...); err != nil {
+			return err
+		}
+		for _, l := range latest {
+			latestRevision[l.AuthorID] = l.Revision
+		}
+	}
+
+	revisions := &batchRevisions{ctx: ctx, at: at, latest: latestRevision}
+
+	if len(plan.deletes) > 0 {
+		ids := make([]int, len(plan.deletes))
+		for i, index := range plan.deletes {
+			ids[i] = ops[index].ID
+			revisions.add(OpDelete, ids[i], before[ids[i]], nil)
+		}
+		query, args, err := sqlx.In("DELETE FROM authors WHERE id IN (?)", ids)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return errs.FromSQLiteDelete(err, resourceName)
+		}
+	}
+
+	if len(plan.updates) > 0 {
+		args := make([]interface{}, 0, len(plan.updates)*13)
+		for _, index := range plan.updates {
+			author, id := ops[index].Author, ops[index].ID
+			author.ID = id
+			author.CreatedAt = before[id].CreatedAt
+			author.CreatedBy = before[id].CreatedBy
+			author.UpdatedAt = at
+			author.UpdatedBy = actor(ctx)
+			args = append(args, id, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+				author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers, stamp, author.UpdatedBy)
+			revisions.add(OpUpdate, id, before[id], author)
+		}
+		_, err := tx.ExecContext(ctx, `WITH v(id, name, sort_name, given_name, family_name, birth_date, death_date,
+				nationality, biography, links, identifiers, updated_at, updated_by) AS (VALUES `+sqliteValues(len(plan.updates), 13)+`)
+			UPDATE authors SET name = v.name, sort_name = v.sort_name, given_name = v.given_name,
+				family_name = v.family_name, birth_date = v.birth_date, death_date = v.death_date,
+				nationality = v.nationality, biography = v.biography, links = v.links, identifiers = v.identifiers,
+				updated_at = v.updated_at, updated_by = v.updated_by
+			FROM v WHERE authors.id = v.id`, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(plan.creates) > 0 {
+		// Number the new rows explicitly, past any id AUTOINCREMENT has
+		// handed out, so each row is known to belong to its operation.
+		var last int
+		err := tx.GetContext(ctx, &last, `SELECT MAX(COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'authors'), 0),
+			COALESCE((SELECT MAX(id) FROM authors), 0))`)
+		if err != nil {
+			return err
+		}
+		args := make([]interface{}, 0, len(plan.creates)*15)
+		for i, index := range plan.creates {
+			author := ops[index].Author
+			author.ID = last + i + 1
+			author.CreatedAt, author.UpdatedAt = at, at
+			author.CreatedBy = actor(ctx)
+			author.UpdatedBy = author.CreatedBy
+			args = append(args, author.ID, author.Name, author.SortName, author.GivenName, author.FamilyName, author.BirthDate,
+				author.DeathDate, author.Nationality, author.Biography, author.Links, author.Identifiers,
+				stamp, stamp, author.CreatedBy, author.UpdatedBy)
+			revisions.add(OpCreate, author.ID, nil, author)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO authors (id, name, sort_name, given_name, family_name, birth_date,
+			death_date, nationality, biography, links, identifiers, created_at, updated_at, created_by, updated_by)
+			VALUES `+sqliteValues(len(plan.creates), 15), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := make([]interface{}, 0, len(revisions.revisions)*8)
+	for _, rev := range revisions.revisions {
+		args = append(args, rev.AuthorID, rev.Revision, rev.Op, rev.Before, rev.After, rev.Actor, rev.RequestID, stamp)
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO author_revisions
+		(author_id, revision, op, before_snapshot, after_snapshot, actor, request_id, created_at)
+		VALUES `+sqliteValues(len(revisions.revisions), 8), args...)
+	return err
+}
+
+func (a sqliteAuthorRepository) StreamAuthors(ctx context.Context, fn func(*Author) error) error {
+	// SQLite runs on a single connection, so other queries wait until the
+	// stream is done.
+	err := streamAuthors(ctx, a.db, "SELECT "+authorColumns+" FROM authors ORDER BY id", fn)
+	return errs.FromSQLite(err, resourceName)
+}
+
+func (a sqliteAuthorRepository) FindAuthorsByIdentifier(scheme string, values []string) ([]*Author, error) {
+	authors := []*Author{}
+	// The values are passed as one JSON array, as there may be more of them
+	// than SQLite takes bind parameters.
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	err = a.db.Select(&authors, `SELECT `+authorColumns+` FROM authors
+		WHERE json_extract(identifiers, '$.' || json_quote(?)) IN (SELECT value FROM json_each(?)) ORDER BY id`,
+		scheme, string(encoded))
+	return authors, errs.FromSQLite(err, resourceName)
+}
+
+// ImportAuthors applies ops in batches of MaxBatchOperations, which keeps
+// each statement under the bind parameter limit, within one transaction.
+func (a sqliteAuthorRepository) ImportAuthors(ctx context.Context, ops []BatchOperation) error {
+	at := now()
+	err := a.inTx(ctx, func(tx *sqlx.Tx) error {
+		for start := 0; start < len(ops); start += MaxBatchOperations {
+			end := min(start+MaxBatchOperations, len(ops))
+			err := applySQLiteBatch(ctx, tx, ops[start:end], at)
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) {
+				return &BatchError{Index: start + batchErr.Index, Err: batchErr.Err}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errs.FromSQLite(err, resourceName)
+}
+
+// insertSQLiteRevision records rev under the next revision number of its
+// author. SQLite serializes writers, so the number cannot be taken
+// concurrently.
+func insertSQLiteRevision(ctx context.Context, tx *sqlx.Tx, rev *Revision) error {
+	err := tx.GetContext(ctx, &rev.Revision, "SELECT COALESCE(MAX(revision), 0) + 1 FROM author_revisions WHERE author_id = ?", rev.AuthorID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO author_revisions
+		(author_id, revision, op, before_snapshot, after_snapshot, actor, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rev.AuthorID, rev.Revision, rev.Op, rev.Before, rev.After, rev.Actor, rev.RequestID, rev.CreatedAt.Format(sqliteTimeLayout))
+	return err
+}
+
+// inTx runs fn in a transaction, leaving error translation to the caller.
+func (a sqliteAuthorRepository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
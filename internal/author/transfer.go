@@ -0,0 +1,530 @@
+package author
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/xlsx"
+)
+
+// Formats of author exports and imports.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// formatContentTypes are the media types of the formats.
+var formatContentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   xlsx.ContentType,
+}
+
+// FormatContentType returns the media type of a format, or a
+// ValidationError if the format is not supported.
+func FormatContentType(format string) (string, error) {
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		return "", errs.NewValidationError(resourceName, "format", "must be one of csv, ndjson or xlsx")
+	}
+	return contentType, nil
+}
+
+// FormatFromContentType returns the format of a media type, or "" if it is
+// not one of the formats.
+func FormatFromContentType(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return FormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return FormatNDJSON
+	case xlsx.ContentType:
+		return FormatXLSX
+	}
+	return ""
+}
+
+// identifierColumnPrefix names the columns that hold one identifier each,
+// such as identifiers.wikidata.
+const identifierColumnPrefix = "identifiers."
+
+// readOnlyColumns are exported but set by the repository, so imports ignore
+// them.
+var readOnlyColumns = []string{"id", "created_at", "updated_at", "created_by", "updated_by"}
+
+// importFields set the author field of each column that imports accept from
+// the text of a cell. Identifiers get one column per scheme.
+var importFields = map[string]func(author *Author, value string) error{
+	"name":        func(a *Author, v string) error { a.Name = v; return nil },
+	"sort_name":   func(a *Author, v string) error { a.SortName = v; return nil },
+	"given_name":  func(a *Author, v string) error { a.GivenName = v; return nil },
+	"family_name": func(a *Author, v string) error { a.FamilyName = v; return nil },
+	"birth_date":  func(a *Author, v string) error { a.BirthDate = PartialDate(strings.TrimSpace(v)); return nil },
+	"death_date":  func(a *Author, v string) error { a.DeathDate = PartialDate(strings.TrimSpace(v)); return nil },
+	"nationality": func(a *Author, v string) error { a.Nationality = v; return nil },
+	"biography":   func(a *Author, v string) error { a.Biography = v; return nil },
+	"links":       setLinks,
+}
+
+func init() {
+	for _, scheme := range supportedSchemes() {
+		scheme := scheme
+		importFields[identifierColumnPrefix+scheme] = func(a *Author, v string) error {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				delete(a.Identifiers, scheme)
+				return nil
+			}
+			if a.Identifiers == nil {
+				a.Identifiers = Identifiers{}
+			}
+			a.Identifiers[scheme] = v
+			return nil
+		}
+	}
+}
+
+// setLinks reads links written as a JSON array, as exports write them, or
+// as URLs separated by whitespace, which is easier to type in a
+// spreadsheet.
+func setLinks(a *Author, value string) error {
+	value = strings.TrimSpace(value)
+	a.Links = nil
+	if value == "" {
+		return nil
+	}
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &a.Links); err != nil {
+			return errs.NewValidationError(resourceName, "links", "must be a JSON array of links or URLs separated by spaces")
+		}
+		return nil
+	}
+	for _, url := range strings.Fields(value) {
+		a.Links = append(a.Links, Link{URL: url})
+	}
+	return nil
+}
+
+// exportColumns are the columns of CSV and spreadsheet exports, in order.
+var exportColumns = func() []string {
+	columns := []string{"id", "name", "sort_name", "given_name", "family_name", "birth_date", "death_date",
+		"nationality", "biography", "links"}
+	for _, scheme := range supportedSchemes() {
+		columns = append(columns, identifierColumnPrefix+scheme)
+	}
+	return append(columns, "created_at", "updated_at", "created_by", "updated_by")
+}()
+
+// exportRecord returns the cells of author under exportColumns.
+func exportRecord(author *Author) []string {
+	var links string
+	if len(author.Links) > 0 {
+		encoded, _ := json.Marshal(author.Links)
+		links = string(encoded)
+	}
+	record := []string{strconv.Itoa(author.ID), author.Name, author.SortName, author.GivenName, author.FamilyName,
+		string(author.BirthDate), string(author.DeathDate), author.Nationality, author.Biography, links}
+	for _, scheme := range supportedSchemes() {
+		record = append(record, author.Identifiers[scheme])
+	}
+	return append(record, author.CreatedAt.UTC().Format(time.RFC3339Nano), author.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		author.CreatedBy, author.UpdatedBy)
+}
+
+// exporter writes authors in one of the formats.
+type exporter interface {
+	Write(author *Author) error
+	// Close writes what is buffered and ends the file.
+	Close() error
+}
+
+func newExporter(format string, w io.Writer) (exporter, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvExporter{writer: writer}, nil
+	case FormatNDJSON:
+		return &ndjsonExporter{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		writer, err := xlsx.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &xlsxExporter{writer: writer}, nil
+	}
+	_, err := FormatContentType(format)
+	return nil, err
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvExporter) Write(author *Author) error {
+	return e.writer.Write(exportRecord(author))
+}
+
+func (e *csvExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ndjsonExporter writes each author as the API returns it, on a line of
+// its own.
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExporter) Write(author *Author) error {
+	return e.encoder.Encode(author)
+}
+
+func (e *ndjsonExporter) Close() error {
+	return nil
+}
+
+type xlsxExporter struct {
+	writer *xlsx.Writer
+}
+
+func (e *xlsxExporter) Write(author *Author) error {
+	return e.writer.Write(exportRecord(author))
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.writer.Close()
+}
+
+// MaxImportRows caps the rows of one import. Imports are checked in full
+// before anything is written, so every row is held in memory.
+const MaxImportRows = 100000
+
+// maxImportErrors caps the errors listed in an ImportReport.
+const maxImportErrors = 1000
+
+// ImportOptions control an import.
+type ImportOptions struct {
+	// Format is FormatCSV, FormatNDJSON or FormatXLSX.
+	Format string
+	// Mapping names the column of the file that holds each field, for
+	// columns not named after their field.
+	Mapping map[string]string
+	// DryRun checks the file and reports what the import would do, without
+	// doing it.
+	DryRun bool
+	// UpsertBy is an identifier scheme. Rows with an identifier in it that
+	// an author already has update that author; the other rows create
+	// authors. Without it every row creates an author.
+	UpsertBy string
+	// MayUpdate, if set, reports whether an existing author may be updated.
+	MayUpdate func(existing *Author) bool
+}
+
+// ImportReport describes an import. The rows of a file are imported all or
+// none: when any row has an error, nothing is.
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Applied tells whether the authors were written.
+	Applied bool `json:"applied"`
+	// Rows counts the rows of the file, without the header and blank rows.
+	Rows int `json:"rows" example:"3"`
+	// Created and Updated count the rows that create and update authors.
+	Created int `json:"created" example:"2"`
+	Updated int `json:"updated" example:"1"`
+	// IgnoredColumns are the columns of the file that were not imported.
+	IgnoredColumns []string      `json:"ignored_columns,omitempty"`
+	Errors         []ImportError `json:"errors,omitempty"`
+	// ErrorsTruncated tells that more rows had errors than are listed.
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+// ImportError is an error in one row of an import file.
+type ImportError struct {
+	// Row is the row of the spreadsheet or the line of the file, counting
+	// from 1.
+	Row     int    `json:"row" example:"2"`
+	Field   string `json:"field,omitempty" example:"birth_date"`
+	Message string `json:"message" example:"\"1929-13\" is not a valid date"`
+}
+
+func (r *ImportReport) addError(row int, err error) {
+	if len(r.Errors) == maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	importErr := ImportError{Row: row, Message: err.Error()}
+	var validationErr *errs.ValidationError
+	if errors.As(err, &validationErr) {
+		importErr.Field = validationErr.Field
+		importErr.Message = validationErr.Message
+	}
+	r.Errors = append(r.Errors, importErr)
+}
+
+// importRow is a row of an import file: the cells it has, by column name.
+type importRow struct {
+	// number is the row of the spreadsheet or the line of the file, from 1.
+	number int
+	values map[string]string
+	// err is set for rows that could not be read.
+	err error
+}
+
+// maxLineLength caps the length of an NDJSON line.
+const maxLineLength = 1 << 20
+
+// readImport reads the rows of an import file, without its header, and the
+// names of its columns. The columns of NDJSON files are the keys of their
+// objects, so no names are returned for them.
+func readImport(format string, file io.ReaderAt, size int64) ([]importRow, []string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(io.NewSectionReader(file, 0, size))
+		// Rows may leave out trailing cells; rows with more cells than the
+		// header names are reported by row.
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		return readTable(func() ([]string, int, error) {
+			record, err := reader.Read()
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, parseErr.StartLine, errs.NewValidationError(resourceName, "file", parseErr.Error())
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			line, _ := reader.FieldPos(0)
+			return record, line, nil
+		})
+	case FormatXLSX:
+		reader, err := xlsx.NewReader(file, size)
+		if err != nil {
+			return nil, nil, errs.NewValidationError(resourceName, "file", err.Error())
+		}
+		defer reader.Close()
+		return readTable(func() ([]string, int, error) {
+			record, err := reader.Read()
+			if errors.Is(err, xlsx.ErrInvalidFile) {
+				return nil, reader.Row(), errs.NewValidationError(resourceName, "file", err.Error())
+			}
+			return record, reader.Row(), err
+		})
+	case FormatNDJSON:
+		rows, err := readNDJSON(io.NewSectionReader(file, 0, size))
+		return rows, nil, err
+	}
+	_, err := FormatContentType(format)
+	return nil, nil, err
+}
+
+// readTable reads the rows of a CSV file or spreadsheet from next, which
+// returns each record with its row number. The first row names the columns.
+// Rows without any values are skipped.
+func readTable(next func() ([]string, int, error)) ([]importRow, []string, error) {
+	header, _, err := next()
+	if err == io.EOF {
+		return nil, nil, errs.NewValidationError(resourceName, "file", "must have a header row")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name != "" && seen[name] {
+			return nil, nil, errs.NewValidationError(resourceName, "file", fmt.Sprintf("column %q appears more than once", name))
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+
+	var rows []importRow
+	for {
+		record, number, err := next()
+		if err == io.EOF {
+			return rows, columns, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) == MaxImportRows {
+			return nil, nil, errs.NewValidationError(resourceName, "file", fmt.Sprintf("must not have more than %d rows", MaxImportRows))
+		}
+		row := importRow{number: number, values: make(map[string]string, len(columns))}
+		blank := true
+		for i, value := range record {
+			if strings.TrimSpace(value) != "" {
+				blank = false
+			}
+			if i >= len(columns) {
+				if value != "" {
+					row.err = errs.NewValidationError(resourceName, "file", fmt.Sprintf("row has %d cells but the header only names %d columns", len(record), len(columns)))
+				}
+				continue
+			}
+			if columns[i] != "" {
+				row.values[columns[i]] = value
+			}
+		}
+		if !blank {
+			rows = append(rows, row)
+		}
+	}
+}
+
+// readNDJSON reads one author object per line. Blank lines are skipped.
+// Identifiers may be given as an object, as exports write them, or in
+// identifiers.<scheme> keys.
+func readNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, errs.NewValidationError(resourceName, "file", fmt.Sprintf("must not have more than %d rows", MaxImportRows))
+		}
+		row := importRow{number: line, values: make(map[string]string)}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(text, &object); err != nil {
+			row.err = errs.NewValidationError(resourceName, "file", "line is not a JSON object")
+			rows = append(rows, row)
+			continue
+		}
+		for key, raw := range object {
+			if key == "identifiers" {
+				var identifiers map[string]*string
+				if err := json.Unmarshal(raw, &identifiers); err != nil {
+					row.err = errs.NewValidationError(resourceName, "identifiers", "must be an object of strings")
+					break
+				}
+				for scheme, value := range identifiers {
+					if value != nil {
+						row.values[identifierColumnPrefix+scheme] = *value
+					}
+				}
+				continue
+			}
+			row.values[key] = jsonText(raw)
+		}
+		rows = append(rows, row)
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, errs.NewValidationError(resourceName, "file", fmt.Sprintf("lines must not be longer than %d bytes", maxLineLength))
+	}
+	return rows, scanner.Err()
+}
+
+// jsonText returns a JSON string as its text, null as "" and any other
+// value as JSON.
+func jsonText(raw json.RawMessage) string {
+	var text *string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text == nil {
+			return ""
+		}
+		return *text
+	}
+	return string(raw)
+}
+
+// columnMapping names the field of each column of an import.
+type columnMapping struct {
+	fields map[string]string
+	// mapped are the fields that the mapping gives a column.
+	mapped map[string]bool
+	// ignored are the columns that are not imported, other than those
+	// exports write but imports cannot set.
+	ignored []string
+}
+
+// mapColumns maps the columns of an import to fields. mapping names the
+// column of each field whose column is not named after it; other columns
+// are matched to the field of their name, ignoring case. columns is nil for
+// files that name columns row by row.
+func mapColumns(mapping map[string]string, columns []string) (*columnMapping, error) {
+	m := &columnMapping{fields: make(map[string]string), mapped: make(map[string]bool, len(mapping))}
+	for field, column := range mapping {
+		if importFields[field] == nil {
+			return nil, errs.NewValidationError(resourceName, "map", fmt.Sprintf("%q is not a field that can be imported", field))
+		}
+		if columns != nil && !slices.Contains(columns, column) {
+			return nil, errs.NewValidationError(resourceName, "map", fmt.Sprintf("the file has no column %q", column))
+		}
+		m.fields[column] = field
+		m.mapped[field] = true
+	}
+	for _, column := range columns {
+		if column == "" {
+			continue
+		}
+		if _, ok := m.fields[column]; ok {
+			continue
+		}
+		field := strings.ToLower(column)
+		if importFields[field] != nil && !m.mapped[field] {
+			m.fields[column] = field
+			continue
+		}
+		if !slices.Contains(readOnlyColumns, field) {
+			m.ignored = append(m.ignored, column)
+		}
+	}
+	return m, nil
+}
+
+// values returns the values of row by field. For files that name columns
+// row by row, columns are matched to fields here.
+func (m *columnMapping) values(row importRow, byRow bool) map[string]string {
+	values := make(map[string]string, len(row.values))
+	for column, value := range row.values {
+		field, ok := m.fields[column]
+		if !ok && byRow {
+			field = strings.ToLower(column)
+			ok = importFields[field] != nil && !m.mapped[field]
+		}
+		if ok {
+			values[field] = value
+		}
+	}
+	return values
+}
+
+// applyValues sets the fields of author from values.
+func applyValues(author *Author, values map[string]string) error {
+	// Set the fields in a fixed order, so that the first error is the same
+	// every time.
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if err := importFields[field](author, values[field]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package authz
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Permissions checked by the route groups.
+const (
+	AuthorsRead  = "authors:read"
+	AuthorsWrite = "authors:write"
+	// AuthorsWriteAny allows updating authors created by someone else when
+	// the ownership rule is enabled.
+	AuthorsWriteAny = "authors:write:any"
+	BooksRead       = "books:read"
+	BooksWrite      = "books:write"
+	APIKeysManage   = "apikeys:manage"
+
+	// Wildcard grants every permission.
+	Wildcard = "*"
+)
+
+// Built-in roles. RoleAnonymous applies to requests without a principal.
+const (
+	RoleAnonymous = "anonymous"
+	RoleReader    = "reader"
+	RoleEditor    = "editor"
+	RoleAdmin     = "admin"
+)
+
+// ErrForbidden is returned when the caller lacks a required permission.
+var ErrForbidden = errors.New("permission denied")
+
+// defaultRoles is used for roles that are not overridden in config.
+var defaultRoles = map[string][]string{
+	RoleAnonymous: {AuthorsRead, BooksRead},
+	RoleReader:    {AuthorsRead, BooksRead},
+	RoleEditor:    {AuthorsRead, AuthorsWrite, BooksRead, BooksWrite},
+	RoleAdmin:     {Wildcard},
+}
+
+// Policy maps roles to permissions and checks them for a principal.
+type Policy struct {
+	roles     map[string]map[string]bool
+	ownership bool
+}
+
+// NewPolicy creates a Policy from role to permission mappings. When ownership
+// is true, callers without AuthorsWriteAny can only modify resources they
+// created.
+func NewPolicy(roles map[string][]string, ownership bool) *Policy {
+	p := &Policy{roles: make(map[string]map[string]bool, len(roles)), ownership: ownership}
+	for role, permissions := range roles {
+		set := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			set[permission] = true
+		}
+		p.roles[role] = set
+	}
+	return p
+}
+
+// NewPolicyFromConfig creates a Policy from the built-in roles, overridden by
+// any roles defined under authz.roles in config.
+func NewPolicyFromConfig(config *config.Config) *Policy {
+	roles := make(map[string][]string, len(defaultRoles))
+	for role, permissions := range defaultRoles {
+		roles[role] = permissions
+	}
+	for role, permissions := range config.Authz.Roles {
+		roles[role] = permissions
+	}
+	return NewPolicy(roles, config.Authz.Ownership)
+}
+
+// Allows reports whether p holds permission through one of its roles or
+// scopes. A nil principal is evaluated as RoleAnonymous.
+func (p *Policy) Allows(principal *auth.Principal, permission string) bool {
+	if principal == nil {
+		return p.roleAllows(RoleAnonymous, permission)
+	}
+	for _, role := range principal.Roles {
+		if p.roleAllows(role, permission) {
+			return true
+		}
+	}
+	for _, scope := range principal.Scopes {
+		if scope == permission || scope == Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// CanModify applies the ownership rule: it reports whether principal may
+// modify a resource created by owner, a method-qualified subject as returned
+// by auth.Principal.Owner. Owners recorded before subjects were qualified
+// match no principal.
+func (p *Policy) CanModify(principal *auth.Principal, owner string) bool {
+	if !p.ownership {
+		return true
+	}
+	if p.Allows(principal, AuthorsWriteAny) {
+		return true
+	}
+	return principal != nil && owner != "" && principal.Owner() == owner
+}
+
+// Require rejects requests whose caller lacks any of permissions. Anonymous
+// callers get 401 so they know to authenticate, others get 403.
+func (p *Policy) Require(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := auth.PrincipalFrom(c)
+		for _, permission := range permissions {
+			if p.Allows(principal, permission) {
+				continue
+			}
+			if principal == nil {
+				auth.Unauthorized(c, auth.ErrNoCredentials)
+				return
+			}
+			httputil.NewError(c, http.StatusForbidden, ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (p *Policy) roleAllows(role, permission string) bool {
+	permissions := p.roles[role]
+	return permissions[permission] || permissions[Wildcard]
+}
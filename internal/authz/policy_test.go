@@ -0,0 +1,91 @@
+package authz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_DefaultRoles(t *testing.T) {
+	// Arrange
+	policy := NewPolicyFromConfig(&config.Config{})
+	reader := &auth.Principal{Subject: "r", Roles: []string{RoleReader}}
+	editor := &auth.Principal{Subject: "e", Roles: []string{RoleEditor}}
+	admin := &auth.Principal{Subject: "a", Roles: []string{RoleAdmin}}
+
+	// Act & Assert
+	assert.True(t, policy.Allows(nil, AuthorsRead))
+	assert.False(t, policy.Allows(nil, AuthorsWrite))
+	assert.True(t, policy.Allows(reader, AuthorsRead))
+	assert.False(t, policy.Allows(reader, AuthorsWrite))
+	assert.True(t, policy.Allows(editor, AuthorsWrite))
+	assert.False(t, policy.Allows(editor, APIKeysManage))
+	assert.True(t, policy.Allows(admin, APIKeysManage))
+}
+
+func TestPolicy_ScopesGrantPermissions(t *testing.T) {
+	// Arrange
+	policy := NewPolicyFromConfig(&config.Config{})
+	machine := &auth.Principal{Subject: "m", Scopes: []string{AuthorsWrite}}
+
+	// Act & Assert
+	assert.True(t, policy.Allows(machine, AuthorsWrite))
+	assert.False(t, policy.Allows(machine, APIKeysManage))
+}
+
+func TestPolicy_ConfigOverridesRoles(t *testing.T) {
+	// Arrange: make reads private and add a custom role.
+	policy := NewPolicyFromConfig(&config.Config{Authz: config.AuthzConfig{Roles: map[string][]string{
+		RoleAnonymous: {},
+		"auditor":     {AuthorsRead},
+	}}})
+	auditor := &auth.Principal{Subject: "x", Roles: []string{"auditor"}}
+
+	// Act & Assert
+	assert.False(t, policy.Allows(nil, AuthorsRead))
+	assert.True(t, policy.Allows(auditor, AuthorsRead))
+}
+
+func TestPolicy_CanModify(t *testing.T) {
+	// Arrange
+	policy := NewPolicyFromConfig(&config.Config{Authz: config.AuthzConfig{Ownership: true}})
+	alice := &auth.Principal{Subject: "alice", Method: "basic", Roles: []string{RoleEditor}}
+	admin := &auth.Principal{Subject: "root", Method: "basic", Roles: []string{RoleAdmin}}
+
+	// Act & Assert
+	assert.True(t, policy.CanModify(alice, "basic:alice"))
+	assert.False(t, policy.CanModify(alice, "mtls:alice"))
+	assert.False(t, policy.CanModify(alice, "alice"))
+	assert.False(t, policy.CanModify(alice, "basic:bob"))
+	assert.False(t, policy.CanModify(alice, ""))
+	assert.True(t, policy.CanModify(admin, "basic:bob"))
+	assert.True(t, NewPolicyFromConfig(&config.Config{}).CanModify(alice, "basic:bob"))
+}
+
+func TestPolicy_Require(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	policy := NewPolicyFromConfig(&config.Config{})
+	serve := func(principal *auth.Principal) int {
+		router := gin.New()
+		router.POST("/", func(c *gin.Context) {
+			if principal != nil {
+				auth.SetPrincipal(c, principal)
+			}
+		}, policy.Require(AuthorsWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Act & Assert
+	assert.Equal(t, http.StatusUnauthorized, serve(nil))
+	assert.Equal(t, http.StatusForbidden, serve(&auth.Principal{Subject: "r", Roles: []string{RoleReader}}))
+	assert.Equal(t, http.StatusOK, serve(&auth.Principal{Subject: "e", Roles: []string{RoleEditor}}))
+}
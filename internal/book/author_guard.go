@@ -0,0 +1,84 @@
+package book
+
+import (
+	"context"
+
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+// guardedAuthorRepository refuses to delete authors who are still credited
+// on books.
+type guardedAuthorRepository struct {
+	author.AuthorRepository
+	books BookRepository
+}
+
+// GuardAuthorDeletes wraps authors so that DeleteAuthor returns an
+// *errs.InUseError while the author is credited on any book. On Postgres the
+// book_authors foreign key enforces the same rule atomically; the check here
+// covers the in-memory stores and gives every store the same error.
+func GuardAuthorDeletes(authors author.AuthorRepository, books BookRepository) author.AuthorRepository {
+	return &guardedAuthorRepository{AuthorRepository: authors, books: books}
+}
+
+func (g *guardedAuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	count, err := g.books.CountBooksByAuthor(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errs.NewInUseError("Author", "book_authors_author_id_fkey")
+	}
+	return g.AuthorRepository.DeleteAuthor(ctx, id)
+}
+
+func (g *guardedAuthorRepository) ApplyAuthorBatch(ctx context.Context, ops []author.BatchOperation) error {
+	for i, op := range ops {
+		if op.Op != author.BatchDelete {
+			continue
+		}
+		count, err := g.books.CountBooksByAuthor(op.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return &author.BatchError{Index: i, Err: errs.NewInUseError("Author", "book_authors_author_id_fkey")}
+		}
+	}
+	return g.AuthorRepository.ApplyAuthorBatch(ctx, ops)
+}
+
+func (g *guardedAuthorRepository) ApplyAuthorOperations(ctx context.Context, ops []author.BatchOperation) ([]error, error) {
+	results := make([]error, len(ops))
+	pending := make([]int, 0, len(ops))
+	for i, op := range ops {
+		if op.Op == author.BatchDelete {
+			count, err := g.books.CountBooksByAuthor(op.ID)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				results[i] = errs.NewInUseError("Author", "book_authors_author_id_fkey")
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	batch := make([]author.BatchOperation, len(pending))
+	for j, i := range pending {
+		batch[j] = ops[i]
+	}
+	applied, err := g.AuthorRepository.ApplyAuthorOperations(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range pending {
+		results[i] = applied[j]
+	}
+	return results, nil
+}
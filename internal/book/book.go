@@ -0,0 +1,121 @@
+package book
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/author"
+)
+
+// resourceName is the name used for books in domain errors.
+const resourceName = "Book"
+
+// dateLayout is the format of publication dates in JSON.
+const dateLayout = "2006-01-02"
+
+// Contributor roles. RoleAuthor is used when a request does not name one.
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+var validRoles = map[string]bool{
+	RoleAuthor:      true,
+	RoleEditor:      true,
+	RoleTranslator:  true,
+	RoleIllustrator: true,
+}
+
+// Book represents a book.
+// @Summary Book struct to represent a book
+// @Description Struct to represent a book and the authors credited on it
+type Book struct {
+	ID    int    `db:"id" json:"id"`
+	Title string `db:"title" json:"title" example:"The Go Programming Language"`
+	// ISBN accepts ISBN-10 or ISBN-13, with or without hyphens, and is
+	// stored as ISBN-13.
+	ISBN        string `db:"isbn" json:"isbn" example:"9780134190440"`
+	PublishedOn *Date  `db:"published_on" json:"published_on,omitempty" swaggertype:"string" format:"date" example:"2015-10-26"`
+	// Language is an ISO 639-1 code, optionally with an ISO 3166 region.
+	Language string `db:"language" json:"language,omitempty" example:"en"`
+	// Authors are credited in the order given.
+	Authors []BookAuthor `db:"-" json:"authors"`
+}
+
+// BookAuthor credits an author on a book.
+type BookAuthor struct {
+	AuthorID int    `db:"author_id" json:"author_id" example:"1"`
+	Role     string `db:"role" json:"role,omitempty" example:"author"`
+	// Position is the 1-based credit order. It is set by the server from the
+	// order of Book.Authors.
+	Position int `db:"position" json:"position" example:"1"`
+}
+
+// Contributor is an author together with their credit on a book.
+type Contributor struct {
+	author.Author
+	Role     string `json:"role" example:"author"`
+	Position int    `json:"position" example:"1"`
+}
+
+// Date is a calendar date without a time of day.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the Date of year, month and day.
+func NewDate(year int, month time.Month, day int) *Date {
+	return &Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	parsed, err := time.Parse(`"`+dateLayout+`"`, string(data))
+	if err != nil {
+		return fmt.Errorf("invalid date %s, expected YYYY-MM-DD", data)
+	}
+	d.Time = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DATE columns.
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		d.Time = time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		return nil
+	case string:
+		return d.scanString(v)
+	case []byte:
+		return d.scanString(string(v))
+	}
+	return fmt.Errorf("cannot scan %T into Date", value)
+}
+
+func (d *Date) scanString(s string) error {
+	if len(s) > len(dateLayout) {
+		s = s[:len(dateLayout)]
+	}
+	parsed, err := time.Parse(dateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	d.Time = parsed
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
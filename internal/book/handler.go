@@ -0,0 +1,263 @@
+// handler.go
+package book
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/authz"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+)
+
+func SetupRouter(router gin.IRouter, bookRepo BookRepository, authorRepo author.AuthorRepository, authn *auth.Authentication, policy *authz.Policy) {
+
+	bookService := NewBookService(bookRepo, authorRepo)
+	handler := NewBookHandler(bookService)
+
+	bookRoutes := router.Group("/books", authn.Optional())
+	{
+		bookRoutes.GET("/", policy.Require(authz.BooksRead), handler.GetAllBooks)
+		bookRoutes.GET("/:id", policy.Require(authz.BooksRead), handler.GetBookByID)
+		bookRoutes.GET("/:id/authors", policy.Require(authz.BooksRead, authz.AuthorsRead), handler.GetBookAuthors)
+		bookRoutes.POST("/", policy.Require(authz.BooksWrite), handler.CreateBook)
+		bookRoutes.PUT("/:id", policy.Require(authz.BooksWrite), handler.UpdateBook)
+		bookRoutes.DELETE("/:id", policy.Require(authz.BooksWrite), handler.DeleteBook)
+	}
+
+	router.GET("/authors/:id/books", authn.Optional(), policy.Require(authz.BooksRead), handler.GetAuthorBooks)
+}
+
+type BookHandler struct {
+	service BookService
+}
+
+func NewBookHandler(service BookService) *BookHandler {
+	return &BookHandler{service: service}
+}
+
+// GetAllBooks fetches all books.
+// @Summary Get all books
+// @Description Retrieve a list of all books with their credited authors. Books have no timestamps, so book responses carry only an ETag for If-None-Match.
+// @Tags books
+// @Produce json
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {array} Book
+// @Success 304 "Not Modified"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError
+// @Router /books [get]
+func (h *BookHandler) GetAllBooks(c *gin.Context) {
+	books, err := h.service.GetAllBooks()
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+	httputil.JSONWithValidators(c, http.StatusOK, books, time.Time{})
+}
+
+// GetBookByID retrieves a book by ID.
+// @Summary Get a book by ID
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} Book
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 404 {object} httputil.HTTPError "Book not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /books/{id} [get]
+func (h *BookHandler) GetBookByID(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	book, err := h.service.GetBookById(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, book, time.Time{})
+}
+
+// GetBookAuthors lists the authors credited on a book.
+// @Summary Get the authors of a book
+// @Description Retrieve the authors credited on a book, in credit order, with their roles
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {array} Contributor
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 404 {object} httputil.HTTPError "Book not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /books/{id}/authors [get]
+func (h *BookHandler) GetBookAuthors(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	contributors, err := h.service.GetBookAuthors(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, contributors, time.Time{})
+}
+
+// GetAuthorBooks lists the books an author is credited on.
+// @Summary Get the books of an author
+// @Tags books
+// @Produce json
+// @Param id path int true "Author ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {array} Book
+// @Success 304 "Not Modified"
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 404 {object} httputil.HTTPError "Author not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /authors/{id}/books [get]
+func (h *BookHandler) GetAuthorBooks(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.service.GetBooksByAuthor(id)
+	if err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	httputil.JSONWithValidators(c, http.StatusOK, books, time.Time{})
+}
+
+// CreateBook creates a new book.
+// @Summary Create a new book
+// @Description Create a book. Authors are credited in the order given; the role defaults to author.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param book body Book true "Book object"
+// @Success 201 {object} Book
+// @Header 201 {string} Location "URL of the new book"
+// @Failure 400 {object} httputil.HTTPError "Bad request, invalid ISBN or unknown author"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing books:write permission"
+// @Failure 409 {object} httputil.HTTPError "ISBN already exists"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /books [post]
+func (h *BookHandler) CreateBook(c *gin.Context) {
+	var newBook Book
+	if err := c.ShouldBindJSON(&newBook); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.CreateBook(&newBook); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, strconv.Itoa(newBook.ID)))
+	c.JSON(http.StatusCreated, newBook)
+}
+
+// UpdateBook replaces a book.
+// @Summary Update a book
+// @Description Replace a book's fields and credited authors
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param book body Book true "Book object"
+// @Success 200 {object} Book
+// @Failure 400 {object} httputil.HTTPError "Bad request, invalid ISBN or unknown author"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing books:write permission"
+// @Failure 404 {object} httputil.HTTPError "Book not found"
+// @Failure 409 {object} httputil.HTTPError "ISBN already exists"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /books/{id} [put]
+func (h *BookHandler) UpdateBook(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+	var updatedBook Book
+	if err := c.ShouldBindJSON(&updatedBook); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.UpdateBook(&updatedBook, id); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedBook)
+}
+
+// DeleteBook deletes a book and its author credits.
+// @Summary Delete a book
+// @Tags books
+// @Param id path int true "Book ID"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 401 {object} httputil.HTTPError "Unauthorized"
+// @Failure 403 {object} httputil.HTTPError "Missing books:write permission"
+// @Failure 404 {object} httputil.HTTPError "Book not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security BearerAuth
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Router /books/{id} [delete]
+func (h *BookHandler) DeleteBook(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteBook(id); err != nil {
+		httputil.NewError(c, httputil.StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// paramID parses the :id path parameter, writing a 400 response if it is not
+// a number.
+func paramID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httputil.NewError(c, http.StatusBadRequest, err)
+		return 0, false
+	}
+	return id, true
+}
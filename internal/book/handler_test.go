@@ -0,0 +1,142 @@
+package book
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/authz"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// roleAuthenticator trusts "Authorization: Test <role>" headers.
+type roleAuthenticator struct{}
+
+func (roleAuthenticator) Scheme() string { return "Test" }
+
+func (roleAuthenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	role, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Test ")
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return &auth.Principal{Subject: role, Method: "test", Roles: []string{role}}, nil
+}
+
+// newTestRouter serves the author and book routes over memory repositories,
+// wired like main.
+func newTestRouter() *gin.Engine {
+	authn := auth.NewAuthentication(roleAuthenticator{})
+	policy := authz.NewPolicyFromConfig(&config.Config{})
+	books := NewMemoryBookRepository()
+	authors := GuardAuthorDeletes(author.NewMemoryAuthorRepository(), books)
+
+	router := gin.New()
+	author.SetupRouter(router, authors, authn, policy)
+	SetupRouter(router, books, authors, authn, policy)
+	return router
+}
+
+func serveAs(router *gin.Engine, role, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("Authorization", "Test "+role)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBookRoutes(t *testing.T) {
+	// Arrange
+	router := newTestRouter()
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"Alan Donovan"}`).Code)
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"Brian Kernighan"}`).Code)
+	body := `{"title":"The Go Programming Language","isbn":"978-0-13-419044-0","published_on":"2015-10-26","language":"en",
+		"authors":[{"author_id":1},{"author_id":2}]}`
+
+	// Act
+	anonymous := serveAs(router, "", "POST", "/books/", body)
+	created := serveAs(router, "editor", "POST", "/books/", body)
+	get := serveAs(router, "", "GET", "/books/1", "")
+	bookAuthors := serveAs(router, "", "GET", "/books/1/authors", "")
+	authorBooks := serveAs(router, "", "GET", "/authors/2/books", "")
+	deleteAuthor := serveAs(router, "editor", "DELETE", "/authors/1", "")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	assert.Equal(t, http.StatusCreated, created.Code)
+	assert.Equal(t, "/books/1", created.Header().Get("Location"))
+	expected := `{"id":1,"title":"The Go Programming Language","isbn":"9780134190440","published_on":"2015-10-26","language":"en",
+		"authors":[{"author_id":1,"role":"author","position":1},{"author_id":2,"role":"author","position":2}]}`
+	assert.JSONEq(t, expected, created.Body.String())
+	assert.Equal(t, http.StatusOK, get.Code)
+	assert.JSONEq(t, expected, get.Body.String())
+	assert.NotEmpty(t, get.Header().Get("ETag"))
+	assert.Empty(t, get.Header().Get("Last-Modified"))
+	var contributors []Contributor
+	require.NoError(t, json.Unmarshal(bookAuthors.Body.Bytes(), &contributors))
+	require.Len(t, contributors, 2)
+	for i, name := range []string{"Alan Donovan", "Brian Kernighan"} {
+		assert.Equal(t, name, contributors[i].Name)
+		assert.Equal(t, "test:editor", contributors[i].CreatedBy)
+		assert.False(t, contributors[i].CreatedAt.IsZero())
+		assert.Equal(t, RoleAuthor, contributors[i].Role)
+		assert.Equal(t, i+1, contributors[i].Position)
+	}
+	assert.JSONEq(t, `[`+expected+`]`, authorBooks.Body.String())
+	assert.Equal(t, http.StatusConflict, deleteAuthor.Code)
+	assert.Equal(t, http.StatusOK, serveAs(router, "", "GET", "/authors/1", "").Code)
+}
+
+func TestBookRoutes_Errors(t *testing.T) {
+	// Arrange
+	router := newTestRouter()
+
+	// Act
+	badISBN := serveAs(router, "editor", "POST", "/books/", `{"title":"Untitled","isbn":"9780134190441"}`)
+	badDate := serveAs(router, "editor", "POST", "/books/", `{"title":"Untitled","isbn":"9780134190440","published_on":"26/10/2015"}`)
+	unknownAuthor := serveAs(router, "editor", "POST", "/books/", `{"title":"Untitled","isbn":"9780134190440","authors":[{"author_id":7}]}`)
+	missingBook := serveAs(router, "", "GET", "/books/7/authors", "")
+	missingAuthor := serveAs(router, "", "GET", "/authors/7/books", "")
+	invalidID := serveAs(router, "", "GET", "/books/abc", "")
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, badISBN.Code)
+	assert.Contains(t, badISBN.Body.String(), "check digit")
+	assert.Equal(t, http.StatusBadRequest, badDate.Code)
+	assert.Equal(t, http.StatusBadRequest, unknownAuthor.Code)
+	assert.Equal(t, http.StatusNotFound, missingBook.Code)
+	assert.Equal(t, http.StatusNotFound, missingAuthor.Code)
+	assert.Equal(t, http.StatusBadRequest, invalidID.Code)
+}
+
+func TestBookRoutes_UpdateAndDelete(t *testing.T) {
+	// Arrange
+	router := newTestRouter()
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/authors/", `{"name":"Alan Donovan"}`).Code)
+	require.Equal(t, http.StatusCreated, serveAs(router, "editor", "POST", "/books/", `{"title":"Draft","isbn":"0134190440","authors":[{"author_id":1}]}`).Code)
+
+	// Act
+	byReader := serveAs(router, "reader", "PUT", "/books/1", `{"title":"Final","isbn":"0134190440"}`)
+	updated := serveAs(router, "editor", "PUT", "/books/1", `{"title":"Final","isbn":"0134190440"}`)
+	deleteAuthor := serveAs(router, "editor", "DELETE", "/authors/1", "")
+	deleted := serveAs(router, "editor", "DELETE", "/books/1", "")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, byReader.Code)
+	assert.Equal(t, http.StatusOK, updated.Code)
+	assert.Contains(t, updated.Body.String(), `"authors":[]`)
+	// Removing the only credit frees the author.
+	assert.Equal(t, http.StatusNoContent, deleteAuthor.Code)
+	assert.Equal(t, http.StatusNoContent, deleted.Code)
+	assert.Equal(t, http.StatusNotFound, serveAs(router, "", "GET", "/books/1", "").Code)
+}
@@ -0,0 +1,86 @@
+package book
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrISBNLength   = errors.New("ISBN must have 10 or 13 digits")
+	ErrISBNChecksum = errors.New("ISBN check digit does not match")
+)
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13, ignoring hyphens and
+// spaces, and returns it as a 13 digit ISBN.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		if r == 'x' {
+			return 'X'
+		}
+		return r
+	}, isbn)
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrISBNChecksum
+		}
+		// ISBN-10s map onto the 978 prefix with a recomputed check digit.
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		if !allDigits(digits) {
+			return "", ErrISBNLength
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", ErrISBNChecksum
+		}
+		return digits, nil
+	}
+	return "", ErrISBNLength
+}
+
+// validISBN10 checks the mod 11 checksum, where a final X stands for 10.
+func validISBN10(digits string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := digits[i]
+		var value int
+		switch {
+		case c >= '0' && c <= '9':
+			value = int(c - '0')
+		case c == 'X' && i == 9:
+			value = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * value
+	}
+	return sum%11 == 0
+}
+
+// isbn13CheckDigit returns the mod 10 check digit for the first 12 digits,
+// weighted alternately by 1 and 3.
+func isbn13CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package book
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{"isbn13", "9780134190440", "9780134190440", nil},
+		{"isbn13 with hyphens", "978-0-13-419044-0", "9780134190440", nil},
+		{"isbn10", "0134190440", "9780134190440", nil},
+		{"isbn10 with X check digit", "0-8044-2957-x", "9780804429573", nil},
+		{"isbn13 bad checksum", "9780134190441", "", ErrISBNChecksum},
+		{"isbn10 bad checksum", "0134190441", "", ErrISBNChecksum},
+		{"X in the middle", "01341X0440", "", ErrISBNChecksum},
+		{"letters", "978013419044A", "", ErrISBNLength},
+		{"too short", "12345", "", ErrISBNLength},
+		{"empty", "", "", ErrISBNLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got, err := NormalizeISBN(tt.input)
+
+			// Assert
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package book
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+type memoryBookRepository struct {
+	mu     sync.RWMutex
+	books  map[int]Book
+	nextID int
+}
+
+// NewMemoryBookRepository returns a thread-safe BookRepository that keeps
+// books in process memory. It is intended for local development and tests.
+// It does not check that authors exist; the service does.
+func NewMemoryBookRepository() BookRepository {
+	return &memoryBookRepository{books: make(map[int]Book), nextID: 1}
+}
+
+func (m *memoryBookRepository) GetAllBooks() ([]*Book, error) {
+	return m.filter(func(Book) bool { return true }), nil
+}
+
+func (m *memoryBookRepository) GetBookById(id int) (*Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	book, ok := m.books[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return copyBook(book), nil
+}
+
+func (m *memoryBookRepository) CreateBook(book *Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkISBN(book.ISBN, 0); err != nil {
+		return err
+	}
+	book.ID = m.nextID
+	m.nextID++
+	m.books[book.ID] = *copyBook(*book)
+	return nil
+}
+
+func (m *memoryBookRepository) UpdateBook(book *Book, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[id]; !ok {
+		return sql.ErrNoRows
+	}
+	if err := m.checkISBN(book.ISBN, id); err != nil {
+		return err
+	}
+	updated := *copyBook(*book)
+	updated.ID = id
+	m.books[id] = updated
+	return nil
+}
+
+func (m *memoryBookRepository) DeleteBook(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.books, id)
+	return nil
+}
+
+func (m *memoryBookRepository) GetBooksByAuthor(authorID int) ([]*Book, error) {
+	return m.filter(func(book Book) bool { return credits(book, authorID) }), nil
+}
+
+func (m *memoryBookRepository) CountBooksByAuthor(authorID int) (int, error) {
+	return len(m.filter(func(book Book) bool { return credits(book, authorID) })), nil
+}
+
+// filter returns copies of the books matching keep, ordered by id.
+func (m *memoryBookRepository) filter(keep func(Book) bool) []*Book {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	books := make([]*Book, 0, len(m.books))
+	for _, book := range m.books {
+		if keep(book) {
+			books = append(books, copyBook(book))
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books
+}
+
+// checkISBN mirrors the unique constraint on books.isbn.
+func (m *memoryBookRepository) checkISBN(isbn string, exceptID int) error {
+	for id, existing := range m.books {
+		if id != exceptID && existing.ISBN == isbn {
+			return errs.NewConflictError(resourceName, "books_isbn_key", "isbn")
+		}
+	}
+	return nil
+}
+
+func credits(book Book, authorID int) bool {
+	for _, a := range book.Authors {
+		if a.AuthorID == authorID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyBook returns a copy of book that shares no memory with it.
+func copyBook(book Book) *Book {
+	book.Authors = append([]BookAuthor{}, book.Authors...)
+	if book.PublishedOn != nil {
+		date := *book.PublishedOn
+		book.PublishedOn = &date
+	}
+	return &book
+}
@@ -0,0 +1,196 @@
+package book
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+const (
+	bookColumns       = "id, title, isbn, published_on, language"
+	bookAuthorColumns = "author_id, role, position"
+)
+
+type BookRepository interface {
+	// GetAllBooks returns every book, with its authors, ordered by id.
+	GetAllBooks() ([]*Book, error)
+	GetBookById(id int) (*Book, error)
+	// CreateBook inserts the book together with its authors.
+	CreateBook(book *Book) error
+	// UpdateBook replaces the book's fields and authors.
+	UpdateBook(book *Book, id int) error
+	DeleteBook(id int) error
+	// GetBooksByAuthor returns the books crediting the author, ordered by id.
+	GetBooksByAuthor(authorID int) ([]*Book, error)
+	CountBooksByAuthor(authorID int) (int, error)
+}
+
+type bookRepository struct {
+	db *sqlx.DB
+}
+
+func NewBookRepository(db *sqlx.DB) BookRepository {
+	return &bookRepository{db: db}
+}
+
+// NewRepository returns the BookRepository implementation for the configured
+// database driver. Books need Postgres; other drivers fall back to an
+// in-memory store that is lost on restart.
+func NewRepository(driver string, db *sqlx.DB) (BookRepository, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewBookRepository(db), nil
+	case database.DriverSQLite, database.DriverMemory:
+		logger.Warning("books are kept in memory", zap.String("driver", driver))
+		return NewMemoryBookRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func (r bookRepository) GetAllBooks() ([]*Book, error) {
+	books := []*Book{}
+	if err := r.db.Select(&books, "SELECT "+bookColumns+" FROM books ORDER BY id"); err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return books, r.loadAuthors(books)
+}
+
+func (r bookRepository) GetBookById(id int) (*Book, error) {
+	var book Book
+	if err := r.db.Get(&book, "SELECT "+bookColumns+" FROM books WHERE id = $1", id); err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &book, r.loadAuthors([]*Book{&book})
+}
+
+func (r bookRepository) CreateBook(book *Book) error {
+	return r.inTx(func(tx *sqlx.Tx) error {
+		err := tx.Get(&book.ID,
+			"INSERT INTO books (title, isbn, published_on, language) VALUES ($1, $2, $3, $4) RETURNING id",
+			book.Title, book.ISBN, book.PublishedOn, book.Language)
+		if err != nil {
+			return err
+		}
+		return insertAuthors(tx, book.ID, book.Authors)
+	})
+}
+
+func (r bookRepository) UpdateBook(book *Book, id int) error {
+	return r.inTx(func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(
+			"UPDATE books SET title = $1, isbn = $2, published_on = $3, language = $4 WHERE id = $5",
+			book.Title, book.ISBN, book.PublishedOn, book.Language, id)
+		if err != nil {
+			return err
+		}
+		if err := requireRowsAffected(res); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM book_authors WHERE book_id = $1", id); err != nil {
+			return err
+		}
+		return insertAuthors(tx, id, book.Authors)
+	})
+}
+
+func (r bookRepository) DeleteBook(id int) error {
+	// book_authors rows are removed by ON DELETE CASCADE.
+	res, err := r.db.Exec("DELETE FROM books WHERE id = $1", id)
+	if err != nil {
+		return errs.FromPostgresDelete(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r bookRepository) GetBooksByAuthor(authorID int) ([]*Book, error) {
+	books := []*Book{}
+	err := r.db.Select(&books,
+		"SELECT "+bookColumns+" FROM books WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = $1) ORDER BY id",
+		authorID)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return books, r.loadAuthors(books)
+}
+
+func (r bookRepository) CountBooksByAuthor(authorID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, "SELECT count(DISTINCT book_id) FROM book_authors WHERE author_id = $1", authorID)
+	return count, errs.FromPostgres(err, resourceName)
+}
+
+// loadAuthors fills in the authors of books with a single query.
+func (r bookRepository) loadAuthors(books []*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make(pq.Int64Array, len(books))
+	byID := make(map[int]*Book, len(books))
+	for i, book := range books {
+		ids[i] = int64(book.ID)
+		book.Authors = []BookAuthor{}
+		byID[book.ID] = book
+	}
+
+	var rows []struct {
+		BookID int `db:"book_id"`
+		BookAuthor
+	}
+	err := r.db.Select(&rows,
+		"SELECT book_id, "+bookAuthorColumns+" FROM book_authors WHERE book_id = ANY($1) ORDER BY book_id, position",
+		ids)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	for _, row := range rows {
+		byID[row.BookID].Authors = append(byID[row.BookID].Authors, row.BookAuthor)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction and translates its error.
+func (r bookRepository) inTx(fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return err
+		}
+		return errs.FromPostgres(err, resourceName)
+	}
+	return tx.Commit()
+}
+
+func insertAuthors(tx *sqlx.Tx, bookID int, authors []BookAuthor) error {
+	for _, a := range authors {
+		_, err := tx.Exec("INSERT INTO book_authors (book_id, author_id, role, position) VALUES ($1, $2, $3, $4)",
+			bookID, a.AuthorID, a.Role, a.Position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireRowsAffected returns sql.ErrNoRows when a write statement matched no
+// rows, so every implementation reports a missing book the same way.
+func requireRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package book
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+// languagePattern matches an ISO 639-1 language code with an optional ISO
+// 3166-1 region, such as "en" or "pt-BR".
+var languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+type BookService interface {
+	GetAllBooks() ([]*Book, error)
+	GetBookById(id int) (*Book, error)
+	CreateBook(book *Book) error
+	UpdateBook(book *Book, id int) error
+	DeleteBook(id int) error
+	// GetBookAuthors returns the authors credited on a book in credit order.
+	GetBookAuthors(id int) ([]*Contributor, error)
+	GetBooksByAuthor(authorID int) ([]*Book, error)
+}
+
+type bookService struct {
+	repo    BookRepository
+	authors author.AuthorRepository
+}
+
+func NewBookService(repo BookRepository, authors author.AuthorRepository) BookService {
+	return &bookService{repo: repo, authors: authors}
+}
+
+func (s bookService) GetAllBooks() ([]*Book, error) {
+	return s.repo.GetAllBooks()
+}
+
+func (s bookService) GetBookById(id int) (*Book, error) {
+	book, err := s.repo.GetBookById(id)
+	if err == sql.ErrNoRows {
+		return nil, errs.NewNotFoundError(resourceName)
+	}
+	return book, err
+}
+
+func (s bookService) CreateBook(book *Book) error {
+	if err := s.validate(book); err != nil {
+		return err
+	}
+	return s.repo.CreateBook(book)
+}
+
+func (s bookService) UpdateBook(book *Book, id int) error {
+	if err := s.validate(book); err != nil {
+		return err
+	}
+	err := s.repo.UpdateBook(book, id)
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError(resourceName)
+	}
+	if err != nil {
+		return err
+	}
+	book.ID = id
+	return nil
+}
+
+func (s bookService) DeleteBook(id int) error {
+	err := s.repo.DeleteBook(id)
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError(resourceName)
+	}
+	return err
+}
+
+func (s bookService) GetBookAuthors(id int) ([]*Contributor, error) {
+	book, err := s.GetBookById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	contributors := make([]*Contributor, 0, len(book.Authors))
+	for _, credit := range book.Authors {
+		a, err := s.authors.GetAuthorById(credit.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		contributors = append(contributors, &Contributor{Author: *a, Role: credit.Role, Position: credit.Position})
+	}
+	return contributors, nil
+}
+
+func (s bookService) GetBooksByAuthor(authorID int) ([]*Book, error) {
+	if _, err := s.authors.GetAuthorById(authorID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("Author")
+		}
+		return nil, err
+	}
+	return s.repo.GetBooksByAuthor(authorID)
+}
+
+// validate normalizes the book in place and checks that its authors exist.
+func (s bookService) validate(book *Book) error {
+	book.Title = strings.TrimSpace(book.Title)
+	if book.Title == "" {
+		return errs.NewValidationError(resourceName, "title", "must not be empty")
+	}
+
+	isbn, err := NormalizeISBN(book.ISBN)
+	if err != nil {
+		return errs.NewValidationError(resourceName, "isbn", err.Error())
+	}
+	book.ISBN = isbn
+
+	if book.Language != "" {
+		language, region, _ := strings.Cut(strings.TrimSpace(book.Language), "-")
+		book.Language = strings.ToLower(language)
+		if region != "" {
+			book.Language += "-" + strings.ToUpper(region)
+		}
+		if !languagePattern.MatchString(book.Language) {
+			return errs.NewValidationError(resourceName, "language", "must be an ISO 639-1 code such as en or pt-BR")
+		}
+	}
+
+	if book.Authors == nil {
+		book.Authors = []BookAuthor{}
+	}
+	seen := make(map[BookAuthor]bool, len(book.Authors))
+	for i := range book.Authors {
+		credit := &book.Authors[i]
+		credit.Position = i + 1
+		if credit.Role == "" {
+			credit.Role = RoleAuthor
+		}
+		if !validRoles[credit.Role] {
+			return errs.NewValidationError(resourceName, "authors", fmt.Sprintf("unknown role %q", credit.Role))
+		}
+		key := BookAuthor{AuthorID: credit.AuthorID, Role: credit.Role}
+		if seen[key] {
+			return errs.NewValidationError(resourceName, "authors", fmt.Sprintf("author %d is credited twice as %s", credit.AuthorID, credit.Role))
+		}
+		seen[key] = true
+
+		if _, err := s.authors.GetAuthorById(credit.AuthorID); err != nil {
+			if err == sql.ErrNoRows {
+				return errs.NewValidationError(resourceName, "authors", fmt.Sprintf("author %d does not exist", credit.AuthorID))
+			}
+			return err
+		}
+	}
+	return nil
+}
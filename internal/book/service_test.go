@@ -0,0 +1,198 @@
+package book
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/author"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestService returns a service over memory repositories with two authors,
+// ids 1 and 2, and the guarded author repository.
+func newTestService(t *testing.T) (BookService, author.AuthorRepository) {
+	t.Helper()
+	authors := author.NewMemoryAuthorRepository()
+	require.NoError(t, authors.CreateAuthor(context.Background(), &author.Author{Name: "Alan Donovan"}))
+	require.NoError(t, authors.CreateAuthor(context.Background(), &author.Author{Name: "Brian Kernighan"}))
+	books := NewMemoryBookRepository()
+	return NewBookService(books, authors), GuardAuthorDeletes(authors, books)
+}
+
+func newBook() *Book {
+	return &Book{
+		Title:       " The Go Programming Language ",
+		ISBN:        "0-13-419044-0",
+		PublishedOn: NewDate(2015, time.October, 26),
+		Language:    "EN",
+		Authors:     []BookAuthor{{AuthorID: 1}, {AuthorID: 2, Role: RoleAuthor}},
+	}
+}
+
+func TestCreateBook_NormalizesAndOrdersAuthors(t *testing.T) {
+	// Arrange
+	svc, _ := newTestService(t)
+	book := newBook()
+
+	// Act
+	err := svc.CreateBook(book)
+
+	// Assert
+	require.NoError(t, err)
+	stored, err := svc.GetBookById(book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "The Go Programming Language", stored.Title)
+	assert.Equal(t, "9780134190440", stored.ISBN)
+	assert.Equal(t, "en", stored.Language)
+	assert.Equal(t, "2015-10-26", stored.PublishedOn.String())
+	assert.Equal(t, []BookAuthor{
+		{AuthorID: 1, Role: RoleAuthor, Position: 1},
+		{AuthorID: 2, Role: RoleAuthor, Position: 2},
+	}, stored.Authors)
+}
+
+func TestCreateBook_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(book *Book)
+		field string
+	}{
+		{"empty title", func(b *Book) { b.Title = " " }, "title"},
+		{"bad checksum", func(b *Book) { b.ISBN = "9780134190441" }, "isbn"},
+		{"bad language", func(b *Book) { b.Language = "english" }, "language"},
+		{"unknown author", func(b *Book) { b.Authors = []BookAuthor{{AuthorID: 42}} }, "authors"},
+		{"unknown role", func(b *Book) { b.Authors = []BookAuthor{{AuthorID: 1, Role: "ghost"}} }, "authors"},
+		{"duplicate credit", func(b *Book) { b.Authors = []BookAuthor{{AuthorID: 1}, {AuthorID: 1}} }, "authors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			svc, _ := newTestService(t)
+			book := newBook()
+			tt.edit(book)
+
+			// Act
+			err := svc.CreateBook(book)
+
+			// Assert
+			var validation *errs.ValidationError
+			require.ErrorAs(t, err, &validation)
+			assert.Equal(t, tt.field, validation.Field)
+		})
+	}
+}
+
+func TestCreateBook_DuplicateISBNIsConflict(t *testing.T) {
+	// Arrange
+	svc, _ := newTestService(t)
+	require.NoError(t, svc.CreateBook(newBook()))
+	duplicate := newBook()
+	// The same ISBN written as ISBN-13.
+	duplicate.ISBN = "978-0-13-419044-0"
+
+	// Act
+	err := svc.CreateBook(duplicate)
+
+	// Assert
+	assert.IsType(t, &errs.ConflictError{}, err)
+}
+
+func TestGetBookAuthorsAndBooksByAuthor(t *testing.T) {
+	// Arrange
+	svc, _ := newTestService(t)
+	book := newBook()
+	book.Authors = []BookAuthor{{AuthorID: 2}, {AuthorID: 1, Role: RoleEditor}}
+	require.NoError(t, svc.CreateBook(book))
+
+	// Act
+	contributors, err := svc.GetBookAuthors(book.ID)
+	require.NoError(t, err)
+	byAuthor, byAuthorErr := svc.GetBooksByAuthor(1)
+	_, unknownErr := svc.GetBooksByAuthor(42)
+
+	// Assert
+	require.Len(t, contributors, 2)
+	assert.Equal(t, "Brian Kernighan", contributors[0].Name)
+	assert.Equal(t, RoleAuthor, contributors[0].Role)
+	assert.Equal(t, "Alan Donovan", contributors[1].Name)
+	assert.Equal(t, RoleEditor, contributors[1].Role)
+	assert.Equal(t, 2, contributors[1].Position)
+	require.NoError(t, byAuthorErr)
+	require.Len(t, byAuthor, 1)
+	assert.Equal(t, book.ID, byAuthor[0].ID)
+	assert.IsType(t, &errs.NotFoundError{}, unknownErr)
+}
+
+func TestGuardAuthorDeletes_BlocksAuthorsWithBooks(t *testing.T) {
+	// Arrange
+	svc, authors := newTestService(t)
+	book := newBook()
+	book.Authors = []BookAuthor{{AuthorID: 1}}
+	require.NoError(t, svc.CreateBook(book))
+
+	// Act
+	credited := authors.DeleteAuthor(context.Background(), 1)
+	uncredited := authors.DeleteAuthor(context.Background(), 2)
+	require.NoError(t, svc.DeleteBook(book.ID))
+	afterBookDeleted := authors.DeleteAuthor(context.Background(), 1)
+
+	// Assert
+	assert.IsType(t, &errs.InUseError{}, credited)
+	assert.NoError(t, uncredited)
+	assert.NoError(t, afterBookDeleted)
+}
+
+func TestGuardAuthorDeletes_FailsOnlyCreditedAuthorsInOperations(t *testing.T) {
+	// Arrange
+	svc, authors := newTestService(t)
+	book := newBook()
+	book.Authors = []BookAuthor{{AuthorID: 1}}
+	require.NoError(t, svc.CreateBook(book))
+
+	// Act
+	results, err := authors.ApplyAuthorOperations(context.Background(), []author.BatchOperation{
+		{Op: author.BatchDelete, ID: 1},
+		{Op: author.BatchDelete, ID: 2},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.IsType(t, &errs.InUseError{}, results[0])
+	assert.NoError(t, results[1])
+	_, stillThere := authors.GetAuthorById(1)
+	assert.NoError(t, stillThere)
+}
+
+func TestUpdateBook_ReplacesAuthors(t *testing.T) {
+	// Arrange
+	svc, _ := newTestService(t)
+	book := newBook()
+	require.NoError(t, svc.CreateBook(book))
+	update := newBook()
+	update.Authors = []BookAuthor{{AuthorID: 2, Role: RoleTranslator}}
+
+	// Act
+	err := svc.UpdateBook(update, book.ID)
+	missingErr := svc.UpdateBook(newBook(), 42)
+
+	// Assert
+	require.NoError(t, err)
+	stored, err := svc.GetBookById(book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []BookAuthor{{AuthorID: 2, Role: RoleTranslator, Position: 1}}, stored.Authors)
+	assert.IsType(t, &errs.NotFoundError{}, missingErr)
+}
@@ -0,0 +1,23 @@
+package errs
+
+// ConflictError represents an error when a resource clashes with an existing one,
+// typically because a unique constraint was violated.
+type ConflictError struct {
+	Resource   string
+	Constraint string
+	Column     string
+}
+
+// NewConflictError creates a new ConflictError.
+func NewConflictError(resource, constraint, column string) error {
+	return &ConflictError{Resource: resource, Constraint: constraint, Column: column}
+}
+
+// Error returns the error message for ConflictError.
+func (e ConflictError) Error() string {
+	msg := e.Resource + " already exists"
+	if e.Constraint != "" {
+		msg += " (constraint " + e.Constraint + ")"
+	}
+	return msg
+}
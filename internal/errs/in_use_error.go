@@ -0,0 +1,22 @@
+package errs
+
+// InUseError represents an error when a resource cannot be deleted because
+// other resources still reference it.
+type InUseError struct {
+	Resource   string
+	Constraint string
+}
+
+// NewInUseError creates a new InUseError.
+func NewInUseError(resource, constraint string) error {
+	return &InUseError{Resource: resource, Constraint: constraint}
+}
+
+// Error returns the error message for InUseError.
+func (e InUseError) Error() string {
+	msg := e.Resource + " is still in use"
+	if e.Constraint != "" {
+		msg += " (constraint " + e.Constraint + ")"
+	}
+	return msg
+}
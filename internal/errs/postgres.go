@@ -0,0 +1,51 @@
+package errs
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes translated by FromPostgres.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgQueryCanceled        = "57014"
+	pgSerializationFailure = "40001"
+)
+
+// FromPostgres translates a Postgres driver error into a domain error for the
+// given resource. Errors that are not *pq.Error, or whose SQLSTATE has no
+// domain equivalent, are returned unchanged.
+func FromPostgres(err error, resource string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		return NewConflictError(resource, pqErr.Constraint, pqErr.Column)
+	case pgForeignKeyViolation:
+		return &ValidationError{Resource: resource, Field: pqErr.Column, Constraint: pqErr.Constraint, Message: "references a missing record"}
+	case pgCheckViolation:
+		return &ValidationError{Resource: resource, Field: pqErr.Column, Constraint: pqErr.Constraint, Message: "violates a check constraint"}
+	case pgQueryCanceled:
+		return NewTimeoutError(resource)
+	case pgSerializationFailure:
+		return NewRetryableError(resource)
+	}
+	return err
+}
+
+// FromPostgresDelete is FromPostgres for DELETE statements, where a foreign
+// key violation means that other rows still reference the deleted one.
+func FromPostgresDelete(err error, resource string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return NewInUseError(resource, pqErr.Constraint)
+	}
+	return FromPostgres(err, resource)
+}
@@ -0,0 +1,75 @@
+package errs
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromPostgres_UniqueViolation(t *testing.T) {
+	// Arrange
+	pqErr := &pq.Error{Code: "23505", Constraint: "authors_name_key", Column: "name"}
+
+	// Act
+	err := FromPostgres(pqErr, "Author")
+
+	// Assert
+	var conflict *ConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, "authors_name_key", conflict.Constraint)
+	assert.Equal(t, "name", conflict.Column)
+	assert.EqualError(t, err, "Author already exists (constraint authors_name_key)")
+}
+
+func TestFromPostgres_ValidationViolations(t *testing.T) {
+	for _, code := range []pq.ErrorCode{"23503", "23514"} {
+		// Arrange
+		pqErr := &pq.Error{Code: code, Constraint: "authors_name_check", Column: "name"}
+
+		// Act
+		err := FromPostgres(pqErr, "Author")
+
+		// Assert
+		var validation *ValidationError
+		assert.True(t, errors.As(err, &validation), string(code))
+		assert.Equal(t, "authors_name_check", validation.Constraint)
+		assert.Equal(t, "name", validation.Field)
+	}
+}
+
+func TestFromPostgres_TransientErrors(t *testing.T) {
+	// Act
+	timeout := FromPostgres(&pq.Error{Code: "57014"}, "Author")
+	retryable := FromPostgres(&pq.Error{Code: "40001"}, "Author")
+
+	// Assert
+	assert.IsType(t, &TimeoutError{}, timeout)
+	assert.IsType(t, &RetryableError{}, retryable)
+}
+
+func TestFromPostgres_PassThrough(t *testing.T) {
+	// Arrange
+	unknown := &pq.Error{Code: "42601"}
+
+	// Act & Assert
+	assert.Nil(t, FromPostgres(nil, "Author"))
+	assert.Equal(t, sql.ErrNoRows, FromPostgres(sql.ErrNoRows, "Author"))
+	assert.Equal(t, unknown, FromPostgres(unknown, "Author"))
+}
+
+func TestFromPostgresDelete_ForeignKeyViolationMeansInUse(t *testing.T) {
+	// Arrange
+	pqErr := &pq.Error{Code: "23503", Constraint: "book_authors_author_id_fkey"}
+
+	// Act
+	err := FromPostgresDelete(pqErr, "Author")
+
+	// Assert
+	var inUse *InUseError
+	assert.True(t, errors.As(err, &inUse))
+	assert.EqualError(t, err, "Author is still in use (constraint book_authors_author_id_fkey)")
+	assert.IsType(t, &ConflictError{}, FromPostgresDelete(&pq.Error{Code: "23505"}, "Author"))
+}
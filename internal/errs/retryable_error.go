@@ -0,0 +1,17 @@
+package errs
+
+// RetryableError represents a transient failure, such as a serialization
+// conflict, where the client may safely retry the same request.
+type RetryableError struct {
+	Resource string
+}
+
+// NewRetryableError creates a new RetryableError.
+func NewRetryableError(resource string) error {
+	return &RetryableError{Resource: resource}
+}
+
+// Error returns the error message for RetryableError.
+func (e RetryableError) Error() string {
+	return e.Resource + " operation failed temporarily, please retry"
+}
@@ -0,0 +1,42 @@
+package errs
+
+import (
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// FromSQLite translates a SQLite driver error into a domain error for the
+// given resource, mirroring FromPostgres. SQLite does not report the
+// offending constraint or column separately, so only the category is kept.
+func FromSQLite(err error, resource string) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return NewConflictError(resource, "", "")
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return &ValidationError{Resource: resource, Message: "references a missing record"}
+	case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return &ValidationError{Resource: resource, Message: "violates a check constraint"}
+	case sqlite3.SQLITE_INTERRUPT:
+		return NewTimeoutError(resource)
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return NewRetryableError(resource)
+	}
+	return err
+}
+
+// FromSQLiteDelete is FromSQLite for DELETE statements, where a foreign key
+// violation means that other rows still reference the deleted one.
+func FromSQLiteDelete(err error, resource string) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return NewInUseError(resource, "")
+	}
+	return FromSQLite(err, resource)
+}
@@ -0,0 +1,27 @@
+package errs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSQLite_UniqueViolation(t *testing.T) {
+	// Arrange
+	db, err := sqlx.Connect("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.MustExec("CREATE TABLE authors (name TEXT UNIQUE)")
+	db.MustExec("INSERT INTO authors (name) VALUES ('John Doe')")
+
+	// Act
+	_, execErr := db.Exec("INSERT INTO authors (name) VALUES ('John Doe')")
+	err = FromSQLite(execErr, "Author")
+
+	// Assert
+	var conflict *ConflictError
+	assert.True(t, errors.As(err, &conflict))
+}
@@ -0,0 +1,16 @@
+package errs
+
+// TimeoutError represents an operation that was cancelled because it took too long.
+type TimeoutError struct {
+	Resource string
+}
+
+// NewTimeoutError creates a new TimeoutError.
+func NewTimeoutError(resource string) error {
+	return &TimeoutError{Resource: resource}
+}
+
+// Error returns the error message for TimeoutError.
+func (e TimeoutError) Error() string {
+	return e.Resource + " operation timed out"
+}
@@ -0,0 +1,30 @@
+package errs
+
+// ValidationError represents an error when a resource fails validation, either
+// in the service layer or because the database rejected it.
+type ValidationError struct {
+	Resource   string
+	Field      string
+	Constraint string
+	Message    string
+}
+
+// NewValidationError creates a new ValidationError for the given field.
+func NewValidationError(resource, field, message string) error {
+	return &ValidationError{Resource: resource, Field: field, Message: message}
+}
+
+// Error returns the error message for ValidationError.
+func (e ValidationError) Error() string {
+	msg := "invalid " + e.Resource
+	if e.Field != "" {
+		msg += " field " + e.Field
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Constraint != "" {
+		msg += " (constraint " + e.Constraint + ")"
+	}
+	return msg
+}
@@ -0,0 +1,290 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	logger.InitTestLogger()
+
+	// Run all tests
+	code := m.Run()
+	os.Exit(code)
+}
+
+func stores() map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			db, err := sqlx.Connect("sqlite", filepath.Join(t.TempDir(), "idempotency.db")+"?_pragma=busy_timeout(5000)")
+			require.NoError(t, err)
+			db.SetMaxOpenConns(1)
+			t.Cleanup(func() { db.Close() })
+			store, err := NewSQLiteStore(db)
+			require.NoError(t, err)
+			return store
+		},
+	}
+}
+
+func TestStore_PutAndGet(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Now().UTC().Truncate(time.Microsecond)
+			record := &Record{
+				Key:         "user:alice|key-1",
+				Fingerprint: "abc",
+				Status:      http.StatusCreated,
+				Header:      Header{"Location": {"/authors/1"}},
+				Body:        []byte(`{"id":1}`),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(time.Hour),
+			}
+			expired := &Record{Key: "user:alice|key-2", Body: []byte{}, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Second)}
+
+			// Act
+			require.NoError(t, store.Put(ctx, expired))
+			require.NoError(t, store.Put(ctx, record))
+			found, err := store.Get(ctx, record.Key)
+			require.NoError(t, err)
+			gone, goneErr := store.Get(ctx, expired.Key)
+			missing, missingErr := store.Get(ctx, "user:bob|key-1")
+
+			// Assert
+			assert.Equal(t, record, found)
+			assert.NoError(t, goneErr)
+			assert.Nil(t, gone)
+			assert.NoError(t, missingErr)
+			assert.Nil(t, missing)
+		})
+	}
+}
+
+func TestStore_LockRejectsHeldKey(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			store := newStore(t)
+			unlock, err := store.Lock(context.Background(), "key")
+			require.NoError(t, err)
+
+			// Act
+			_, held := store.Lock(context.Background(), "key")
+			otherUnlock, otherErr := store.Lock(context.Background(), "other")
+			unlock()
+			relockUnlock, relockErr := store.Lock(context.Background(), "key")
+
+			// Assert
+			assert.ErrorIs(t, held, ErrKeyInFlight)
+			require.NoError(t, otherErr)
+			otherUnlock()
+			require.NoError(t, relockErr)
+			relockUnlock()
+		})
+	}
+}
+
+// newRouter serves POST /authors/ with a handler that counts its calls and
+// answers with the status in the X-Status header, 201 by default. Requests
+// are authenticated as the subject in the X-User header, by the method in the
+// X-Method header.
+func newRouter(guard *Guard, calls *int32) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-User"); subject != "" {
+			auth.SetPrincipal(c, &auth.Principal{Subject: subject, Method: c.GetHeader("X-Method")})
+		}
+		c.Header("X-Request-ID", c.GetHeader("X-Request"))
+	}, guard.Handler())
+	router.POST("/authors/", func(c *gin.Context) {
+		n := atomic.AddInt32(calls, 1)
+		time.Sleep(5 * time.Millisecond)
+		status := http.StatusCreated
+		if value := c.GetHeader("X-Status"); value != "" {
+			status, _ = strconv.Atoi(value)
+		}
+		c.Header("Location", "/authors/"+strconv.Itoa(int(n)))
+		if c.GetHeader("X-No-Store") != "" {
+			c.Header("Cache-Control", "private, no-store")
+		}
+		if c.GetHeader("X-Empty") != "" {
+			c.Status(status)
+			return
+		}
+		c.JSON(status, gin.H{"call": n})
+	})
+	router.GET("/authors/", func(c *gin.Context) {
+		atomic.AddInt32(calls, 1)
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func send(router *gin.Engine, method, key, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/authors/", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_ReplaysStoredResponse(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := newRouter(NewGuard(NewMemoryStore(), time.Hour), &calls)
+
+	// Act
+	first := send(router, "POST", "key-1", `{"name":"John Doe"}`, "X-User", "alice", "X-Request", "req-1")
+	retry := send(router, "POST", "key-1", `{"name":"John Doe"}`, "X-User", "alice", "X-Request", "req-2")
+	otherBody := send(router, "POST", "key-1", `{"name":"Jane Smith"}`, "X-User", "alice")
+	otherCaller := send(router, "POST", "key-1", `{"name":"John Doe"}`, "X-User", "bob")
+	otherMethod := send(router, "POST", "key-1", `{"name":"John Doe"}`, "X-User", "alice", "X-Method", "jwt")
+	withoutKey := send(router, "POST", "", `{"name":"John Doe"}`, "X-User", "alice")
+	get := send(router, "GET", "key-1", "", "X-User", "alice")
+	tooLong := send(router, "POST", strings.Repeat("k", MaxKeyLength+1), `{}`, "X-User", "alice")
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/authors/1", retry.Header().Get("Location"))
+	assert.Equal(t, "true", retry.Header().Get(HeaderReplayed))
+	assert.Empty(t, first.Header().Get(HeaderReplayed))
+	assert.Equal(t, "req-2", retry.Header().Get("X-Request-ID"), "headers set before the handler must not be replayed")
+	assert.Equal(t, http.StatusUnprocessableEntity, otherBody.Code)
+	assert.Equal(t, http.StatusCreated, otherCaller.Code)
+	assert.Empty(t, otherMethod.Header().Get(HeaderReplayed), "subjects of different methods must not share keys")
+	assert.Equal(t, http.StatusCreated, withoutKey.Code)
+	assert.Equal(t, http.StatusOK, get.Code)
+	assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+	assert.Equal(t, int32(5), calls)
+}
+
+func TestHandler_ServerErrorsAreNotStored(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := newRouter(NewGuard(NewMemoryStore(), time.Hour), &calls)
+
+	// Act
+	failed := send(router, "POST", "key-1", `{}`, "X-Status", "503")
+	retry := send(router, "POST", "key-1", `{}`)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, failed.Code)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestHandler_NoStoreResponsesAreNotStored(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := newRouter(NewGuard(NewMemoryStore(), time.Hour), &calls)
+
+	// Act
+	first := send(router, "POST", "key-1", `{}`, "X-No-Store", "true")
+	retry := send(router, "POST", "key-1", `{}`, "X-No-Store", "true")
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestHandler_ConcurrentDuplicatesRunOnce(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var calls int32
+			router := newRouter(NewGuard(newStore(t), time.Hour), &calls)
+			var wg sync.WaitGroup
+			responses := make([]*httptest.ResponseRecorder, 10)
+
+			// Act
+			for i := range responses {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					responses[i] = send(router, "POST", "key-1", `{"name":"John Doe"}`)
+				}(i)
+			}
+			wg.Wait()
+
+			// Assert: duplicates either replay the response or are told
+			// that the first request is still running.
+			assert.Equal(t, int32(1), calls)
+			for _, w := range responses {
+				if w.Code == http.StatusConflict {
+					assert.Contains(t, w.Body.String(), ErrKeyInFlight.Error())
+					continue
+				}
+				assert.Equal(t, http.StatusCreated, w.Code)
+				assert.JSONEq(t, `{"call":1}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_ReplaysEmptyBody(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var calls int32
+			router := newRouter(NewGuard(newStore(t), time.Hour), &calls)
+
+			// Act
+			first := send(router, "POST", "key-1", `{}`, "X-Empty", "true")
+			retry := send(router, "POST", "key-1", `{}`, "X-Empty", "true")
+
+			// Assert
+			assert.Equal(t, http.StatusCreated, first.Code)
+			assert.Equal(t, http.StatusCreated, retry.Code)
+			assert.Equal(t, "true", retry.Header().Get(HeaderReplayed))
+			assert.Empty(t, retry.Body.String())
+			assert.Equal(t, int32(1), calls)
+		})
+	}
+}
+
+func TestHandler_NilGuard(t *testing.T) {
+	// Arrange
+	var calls int32
+	var guard *Guard
+	router := newRouter(guard, &calls)
+
+	// Act
+	send(router, "POST", "key-1", `{}`)
+	send(router, "POST", "key-1", `{}`)
+
+	// Assert
+	assert.Equal(t, int32(2), calls)
+}
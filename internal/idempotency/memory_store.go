@@ -0,0 +1,74 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	keyLocks
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// NewMemoryStore returns a thread-safe Store that keeps records in process
+// memory. It is intended for local development and tests.
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[string]Record)}
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (*Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, ok := m.records[key]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	record.Header = Header(http.Header(record.Header).Clone())
+	record.Body = append([]byte(nil), record.Body...)
+	return &record, nil
+}
+
+func (m *memoryStore) Put(ctx context.Context, record *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, stored := range m.records {
+		if !stored.ExpiresAt.After(now) {
+			delete(m.records, key)
+		}
+	}
+	stored := *record
+	stored.Header = Header(http.Header(record.Header).Clone())
+	stored.Body = append([]byte(nil), record.Body...)
+	m.records[record.Key] = stored
+	return nil
+}
+
+// keyLocks tracks the keys held within the process. It implements
+// Store.Lock for the stores that only one process uses.
+type keyLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *keyLocks) Lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return nil, ErrKeyInFlight
+	}
+	if l.held == nil {
+		l.held = make(map[string]bool)
+	}
+	l.held[key] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}, nil
+}
@@ -0,0 +1,235 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+var replayedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "idempotency_replayed_total",
+	Help: "Requests answered with the stored response of an earlier request with the same Idempotency-Key, by route.",
+}, []string{"route"})
+
+const (
+	// HeaderKey is the request header that carries the idempotency key.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on responses that were replayed.
+	HeaderReplayed = "Idempotent-Replayed"
+	// MaxKeyLength is the longest idempotency key accepted.
+	MaxKeyLength = 255
+)
+
+var (
+	// ErrKeyTooLong is returned for idempotency keys longer than MaxKeyLength.
+	ErrKeyTooLong = errors.New("Idempotency-Key must not be longer than 255 characters")
+	// ErrKeyReused is returned when a key is sent again with a different
+	// request.
+	ErrKeyReused = errors.New("Idempotency-Key was already used with a different request")
+	// ErrKeyInFlight is returned while an earlier request with the same key
+	// is still running.
+	ErrKeyInFlight = errors.New("a request with this Idempotency-Key is in progress, retry later")
+	// ErrUnavailable is returned when the store cannot be reached, rather
+	// than risk applying a request twice.
+	ErrUnavailable = errors.New("idempotency keys are unavailable, retry later")
+)
+
+// Guard builds middleware that replays responses by idempotency key.
+type Guard struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewGuard creates a Guard that keeps responses in store for ttl.
+func NewGuard(store Store, ttl time.Duration) *Guard {
+	return &Guard{store: store, ttl: ttl}
+}
+
+// NewGuardFromConfig creates the configured Guard, or nil if idempotency
+// keys are disabled.
+func NewGuardFromConfig(config *config.Config, db *sqlx.DB) (*Guard, error) {
+	if !config.Idempotency.Enabled {
+		return nil, nil
+	}
+	store, err := NewStore(config.Database.Driver, db)
+	if err != nil {
+		return nil, err
+	}
+	return NewGuard(store, config.Idempotency.TTL), nil
+}
+
+// Handler makes POST and PATCH requests that carry an Idempotency-Key
+// header safe to retry. The first request with a key runs and, unless it
+// fails with a server error or is marked Cache-Control: no-store, as
+// responses carrying secrets are, its response is stored; later requests with
+// the key get the stored response, or 422 if they differ from the first.
+// A request whose key is held by one still running gets 409, rather than
+// waiting for it. Keys are scoped to the
+// caller, so Handler must run after authentication. A nil Guard lets every
+// request through.
+func (g *Guard) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if g == nil || key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(key) > MaxKeyLength {
+			httputil.NewError(c, http.StatusBadRequest, ErrKeyTooLong)
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				httputil.NewError(c, httputil.BindStatus(err), err)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		// Keys are scoped to the caller, so that callers cannot replay each
+		// other's responses.
+		principal, _ := auth.PrincipalFrom(c)
+		_, client := auth.ClientKey(c, principal)
+		key = client + "|" + key
+		fingerprint := requestFingerprint(c.Request, body)
+
+		ctx := c.Request.Context()
+		unlock, err := g.store.Lock(ctx, key)
+		if errors.Is(err, ErrKeyInFlight) {
+			httputil.NewError(c, http.StatusConflict, err)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			g.unavailable(c, err)
+			return
+		}
+		defer unlock()
+
+		record, err := g.store.Get(ctx, key)
+		if err != nil {
+			g.unavailable(c, err)
+			return
+		}
+		if record != nil {
+			if record.Fingerprint != fingerprint {
+				httputil.NewError(c, http.StatusUnprocessableEntity, ErrKeyReused)
+				c.Abort()
+				return
+			}
+			replayedRequests.WithLabelValues(c.FullPath()).Inc()
+			replay(c, record)
+			return
+		}
+
+		// Headers set before the handler runs, such as the request id and
+		// rate limits, describe this request rather than the response.
+		before := c.Writer.Header().Clone()
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || noStore(c.Writer.Header()) {
+			return
+		}
+		header := Header{}
+		for name, values := range c.Writer.Header() {
+			if !slices.Equal(before[name], values) {
+				header[name] = values
+			}
+		}
+		now := time.Now().UTC()
+		record = &Record{
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      status,
+			Header:      header,
+			Body:        append([]byte{}, recorder.body.Bytes()...),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(g.ttl),
+		}
+		// The response has been sent, so a failure can only be logged.
+		if err := g.store.Put(context.WithoutCancel(ctx), record); err != nil {
+			logger.Warning("idempotency key not stored", zap.Error(err))
+		}
+	}
+}
+
+func (g *Guard) unavailable(c *gin.Context, err error) {
+	logger.Warning("idempotency store unavailable", zap.Error(err))
+	httputil.NewError(c, http.StatusServiceUnavailable, ErrUnavailable)
+	c.Abort()
+}
+
+// replay writes the stored response.
+func replay(c *gin.Context, record *Record) {
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(HeaderReplayed, "true")
+	c.Writer.WriteHeader(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// noStore reports whether header forbids storing the response.
+func noStore(header http.Header) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestFingerprint hashes what makes two requests the same: the method,
+// the URL and the body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,70 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteSchema is idempotent, so it is applied on every start rather than
+// tracked with PRAGMA user_version, which the author tables already use.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key         TEXT      PRIMARY KEY,
+	fingerprint TEXT      NOT NULL,
+	status      INTEGER   NOT NULL,
+	header      TEXT      NOT NULL DEFAULT '{}',
+	body        BLOB      NOT NULL,
+	created_at  TIMESTAMP NOT NULL,
+	expires_at  TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at)`
+
+// sqliteTimeLayout has a fixed width so timestamps compare correctly as
+// text.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+type sqliteStore struct {
+	// A SQLite database belongs to one process, so keys are locked in
+	// memory; holding a connection would block the single one there is.
+	keyLocks
+	db *sqlx.DB
+}
+
+// NewSQLiteStore returns a Store backed by SQLite and creates the
+// idempotency_keys table if needed.
+func NewSQLiteStore(db *sqlx.DB) (Store, error) {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Get(ctx context.Context, key string) (*Record, error) {
+	var record Record
+	err := s.db.GetContext(ctx, &record, "SELECT "+recordColumns+" FROM idempotency_keys WHERE key = ? AND expires_at > ?",
+		key, time.Now().UTC().Format(sqliteTimeLayout))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *sqliteStore) Put(ctx context.Context, record *Record) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO idempotency_keys (`+recordColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET fingerprint = excluded.fingerprint, status = excluded.status,
+			header = excluded.header, body = excluded.body, created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		record.Key, record.Fingerprint, record.Status, record.Header, record.Body,
+		record.CreatedAt.UTC().Format(sqliteTimeLayout), record.ExpiresAt.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", time.Now().UTC().Format(sqliteTimeLayout))
+	return err
+}
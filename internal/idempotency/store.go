@@ -0,0 +1,167 @@
+// Package idempotency replays the stored response of a POST or PATCH
+// request when a client repeats it with the same Idempotency-Key header, so
+// that retries do not apply a change twice.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+// Record is the response stored for an idempotency key.
+type Record struct {
+	Key string `db:"key"`
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string    `db:"fingerprint"`
+	Status      int       `db:"status"`
+	Header      Header    `db:"header"`
+	Body        []byte    `db:"body"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// Header holds the response headers of a Record, stored as a JSON object.
+type Header http.Header
+
+// Scan implements sql.Scanner.
+func (h *Header) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("idempotency: cannot scan %T into Header", src)
+	}
+	return json.Unmarshal(b, h)
+}
+
+// Value implements driver.Valuer.
+func (h Header) Value() (driver.Value, error) {
+	if h == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(h)
+	return string(b), err
+}
+
+// Store keeps the responses of requests by idempotency key.
+type Store interface {
+	// Lock holds key until unlock is called, so that concurrent requests
+	// with the same key run one at a time. It does not wait: if another
+	// request holds key, it returns ErrKeyInFlight.
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+	// Get returns the unexpired record for key, or nil if there is none.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Put stores record, replacing an expired record with the same key, and
+	// drops expired records.
+	Put(ctx context.Context, record *Record) error
+}
+
+// recordColumns are selected for every record read.
+const recordColumns = `key, fingerprint, status, header, body, created_at, expires_at`
+
+// advisoryLockClass is the first key of the advisory locks taken on
+// idempotency keys, which keeps them apart from other advisory locks.
+const advisoryLockClass = 4817
+
+type postgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore returns a Store backed by the idempotency_keys table.
+// Keys are locked with session advisory locks, which serialise requests
+// across every instance of the service.
+func NewPostgresStore(db *sqlx.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// NewStore returns the Store implementation for the configured database
+// driver. db is unused, and may be nil, for the memory driver.
+func NewStore(driver string, db *sqlx.DB) (Store, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewPostgresStore(db), nil
+	case database.DriverSQLite:
+		return NewSQLiteStore(db)
+	case database.DriverMemory:
+		logger.Warning("idempotency keys are kept in memory", zap.String("driver", driver))
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func (s *postgresStore) Lock(ctx context.Context, key string) (func(), error) {
+	// The lock belongs to the session, so it takes a connection of its own
+	// for as long as it is held.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", advisoryLockClass, key).Scan(&locked); err != nil {
+		discard(conn)
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrKeyInFlight
+	}
+	return func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", advisoryLockClass, key)
+		if err != nil {
+			logger.Warning("idempotency key unlock failed", zap.Error(err))
+			discard(conn)
+			return
+		}
+		conn.Close()
+	}, nil
+}
+
+// discard closes the session of conn rather than returning it to the pool,
+// which releases any advisory lock it may hold.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
+
+func (s *postgresStore) Get(ctx context.Context, key string) (*Record, error) {
+	var record Record
+	err := s.db.GetContext(ctx, &record, "SELECT "+recordColumns+" FROM idempotency_keys WHERE key = $1 AND expires_at > now()", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *postgresStore) Put(ctx context.Context, record *Record) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO idempotency_keys (`+recordColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = EXCLUDED.status,
+			header = EXCLUDED.header, body = EXCLUDED.body, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`,
+		record.Key, record.Fingerprint, record.Status, record.Header, record.Body, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	return err
+}
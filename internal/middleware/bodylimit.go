@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// ErrBodyTooLarge is returned when the declared Content-Length exceeds the limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyLimit caps request bodies at the route's configured size, falling back
+// to the default. Requests that declare a larger Content-Length are rejected
+// with 413 up front; bodies that turn out larger fail to bind, and handlers
+// map that to 413 with httputil.BindStatus.
+func BodyLimit(config config.HTTPConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := config.MaxBodySize
+		for _, route := range config.BodyLimits {
+			if route.Path == c.FullPath() && (route.Method == "" || strings.EqualFold(route.Method, c.Request.Method)) {
+				limit = route.MaxBodySize
+				break
+			}
+		}
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			httputil.NewError(c, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
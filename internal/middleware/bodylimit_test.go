@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+func newBodyLimitRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(BodyLimit(config.HTTPConfig{
+		MaxBodySize: 16,
+		BodyLimits:  []config.BodyLimitRoute{{Method: "post", Path: "/large", MaxBodySize: 64}},
+	}))
+	bind := func(c *gin.Context) {
+		var body map[string]string
+		if err := c.ShouldBindJSON(&body); err != nil {
+			httputil.NewError(c, httputil.BindStatus(err), err)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.POST("/small", bind)
+	router.POST("/large", bind)
+	return router
+}
+
+func post(router *gin.Engine, path, body string, chunked bool) int {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if chunked {
+		req.ContentLength = -1
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestBodyLimit(t *testing.T) {
+	// Arrange
+	router := newBodyLimitRouter()
+	small := `{"a":"b"}`
+	large := `{"name":"` + strings.Repeat("x", 32) + `"}`
+
+	// Act & Assert
+	assert.Equal(t, http.StatusOK, post(router, "/small", small, false))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(router, "/small", large, false))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(router, "/small", large, true))
+	assert.Equal(t, http.StatusOK, post(router, "/large", large, false))
+	assert.Equal(t, http.StatusBadRequest, post(router, "/small", `{`, false))
+}
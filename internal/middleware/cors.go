@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// ErrOriginNotAllowed is returned for preflight requests from other origins.
+var ErrOriginNotAllowed = errors.New("origin not allowed")
+
+// CORS answers preflight requests and adds Access-Control-* headers for the
+// configured origins. config must have passed Validate: any origin is
+// answered with a literal "*", which browsers refuse for credentialed
+// requests. It must be installed on the engine with Use so that it
+// also runs for OPTIONS requests, which have no route of their own.
+func CORS(config config.CORSConfig) gin.HandlerFunc {
+	origins := make(map[string]bool, len(config.AllowOrigins))
+	anyOrigin := false
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		origins[strings.TrimRight(origin, "/")] = true
+	}
+	methods := strings.Join(config.AllowMethods, ", ")
+	headers := strings.Join(config.AllowHeaders, ", ")
+	expose := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if !config.Enabled || origin == "" {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		allowed := anyOrigin || origins[origin]
+		if !allowed {
+			if preflight {
+				httputil.NewError(c, http.StatusForbidden, ErrOriginNotAllowed)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if anyOrigin {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if expose != "" {
+				header.Set("Access-Control-Expose-Headers", expose)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", methods)
+		if headers != "" {
+			header.Set("Access-Control-Allow-Headers", headers)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func newCORSRouter(cors config.CORSConfig) *gin.Engine {
+	router := gin.New()
+	router.Use(CORS(cors))
+	router.GET("/authors/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+var testCORS = config.CORSConfig{
+	Enabled:       true,
+	AllowOrigins:  []string{"https://app.example.com"},
+	AllowMethods:  []string{"GET", "POST"},
+	AllowHeaders:  []string{"Authorization", "Content-Type"},
+	ExposeHeaders: []string{"RateLimit-Remaining"},
+	MaxAge:        10 * time.Minute,
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	// Arrange
+	router := newCORSRouter(testCORS)
+	req, _ := http.NewRequest(http.MethodOptions, "/authors/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORS_PreflightFromOtherOriginIsForbidden(t *testing.T) {
+	// Arrange
+	router := newCORSRouter(testCORS)
+	req, _ := http.NewRequest(http.MethodOptions, "/authors/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_SimpleRequest(t *testing.T) {
+	// Arrange
+	router := newCORSRouter(testCORS)
+	allowed, _ := http.NewRequest(http.MethodGet, "/authors/", nil)
+	allowed.Header.Set("Origin", "https://app.example.com")
+	other, _ := http.NewRequest(http.MethodGet, "/authors/", nil)
+	other.Header.Set("Origin", "https://evil.example.com")
+	allowedRec, otherRec := httptest.NewRecorder(), httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(allowedRec, allowed)
+	router.ServeHTTP(otherRec, other)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, allowedRec.Code)
+	assert.Equal(t, "https://app.example.com", allowedRec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "RateLimit-Remaining", allowedRec.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", allowedRec.Header().Get("Vary"))
+	assert.Equal(t, http.StatusOK, otherRec.Code)
+	assert.Empty(t, otherRec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_AnyOriginIsNeverEchoed(t *testing.T) {
+	// Arrange
+	withoutCredentials := newCORSRouter(config.CORSConfig{Enabled: true, AllowOrigins: []string{"*"}})
+	withCredentials := newCORSRouter(config.CORSConfig{Enabled: true, AllowOrigins: []string{"*"}, AllowCredentials: true})
+	req, _ := http.NewRequest(http.MethodGet, "/authors/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	withoutRec, withRec := httptest.NewRecorder(), httptest.NewRecorder()
+
+	// Act
+	withoutCredentials.ServeHTTP(withoutRec, req)
+	withCredentials.ServeHTTP(withRec, req)
+
+	// Assert
+	assert.Equal(t, "*", withoutRec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "*", withRec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSConfig_RejectsAnyOriginWithCredentials(t *testing.T) {
+	// Arrange
+	anyOrigin := config.CORSConfig{AllowOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}
+	listed := config.CORSConfig{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true}
+	withoutCredentials := config.CORSConfig{AllowOrigins: []string{"*"}}
+
+	// Act & Assert
+	assert.ErrorIs(t, anyOrigin.Validate(), config.ErrCORSAnyOriginWithCredentials)
+	assert.NoError(t, listed.Validate())
+	assert.NoError(t, withoutCredentials.Validate())
+}
+
+func TestCORS_DisabledAddsNoHeaders(t *testing.T) {
+	// Arrange
+	router := newCORSRouter(config.CORSConfig{AllowOrigins: []string{"*"}})
+	req, _ := http.NewRequest(http.MethodGet, "/authors/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	// Arrange
+	headers := config.SecurityHeadersConfig{
+		HSTSMaxAge:                   365 * 24 * time.Hour,
+		HSTSIncludeSubdomains:        true,
+		ContentSecurityPolicy:        "default-src 'none'",
+		SwaggerContentSecurityPolicy: "default-src 'self'",
+	}
+	router := gin.New()
+	router.Use(SecurityHeaders(headers))
+	router.GET("/authors/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/swagger/*any", SwaggerHeaders(headers), func(c *gin.Context) { c.Status(http.StatusOK) })
+	api, swagger := httptest.NewRecorder(), httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(api, httptest.NewRequest(http.MethodGet, "/authors/", nil))
+	router.ServeHTTP(swagger, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+
+	// Assert
+	assert.Equal(t, "nosniff", api.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", api.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "max-age=31536000; includeSubDomains", api.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'", api.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "default-src 'self'", swagger.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeaders_NoHSTSByDefault(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	router.Use(SecurityHeaders(config.SecurityHeadersConfig{}))
+	router.GET("/authors/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/authors/", nil))
+
+	// Assert
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+}
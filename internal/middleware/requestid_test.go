@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	// Arrange
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestIDFrom(c.Request.Context()))
+	})
+	serve := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	propagated := serve("req-42")
+	generated := serve("")
+	rejected := serve("bad id\twith spaces")
+
+	// Assert
+	assert.Equal(t, "req-42", propagated.Header().Get(RequestIDHeader))
+	assert.Equal(t, "req-42", propagated.Body.String())
+	assert.Len(t, generated.Body.String(), 32)
+	assert.Equal(t, generated.Body.String(), generated.Header().Get(RequestIDHeader))
+	assert.Len(t, rejected.Body.String(), 32)
+}
@@ -0,0 +1,156 @@
+// handler.go
+package oidc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/user"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+const (
+	// flowCookie carries the state, nonce and PKCE verifier of a sign-in
+	// from the login redirect to the callback.
+	flowCookie = "oidc_flow"
+	flowPath   = "/auth/oidc"
+	flowMaxAge = 10 * time.Minute
+)
+
+var (
+	ErrInvalidState = errors.New("sign-in expired or was started in another browser")
+	ErrProvider     = errors.New("identity provider rejected the sign-in")
+)
+
+func SetupRouter(router gin.IRouter, rp *RelyingParty, service user.UserService, secureCookie bool) {
+
+	handler := NewOIDCHandler(rp, service)
+	handler.secureCookie = secureCookie
+
+	oidcRoutes := router.Group(flowPath)
+	{
+		oidcRoutes.GET("/login", handler.Login)
+		oidcRoutes.GET("/callback", handler.Callback)
+	}
+}
+
+type OIDCHandler struct {
+	rp      *RelyingParty
+	service user.UserService
+	// secureCookie marks the flow and session cookies Secure.
+	secureCookie bool
+}
+
+func NewOIDCHandler(rp *RelyingParty, service user.UserService) *OIDCHandler {
+	return &OIDCHandler{rp: rp, service: service, secureCookie: true}
+}
+
+// Login redirects the browser to the identity provider.
+// @Summary Sign in with OpenID Connect
+// @Description Redirect to the identity provider using the authorization code flow with PKCE.
+// @Tags users
+// @Success 302
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 502 {object} httputil.HTTPError "Identity provider unavailable"
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	var flow [3]string
+	for i := range flow {
+		value, err := randomString()
+		if err != nil {
+			httputil.NewError(c, http.StatusInternalServerError, err)
+			return
+		}
+		flow[i] = value
+	}
+	state, nonce, verifier := flow[0], flow[1], flow[2]
+
+	redirect, err := h.rp.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		logger.Error("oidc: cannot start sign-in", zap.Error(err))
+		httputil.NewError(c, http.StatusBadGateway, errors.New("identity provider unavailable"))
+		return
+	}
+
+	h.setFlowCookie(c, strings.Join(flow[:], "."), flowMaxAge)
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// Callback completes the sign-in started by Login.
+// @Summary OpenID Connect callback
+// @Description Exchange the authorization code, validate the ID token, provision the user and start a session. Redirects to the configured post-login URL with the session cookie set.
+// @Tags users
+// @Param code query string false "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 302
+// @Failure 400 {object} httputil.HTTPError "Invalid or expired sign-in"
+// @Failure 401 {object} httputil.HTTPError "Sign-in rejected"
+// @Failure 409 {object} httputil.HTTPError "Email belongs to an account that is not linked"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 502 {object} httputil.HTTPError "Identity provider unavailable"
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// The flow cookie is single use.
+	raw, _ := c.Cookie(flowCookie)
+	h.setFlowCookie(c, "", -1)
+
+	flow := strings.Split(raw, ".")
+	if len(flow) != 3 || subtle.ConstantTimeCompare([]byte(flow[0]), []byte(c.Query("state"))) != 1 {
+		httputil.NewError(c, http.StatusBadRequest, ErrInvalidState)
+		return
+	}
+	nonce, verifier := flow[1], flow[2]
+
+	if code := c.Query("error"); code != "" {
+		logger.Warning("oidc: provider returned an error", zap.String("error", code), zap.String("description", c.Query("error_description")))
+		httputil.NewError(c, http.StatusUnauthorized, ErrProvider)
+		return
+	}
+
+	rawIDToken, err := h.rp.Exchange(c.Request.Context(), c.Query("code"), verifier)
+	if err != nil {
+		logger.Warning("oidc: code exchange failed", zap.Error(err))
+		httputil.NewError(c, http.StatusBadGateway, ErrProvider)
+		return
+	}
+	claims, err := h.rp.VerifyIDToken(c.Request.Context(), rawIDToken, nonce)
+	if err != nil {
+		logger.Warning("oidc: rejected ID token", zap.Error(err))
+		httputil.NewError(c, http.StatusUnauthorized, ErrProvider)
+		return
+	}
+
+	identity := &user.Identity{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}
+	_, session, token, err := h.service.LoginWithIdentity(c.Request.Context(), identity, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		httputil.NewError(c, user.StatusFromError(err), err)
+		return
+	}
+
+	user.SetSessionCookie(c, token, time.Until(session.ExpiresAt), h.secureCookie)
+	c.Redirect(http.StatusFound, h.rp.PostLoginURL())
+}
+
+// setFlowCookie sets the flow cookie, or deletes it when maxAge is negative.
+// SameSite=Lax lets the cookie through the provider's top-level redirect.
+func (h *OIDCHandler) setFlowCookie(c *gin.Context, value string, maxAge time.Duration) {
+	seconds := int(maxAge.Seconds())
+	if maxAge < 0 {
+		seconds = -1
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(flowCookie, value, seconds, flowPath, "", h.secureCookie, true)
+}
@@ -0,0 +1,167 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/oidc/mockidp"
+	"github.com/nilemarezz/go-init-template/internal/user"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+	"github.com/nilemarezz/go-init-template/pkg/mail"
+)
+
+const testRedirectURL = "https://app.example.com/auth/oidc/callback"
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+type discardSender struct{}
+
+func (discardSender) Send(mail.Message) error { return nil }
+
+// newTestApp serves a mock IdP and returns a router with the OIDC routes.
+func newTestApp(t *testing.T) (*gin.Engine, user.UserService) {
+	t.Helper()
+	var idp *mockidp.Server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	issuer := server.URL + "/mock-idp"
+	idp, err := mockidp.New(issuer, "app", "secret", mockidp.User{Subject: "jane", Email: "jane@example.com", Name: "Jane"})
+	require.NoError(t, err)
+
+	rp, err := NewRelyingParty(config.OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     "app",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+		PostLoginURL: "/welcome",
+		ClockSkew:    30 * time.Second,
+	})
+	require.NoError(t, err)
+
+	service := user.NewUserService(user.NewMemoryUserRepository(), discardSender{}, audit.NewLog(audit.NewMemoryStore()), user.Options{
+		DefaultRoles: []string{"editor"},
+		SessionTTL:   time.Hour,
+	})
+	router := gin.New()
+	SetupRouter(router, rp, service, true)
+	return router, service
+}
+
+func serve(router *gin.Engine, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func cookie(t *testing.T, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %s cookie set", name)
+	return nil
+}
+
+// authorize follows the login redirect to the IdP and returns the callback
+// URL it redirects back to.
+func authorize(t *testing.T, location string) *url.URL {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(location)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback
+}
+
+func TestOIDCFlow_SignsInAndProvisionsUser(t *testing.T) {
+	// Arrange
+	router, service := newTestApp(t)
+
+	// Act: start the sign-in, approve it at the IdP and return to the callback.
+	login := serve(router, "/auth/oidc/login")
+	require.Equal(t, http.StatusFound, login.Code)
+	flow := cookie(t, login, flowCookie)
+	location, err := url.Parse(login.Header().Get("Location"))
+	require.NoError(t, err)
+	callback := authorize(t, location.String())
+	w := serve(router, callback.RequestURI(), flow)
+
+	// Assert
+	assert.Equal(t, "S256", location.Query().Get("code_challenge_method"))
+	assert.Equal(t, testRedirectURL, location.Query().Get("redirect_uri"))
+	assert.True(t, flow.HttpOnly)
+	assert.Equal(t, testRedirectURL, callback.Scheme+"://"+callback.Host+callback.Path)
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	assert.Equal(t, "/welcome", w.Header().Get("Location"))
+	session := cookie(t, w, user.SessionCookie)
+	signedIn, _, err := service.AuthenticateSession(session.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", signedIn.Email)
+	assert.NotNil(t, signedIn.EmailVerifiedAt)
+}
+
+func TestOIDCCallback_RejectsStateMismatchAndReplayedCode(t *testing.T) {
+	// Arrange
+	router, _ := newTestApp(t)
+	login := serve(router, "/auth/oidc/login")
+	flow := cookie(t, login, flowCookie)
+	callback := authorize(t, login.Header().Get("Location"))
+
+	// Act
+	forged := callback.Query()
+	forged.Set("state", "forged")
+	mismatch := serve(router, callback.Path+"?"+forged.Encode(), flow)
+	missing := serve(router, callback.RequestURI())
+	first := serve(router, callback.RequestURI(), flow)
+	replay := serve(router, callback.RequestURI(), flow)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, mismatch.Code)
+	assert.Equal(t, http.StatusBadRequest, missing.Code)
+	assert.Equal(t, http.StatusFound, first.Code)
+	assert.Equal(t, http.StatusBadGateway, replay.Code)
+}
+
+func TestOIDCCallback_ProviderErrorIs401(t *testing.T) {
+	// Arrange
+	router, _ := newTestApp(t)
+	login := serve(router, "/auth/oidc/login")
+	flow := cookie(t, login, flowCookie)
+	location, _ := url.Parse(login.Header().Get("Location"))
+	state := location.Query().Get("state")
+
+	// Act
+	w := serve(router, "/auth/oidc/callback?error=access_denied&state="+state, flow)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
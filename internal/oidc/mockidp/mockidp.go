@@ -0,0 +1,286 @@
+// Package mockidp is a minimal OpenID provider for tests and development. It
+// signs in a single configured user without asking for a password, so the
+// OIDC flow can be exercised without network access. Never enable it in
+// production.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+const (
+	keyID    = "mock"
+	codeTTL  = time.Minute
+	tokenTTL = 5 * time.Minute
+)
+
+// User is the account the provider signs in.
+type User struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+// grant is an issued authorization code.
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	expiresAt   time.Time
+}
+
+// Server is an OpenID provider serving discovery, authorization, token and
+// JWKS endpoints below the issuer URL's path.
+type Server struct {
+	issuer       string
+	path         string
+	clientID     string
+	clientSecret string
+	user         User
+	key          *rsa.PrivateKey
+	mux          *http.ServeMux
+	now          func() time.Time
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// New creates a Server for issuer that accepts a single client.
+func New(issuer, clientID, clientSecret string, user User) (*Server, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, err
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		issuer:       issuer,
+		path:         strings.TrimSuffix(u.Path, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		user:         user,
+		key:          key,
+		mux:          http.NewServeMux(),
+		now:          time.Now,
+		grants:       make(map[string]grant),
+	}
+	s.mux.HandleFunc(s.path+"/.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc(s.path+"/authorize", s.authorize)
+	s.mux.HandleFunc(s.path+"/token", s.token)
+	s.mux.HandleFunc(s.path+"/jwks", s.jwks)
+	return s, nil
+}
+
+// NewFromConfig creates a Server for the configured issuer and client that
+// signs in auth.oidc.mockuser.
+func NewFromConfig(config *config.Config) (*Server, error) {
+	oidc := config.Auth.OIDC
+	if oidc.MockUser.Subject == "" || oidc.MockUser.Email == "" {
+		return nil, errors.New("mockidp: auth.oidc.mockuser needs a subject and an email")
+	}
+	return New(oidc.Issuer, oidc.ClientID, oidc.ClientSecret, User(oidc.MockUser))
+}
+
+// Path returns the URL path the endpoints are served under.
+func (s *Server) Path() string {
+	return s.path
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+// authorize approves every request from the known client and redirects back
+// with an authorization code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	// Without a trustworthy client and redirect URI, errors cannot be
+	// redirected back (RFC 6749, section 4.1.2.1).
+	if query.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	callback, err := url.Parse(redirectURI)
+	if err != nil || !callback.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{"state": {query.Get("state")}}
+	switch {
+	case query.Get("response_type") != "code":
+		params.Set("error", "unsupported_response_type")
+	case !strings.Contains(" "+query.Get("scope")+" ", " openid "):
+		params.Set("error", "invalid_scope")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "PKCE with S256 is required")
+	default:
+		code, err := randomString()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.grants[code] = grant{
+			redirectURI: redirectURI,
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			expiresAt:   s.now().Add(codeTTL),
+		}
+		s.mu.Unlock()
+		params.Set("code", code)
+	}
+
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// token redeems an authorization code for a signed ID token.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single use, even when the redemption fails.
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, ok := s.grants[code]
+	delete(s.grants, code)
+	s.mu.Unlock()
+	if !ok || !s.now().Before(g.expiresAt) ||
+		g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		g.challenge != challenge(r.PostForm.Get("code_verifier")) {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	idToken, err := s.signIDToken(g.nonce)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	accessToken, err := randomString()
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	public := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) signIDToken(nonce string) (string, error) {
+	now := s.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.issuer,
+		"sub":            s.user.Subject,
+		"aud":            s.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(tokenTTL).Unix(),
+		"nonce":          nonce,
+		"email":          s.user.Email,
+		"email_verified": true,
+		"name":           s.user.Name,
+	})
+	token.Header["kid"] = keyID
+	return token.SignedString(s.key)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// keyRefreshInterval is how often the provider's signing keys are reloaded.
+const keyRefreshInterval = time.Hour
+
+// ErrInvalidIDToken is returned when an ID token fails validation.
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// IDTokenClaims are the ID token claims used to sign a user in.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce string `json:"nonce,omitempty"`
+	// AuthorizedParty is the client the token was issued to.
+	AuthorizedParty string `json:"azp,omitempty"`
+	Email           string `json:"email,omitempty"`
+	EmailVerified   bool   `json:"email_verified,omitempty"`
+	Name            string `json:"name,omitempty"`
+}
+
+// providerMetadata is the part of the discovery document the relying party
+// needs (OpenID Connect Discovery 1.0).
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// provider is a discovered OpenID provider.
+type provider struct {
+	metadata providerMetadata
+	keys     *auth.KeySet
+}
+
+// RelyingParty signs users in with an OpenID provider using the
+// authorization code flow with PKCE.
+type RelyingParty struct {
+	config config.OIDCConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	mu       sync.Mutex
+	provider *provider
+}
+
+// NewRelyingParty creates a RelyingParty. The provider is discovered on first
+// use, so the service can start while the provider is unreachable.
+func NewRelyingParty(oidcConfig config.OIDCConfig) (*RelyingParty, error) {
+	if oidcConfig.Issuer == "" || oidcConfig.ClientID == "" || oidcConfig.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer, clientid and redirecturl are required")
+	}
+	return &RelyingParty{
+		config: oidcConfig,
+		client: &http.Client{Timeout: 10 * time.Second},
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{"RS256", "ES256"}),
+			jwt.WithIssuer(oidcConfig.Issuer),
+			jwt.WithAudience(oidcConfig.ClientID),
+			jwt.WithLeeway(oidcConfig.ClockSkew),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+	}, nil
+}
+
+// AuthCodeURL returns the provider URL that starts a sign-in. The PKCE
+// challenge is derived from verifier, which is later passed to Exchange.
+func (rp *RelyingParty) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	p, err := rp.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.config.ClientID},
+		"redirect_uri":          {rp.config.RedirectURL},
+		"scope":                 {strings.Join(rp.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token.
+func (rp *RelyingParty) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	p, err := rp.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(rp.config.ClientID), url.QueryEscape(rp.config.ClientSecret))
+
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: cannot parse token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token request failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc: token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken validates the signature, issuer, audience, lifetime and nonce
+// of an ID token and returns its claims.
+func (rp *RelyingParty) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	p, err := rp.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims IDTokenClaims
+	if _, err := rp.parser.ParseWithClaims(raw, &claims, p.keys.Keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != rp.config.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	return &claims, nil
+}
+
+// PostLoginURL returns where the browser is sent after signing in.
+func (rp *RelyingParty) PostLoginURL() string {
+	return rp.config.PostLoginURL
+}
+
+func (rp *RelyingParty) scopes() []string {
+	for _, scope := range rp.config.Scopes {
+		if scope == "openid" {
+			return rp.config.Scopes
+		}
+	}
+	return append([]string{"openid"}, rp.config.Scopes...)
+}
+
+// discover reads the provider's discovery document and signing keys once.
+// Failures are not cached, so a later request retries.
+func (rp *RelyingParty) discover(ctx context.Context) (*provider, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.provider != nil {
+		return rp.provider, nil
+	}
+
+	wellKnown := strings.TrimSuffix(rp.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery failed: unexpected status %s", resp.Status)
+	}
+
+	var metadata providerMetadata
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("oidc: cannot parse discovery document: %v", err)
+	}
+	// The issuer must match exactly, otherwise another provider's tokens
+	// could be accepted.
+	if metadata.Issuer != rp.config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", metadata.Issuer, rp.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing endpoints")
+	}
+
+	keys, err := auth.NewKeySet(metadata.JWKSURI, keyRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	rp.provider = &provider{metadata: metadata, keys: keys}
+	return rp.provider, nil
+}
+
+// randomString returns a URL-safe string with 256 bits of entropy, used for
+// state, nonce and the PKCE code verifier.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge returns the S256 PKCE code challenge for verifier (RFC 7636).
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
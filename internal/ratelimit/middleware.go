@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+var rejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ratelimit_rejected_total",
+	Help: "Requests rejected by the rate limiter, by route and client kind.",
+}, []string{"route", "client"})
+
+// ErrRateLimited is returned when a client has no tokens left.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Route is a limit for one route pattern.
+type Route struct {
+	// Method is the HTTP method to match; empty matches every method.
+	Method string
+	// Path is the route pattern as registered, e.g. /authors/:id.
+	Path  string
+	Limit Limit
+}
+
+// Limiter builds middleware that enforces token bucket limits per client.
+type Limiter struct {
+	store        Store
+	defaultLimit Limit
+	routes       []Route
+}
+
+// NewLimiter creates a Limiter. Routes without a matching rule share the
+// default bucket of each client; a zero default rate leaves them unlimited.
+func NewLimiter(store Store, defaultLimit Limit, routes ...Route) *Limiter {
+	return &Limiter{store: store, defaultLimit: defaultLimit, routes: routes}
+}
+
+// NewLimiterFromConfig creates the configured Limiter, or nil if rate
+// limiting is disabled.
+func NewLimiterFromConfig(config *config.Config) (*Limiter, error) {
+	if !config.RateLimit.Enabled {
+		return nil, nil
+	}
+	store, err := NewStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]Route, 0, len(config.RateLimit.Routes))
+	for _, route := range config.RateLimit.Routes {
+		routes = append(routes, Route{
+			Method: strings.ToUpper(route.Method),
+			Path:   route.Path,
+			Limit:  Limit{Rate: route.Rate, Burst: route.Burst},
+		})
+	}
+	defaultLimit := Limit{Rate: config.RateLimit.Default.Rate, Burst: config.RateLimit.Default.Burst}
+	return NewLimiter(store, defaultLimit, routes...), nil
+}
+
+// Handler limits requests by client. It runs ahead of authentication: every
+// request first spends a token of its IP address, before any credentials it
+// presents are verified, so that a client out of tokens can neither make the
+// server check passwords nor learn whether they are correct. Callers whose
+// credentials verify then also spend a token of their API key or user, the
+// bucket the rate limit headers describe. A nil Limiter lets every request
+// through.
+func (l *Limiter) Handler(authn *auth.Authentication) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		bucket, limit := l.match(c.Request.Method, c.FullPath())
+		if limit.Rate <= 0 || limit.Burst <= 0 {
+			c.Next()
+			return
+		}
+		if !l.take(c, bucket, "ip", "ip:"+c.ClientIP(), limit) {
+			return
+		}
+		if principal, _ := authn.Identify(c); principal != nil {
+			kind, client := auth.ClientKey(c, principal)
+			if !l.take(c, bucket, kind, client, limit) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// take spends a token of client in bucket and sets the rate limit headers. If
+// none is left, it answers 429 and returns false.
+func (l *Limiter) take(c *gin.Context, bucket, kind, client string, limit Limit) bool {
+	result, err := l.store.Take(bucket+"|"+client, limit)
+	if err != nil {
+		// Fail open: an unavailable store should not take the API down.
+		logger.Warning("rate limit store unavailable", zap.Error(err))
+		return true
+	}
+
+	header := c.Writer.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+	if !result.Allowed {
+		rejectedRequests.WithLabelValues(bucket, kind).Inc()
+		header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+		httputil.NewError(c, http.StatusTooManyRequests, ErrRateLimited)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// match returns the bucket name and limit for a route.
+func (l *Limiter) match(method, path string) (string, Limit) {
+	for _, route := range l.routes {
+		if route.Path == path && (route.Method == "" || route.Method == method) {
+			return route.Method + " " + route.Path, route.Limit
+		}
+	}
+	return "default", l.defaultLimit
+}
+
+// ceilSeconds formats d as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
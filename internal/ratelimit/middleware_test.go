@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// principalHeader authenticates "X-Test-Principal: apikey|invalid|<subject>",
+// taking the API key from X-Test-Key and the method of a subject from
+// X-Test-Method, "jwt" by default.
+type principalHeader struct{}
+
+func (principalHeader) Scheme() string { return "" }
+
+func (principalHeader) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	switch value := c.GetHeader("X-Test-Principal"); value {
+	case "":
+		return nil, auth.ErrNoCredentials
+	case "invalid":
+		return nil, errors.New("invalid credentials")
+	case "apikey":
+		return &auth.Principal{Subject: "owner", Method: "apikey", CredentialID: c.GetHeader("X-Test-Key")}, nil
+	default:
+		method := c.GetHeader("X-Test-Method")
+		if method == "" {
+			method = "jwt"
+		}
+		return &auth.Principal{Subject: value, Method: method}, nil
+	}
+}
+
+func newTestRouter(limiter *Limiter) *gin.Engine {
+	router := gin.New()
+	authn := auth.NewAuthentication(principalHeader{})
+	api := router.Group("", limiter.Handler(authn), authn.Optional())
+	api.GET("/authors/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/authors/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func get(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimiter_RejectsWith429AndHeaders(t *testing.T) {
+	// Arrange
+	router := newTestRouter(NewLimiter(NewMemoryStore(), Limit{Rate: 0.5, Burst: 1}))
+
+	// Act
+	allowed := get(router, "/authors/", nil)
+	rejected := get(router, "/authors/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, allowed.Code)
+	assert.Equal(t, "1", allowed.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", allowed.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", allowed.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, allowed.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "2", rejected.Header().Get("Retry-After"))
+	assert.Contains(t, rejected.Body.String(), ErrRateLimited.Error())
+}
+
+func TestLimiter_KeysByAPIKeyUserAndIP(t *testing.T) {
+	// Arrange: every caller comes from its own address, so that only their
+	// API key or user buckets are shared.
+	router := newTestRouter(NewLimiter(NewMemoryStore(), Limit{Rate: 0.001, Burst: 1}))
+	from := func(ip string, headers map[string]string) map[string]string {
+		withIP := map[string]string{"X-Forwarded-For": ip}
+		for key, value := range headers {
+			withIP[key] = value
+		}
+		return withIP
+	}
+	keyA := map[string]string{"X-Test-Principal": "apikey", "X-Test-Key": "aaaa"}
+	keyB := map[string]string{"X-Test-Principal": "apikey", "X-Test-Key": "bbbb"}
+	user := map[string]string{"X-Test-Principal": "alice"}
+	basicUser := map[string]string{"X-Test-Principal": "alice", "X-Test-Method": "basic"}
+
+	// Act
+	codes := []int{
+		get(router, "/authors/", from("198.51.100.1", keyA)).Code,
+		get(router, "/authors/", from("198.51.100.2", keyB)).Code,
+		get(router, "/authors/", from("198.51.100.3", user)).Code,
+		get(router, "/authors/", from("198.51.100.4", basicUser)).Code,
+		get(router, "/authors/", from("198.51.100.5", nil)).Code,
+		get(router, "/authors/", from("198.51.100.6", keyA)).Code,
+		get(router, "/authors/", from("198.51.100.7", user)).Code,
+		get(router, "/authors/", from("198.51.100.8", basicUser)).Code,
+		get(router, "/authors/", from("198.51.100.5", nil)).Code,
+	}
+
+	// Assert: the same subject of another method has its own bucket.
+	assert.Equal(t, []int{200, 200, 200, 200, 200, 429, 429, 429, 429}, codes)
+}
+
+// countingAuthenticator counts the credentials it verifies.
+type countingAuthenticator struct {
+	principalHeader
+	calls int
+}
+
+func (a *countingAuthenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	a.calls++
+	return a.principalHeader.Authenticate(c)
+}
+
+func TestLimiter_IPTokensAreSpentBeforeCredentialsAreVerified(t *testing.T) {
+	// Arrange
+	authenticator := &countingAuthenticator{}
+	authn := auth.NewAuthentication(authenticator)
+	router := gin.New()
+	api := router.Group("", NewLimiter(NewMemoryStore(), Limit{Rate: 0.001, Burst: 2}).Handler(authn), authn.Optional())
+	api.GET("/authors/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	invalid := map[string]string{"X-Test-Principal": "invalid"}
+
+	// Act
+	codes := []int{
+		get(router, "/authors/", invalid).Code,
+		get(router, "/authors/", invalid).Code,
+		get(router, "/authors/", invalid).Code,
+		get(router, "/authors/", nil).Code,
+		get(router, "/authors/", map[string]string{"X-Test-Principal": "alice"}).Code,
+	}
+
+	// Assert: once the address is out of tokens, neither wrong nor right
+	// credentials are checked.
+	assert.Equal(t, []int{401, 401, 429, 429, 429}, codes)
+	assert.Equal(t, 2, authenticator.calls)
+}
+
+func TestLimiter_RouteRulesUseTheirOwnBucket(t *testing.T) {
+	// Arrange
+	limiter := NewLimiter(NewMemoryStore(), Limit{Rate: 0.001, Burst: 1},
+		Route{Method: http.MethodGet, Path: "/authors/:id", Limit: Limit{Rate: 0.001, Burst: 2}})
+	router := newTestRouter(limiter)
+
+	// Act
+	list := get(router, "/authors/", nil)
+	first := get(router, "/authors/1", nil)
+	second := get(router, "/authors/2", nil)
+	third := get(router, "/authors/3", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, http.StatusTooManyRequests, third.Code)
+}
+
+func TestLimiter_ZeroRateIsUnlimited(t *testing.T) {
+	// Arrange
+	router := newTestRouter(NewLimiter(NewMemoryStore(), Limit{}))
+
+	// Act
+	first := get(router, "/authors/", nil)
+	second := get(router, "/authors/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, first.Header().Get("RateLimit-Limit"))
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, Limit) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+func TestLimiter_FailsOpenWhenStoreErrors(t *testing.T) {
+	// Arrange
+	router := newTestRouter(NewLimiter(failingStore{}, Limit{Rate: 1, Burst: 1}))
+
+	// Act
+	rec := get(router, "/authors/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLimiter_NilLetsRequestsThrough(t *testing.T) {
+	// Arrange
+	var limiter *Limiter
+	router := newTestRouter(limiter)
+
+	// Act
+	rec := get(router, "/authors/", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
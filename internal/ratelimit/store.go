@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Supported values for the ratelimit.store config key.
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Limit is a token bucket: it refills at Rate tokens per second and holds at
+// most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next token is available, zero if allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+// NewStore returns the Store implementation for the configured backend.
+func NewStore(config *config.Config) (Store, error) {
+	switch config.RateLimit.Store {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		return NewRedisStore(config.RateLimit.RedisAddr)
+	}
+	return nil, fmt.Errorf("unsupported rate limit store %q", config.RateLimit.Store)
+}
+
+// newResult builds a Result from the tokens left after a take attempt.
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TakesUntilEmptyThenRefills(t *testing.T) {
+	// Arrange
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	// Act
+	first, _ := store.Take("k", limit)
+	second, _ := store.Take("k", limit)
+	rejected, _ := store.Take("k", limit)
+	now = now.Add(time.Second)
+	refilled, _ := store.Take("k", limit)
+
+	// Assert
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, time.Second, rejected.RetryAfter)
+	assert.Equal(t, 2*time.Second, rejected.ResetAfter)
+	assert.True(t, refilled.Allowed)
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	// Act
+	a, _ := store.Take("a", limit)
+	b, _ := store.Take("b", limit)
+
+	// Assert
+	assert.True(t, a.Allowed)
+	assert.True(t, b.Allowed)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	// Arrange
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	_, _ = store.Take("idle", Limit{Rate: 1, Burst: 1})
+
+	// Act
+	now = now.Add(2 * sweepInterval)
+	_, _ = store.Take("other", Limit{Rate: 1, Burst: 1})
+
+	// Assert
+	assert.NotContains(t, store.buckets, "idle")
+}
+
+func TestRedisStore_TakesUntilEmpty(t *testing.T) {
+	// Arrange
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore(server.Addr())
+	require.NoError(t, err)
+	defer store.Close()
+	limit := Limit{Rate: 0.001, Burst: 2}
+
+	// Act
+	first, firstErr := store.Take("k", limit)
+	second, _ := store.Take("k", limit)
+	rejected, _ := store.Take("k", limit)
+	other, _ := store.Take("other", limit)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, 0, rejected.Remaining)
+	assert.Greater(t, rejected.RetryAfter, time.Duration(0))
+	assert.True(t, other.Allowed)
+}
@@ -0,0 +1,75 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+)
+
+// SessionCookie is the name of the cookie holding the session token.
+const SessionCookie = "session"
+
+// userContextKey and sessionContextKey hold the signed-in *User and *Session.
+const (
+	userContextKey    = "user.user"
+	sessionContextKey = "user.session"
+)
+
+// Authenticator authenticates requests carrying a session token in the
+// session cookie or an "Authorization: Session <token>" header.
+type Authenticator struct {
+	service UserService
+}
+
+// NewAuthenticator creates an auth.Authenticator backed by service.
+func NewAuthenticator(service UserService) *Authenticator {
+	return &Authenticator{service: service}
+}
+
+func (a *Authenticator) Scheme() string {
+	return "Session"
+}
+
+func (a *Authenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	token, _ := c.Cookie(SessionCookie)
+	fromCookie := true
+	if scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Session") {
+		token, fromCookie = value, false
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	user, session, err := a.service.AuthenticateSession(token)
+	if errors.Is(err, ErrInvalidSession) && fromCookie {
+		// Browsers keep sending expired cookies; treat them as anonymous
+		// rather than failing every public request.
+		return nil, auth.ErrNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Set(userContextKey, user)
+	c.Set(sessionContextKey, session)
+	return &auth.Principal{
+		Subject:      user.Email,
+		Method:       "session",
+		CredentialID: strconv.Itoa(session.ID),
+		Roles:        user.Roles,
+	}, nil
+}
+
+// CurrentUser returns the user signed in with a session, if any.
+func CurrentUser(c *gin.Context) (*User, *Session, bool) {
+	user, ok := c.Get(userContextKey)
+	if !ok {
+		return nil, nil, false
+	}
+	session, _ := c.Get(sessionContextKey)
+	return user.(*User), session.(*Session), true
+}
@@ -0,0 +1,342 @@
+// handler.go
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	httputil "github.com/nilemarezz/go-init-template/internal/util"
+)
+
+func SetupRouter(router gin.IRouter, service UserService, authn *auth.Authentication, secureCookie bool) {
+
+	handler := NewUserHandler(service)
+	handler.secureCookie = secureCookie
+
+	userRoutes := router.Group("/users", authn.Optional())
+	{
+		userRoutes.POST("/register", handler.Register)
+		userRoutes.POST("/verify-email", handler.VerifyEmail)
+		userRoutes.POST("/verify-email/resend", handler.ResendVerification)
+		userRoutes.POST("/login", handler.Login)
+		userRoutes.POST("/logout", requireSession, handler.Logout)
+		userRoutes.POST("/password-reset", handler.RequestPasswordReset)
+		userRoutes.POST("/password-reset/confirm", handler.ResetPassword)
+	}
+
+	meRoutes := userRoutes.Group("/me", requireSession)
+	{
+		meRoutes.GET("", handler.GetMe)
+		meRoutes.GET("/sessions", handler.ListSessions)
+		meRoutes.DELETE("/sessions/:id", handler.RevokeSession)
+	}
+}
+
+// requireSession rejects requests that are not signed in with a user session.
+// Other credentials, such as API keys, do not identify a user account.
+func requireSession(c *gin.Context) {
+	if _, _, ok := CurrentUser(c); !ok {
+		auth.Unauthorized(c, auth.ErrNoCredentials)
+		return
+	}
+	c.Next()
+}
+
+type UserHandler struct {
+	service UserService
+	// secureCookie marks the session cookie Secure.
+	secureCookie bool
+}
+
+func NewUserHandler(service UserService) *UserHandler {
+	return &UserHandler{service: service, secureCookie: true}
+}
+
+// Register creates a user account.
+// @Summary Register a user
+// @Description Create an account and email a verification link.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body RegisterRequest true "Account to create"
+// @Success 201 {object} User
+// @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 409 {object} httputil.HTTPError "Email already registered"
+// @Failure 413 {object} httputil.HTTPError "Request body too large"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/register [post]
+func (h *UserHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	user, err := h.service.Register(&req)
+	if err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// VerifyEmail confirms an email address.
+// @Summary Verify an email address
+// @Description Redeem the token from a verification email.
+// @Tags users
+// @Accept json
+// @Param token body TokenRequest true "Verification token"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid or expired token"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.VerifyEmail(req.Token); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification emails a new verification link.
+// @Summary Resend the verification email
+// @Description Always accepted, so that callers cannot tell which addresses have accounts.
+// @Tags users
+// @Accept json
+// @Param email body EmailRequest true "Account email"
+// @Success 202
+// @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/verify-email/resend [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.ResendVerification(req.Email); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// Login signs a user in.
+// @Summary Log in
+// @Description Start a session. The token is set as an HttpOnly session cookie and also returned for clients that send it as "Authorization: Session <token>".
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Email and password"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 401 {object} httputil.HTTPError "Invalid email or password"
+// @Failure 403 {object} httputil.HTTPError "Email address is not verified"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/login [post]
+func (h *UserHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	user, session, token, err := h.service.Login(c.Request.Context(), &req, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	SetSessionCookie(c, token, time.Until(session.ExpiresAt), h.secureCookie)
+	c.JSON(http.StatusOK, LoginResponse{User: user, Token: token, ExpiresAt: session.ExpiresAt})
+}
+
+// Logout ends the current session.
+// @Summary Log out
+// @Description Revoke the current session and clear the session cookie.
+// @Tags users
+// @Success 204
+// @Failure 401 {object} httputil.HTTPError "Not signed in"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security SessionAuth
+// @Router /users/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	user, session, _ := CurrentUser(c)
+	if err := h.service.RevokeSession(c.Request.Context(), user.ID, session.ID); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	SetSessionCookie(c, "", -1, h.secureCookie)
+	c.Status(http.StatusNoContent)
+}
+
+// GetMe returns the signed-in user.
+// @Summary Get the current user
+// @Tags users
+// @Produce json
+// @Success 200 {object} User
+// @Failure 401 {object} httputil.HTTPError "Not signed in"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Security SessionAuth
+// @Router /users/me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, _, _ := CurrentUser(c)
+	c.JSON(http.StatusOK, user)
+}
+
+// ListSessions lists the signed-in user's active sessions.
+// @Summary List my sessions
+// @Tags users
+// @Produce json
+// @Success 200 {array} Session
+// @Failure 401 {object} httputil.HTTPError "Not signed in"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security SessionAuth
+// @Router /users/me/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	user, current, _ := CurrentUser(c)
+	sessions, err := h.service.ListSessions(user.ID)
+	if err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == current.ID
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession ends one of the signed-in user's sessions.
+// @Summary Revoke one of my sessions
+// @Tags users
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid ID format"
+// @Failure 401 {object} httputil.HTTPError "Not signed in"
+// @Failure 404 {object} httputil.HTTPError "Session not found"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Security SessionAuth
+// @Router /users/me/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httputil.NewError(c, http.StatusBadRequest, errors.New("invalid id"))
+		return
+	}
+
+	user, current, _ := CurrentUser(c)
+	if err := h.service.RevokeSession(c.Request.Context(), user.ID, id); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+	if id == current.ID {
+		SetSessionCookie(c, "", -1, h.secureCookie)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset emails a password reset link.
+// @Summary Request a password reset
+// @Description Always accepted, so that callers cannot tell which addresses have accounts.
+// @Tags users
+// @Accept json
+// @Param email body EmailRequest true "Account email"
+// @Success 202
+// @Failure 400 {object} httputil.HTTPError "Bad request"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/password-reset [post]
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(req.Email); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password.
+// @Summary Reset a password
+// @Description Set a new password with the token from a reset email. All sessions of the user are revoked.
+// @Tags users
+// @Accept json
+// @Param reset body PasswordResetConfirmRequest true "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} httputil.HTTPError "Invalid or expired token, or password too short"
+// @Failure 429 {object} httputil.HTTPError "Too Many Requests"
+// @Failure 500 {object} httputil.HTTPError "Internal Server Error"
+// @Router /users/password-reset/confirm [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.NewError(c, httputil.BindStatus(err), err)
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		httputil.NewError(c, StatusFromError(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetSessionCookie sets the session cookie, or deletes it when maxAge is
+// negative. SameSite=Lax keeps the cookie off cross-site POSTs while still
+// sending it after sign-in redirects. The response carries the token, so it
+// is marked Cache-Control: no-store.
+func SetSessionCookie(c *gin.Context, token string, maxAge time.Duration, secure bool) {
+	c.Header("Cache-Control", "no-store")
+	seconds := int(maxAge.Seconds())
+	if maxAge < 0 {
+		seconds = -1
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, token, seconds, "/", "", secure, true)
+}
+
+// StatusFromError maps the account errors of this package and falls back to
+// httputil.StatusFromError for domain errors.
+func StatusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrEmailNotVerified):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidToken):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIdentityNotLinked):
+		return http.StatusConflict
+	}
+	return httputil.StatusFromError(err)
+}
@@ -0,0 +1,135 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+)
+
+func newTestRouter() (*gin.Engine, *recordingSender) {
+	sender := &recordingSender{}
+	service := NewUserService(NewMemoryUserRepository(), sender, audit.NewLog(audit.NewMemoryStore()), testOptions)
+	router := gin.New()
+	SetupRouter(router, service, auth.NewAuthentication(NewAuthenticator(service)), true)
+	return router, sender
+}
+
+func request(router *gin.Engine, method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == SessionCookie {
+			return cookie
+		}
+	}
+	t.Fatal("no session cookie set")
+	return nil
+}
+
+func TestUserFlow_RegisterVerifyLoginLogout(t *testing.T) {
+	// Arrange
+	router, sender := newTestRouter()
+	credentials := `{"email":"jane@example.com","password":"long enough"}`
+
+	// Act & Assert: register, but logging in needs a verified email.
+	register := request(router, "POST", "/users/register", `{"email":"jane@example.com","name":"Jane","password":"long enough"}`)
+	require.Equal(t, http.StatusCreated, register.Code)
+	assert.NotContains(t, register.Body.String(), "password")
+	assert.Equal(t, http.StatusForbidden, request(router, "POST", "/users/login", credentials).Code)
+
+	// Verify the email and log in.
+	verify := request(router, "POST", "/users/verify-email", `{"token":"`+sender.lastToken(t)+`"}`)
+	require.Equal(t, http.StatusNoContent, verify.Code)
+	login := request(router, "POST", "/users/login", credentials)
+	require.Equal(t, http.StatusOK, login.Code)
+	cookie := sessionCookie(t, login)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, "no-store", login.Header().Get("Cache-Control"))
+	var body LoginResponse
+	require.NoError(t, json.Unmarshal(login.Body.Bytes(), &body))
+	assert.Equal(t, cookie.Value, body.Token)
+
+	// The cookie signs the user in.
+	me := request(router, "GET", "/users/me", "", cookie)
+	assert.Equal(t, http.StatusOK, me.Code)
+	assert.Contains(t, me.Body.String(), `"email":"jane@example.com"`)
+	sessions := request(router, "GET", "/users/me/sessions", "", cookie)
+	assert.Equal(t, http.StatusOK, sessions.Code)
+	assert.Contains(t, sessions.Body.String(), `"current":true`)
+
+	// Logging out revokes the session and clears the cookie.
+	logout := request(router, "POST", "/users/logout", "", cookie)
+	assert.Equal(t, http.StatusNoContent, logout.Code)
+	assert.Equal(t, "", sessionCookie(t, logout).Value)
+	assert.Equal(t, http.StatusUnauthorized, request(router, "GET", "/users/me", "", cookie).Code)
+}
+
+func TestLogin_WrongPasswordIs401(t *testing.T) {
+	// Arrange
+	router, _ := newTestRouter()
+	request(router, "POST", "/users/register", `{"email":"jane@example.com","name":"Jane","password":"long enough"}`)
+
+	// Act
+	w := request(router, "POST", "/users/login", `{"email":"jane@example.com","password":"wrong password"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRegister_InvalidEmailIs400(t *testing.T) {
+	// Arrange
+	router, _ := newTestRouter()
+
+	// Act
+	w := request(router, "POST", "/users/register", `{"email":"not-an-email","name":"Jane","password":"long enough"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPasswordReset_AlwaysAccepted(t *testing.T) {
+	// Arrange
+	router, sender := newTestRouter()
+
+	// Act
+	w := request(router, "POST", "/users/password-reset", `{"email":"nobody@example.com"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, sender.messages)
+}
+
+func TestMe_RequiresSession(t *testing.T) {
+	// Arrange
+	router, _ := newTestRouter()
+
+	// Act
+	anonymous := request(router, "GET", "/users/me", "")
+	expired := request(router, "GET", "/users/me", "", &http.Cookie{Name: SessionCookie, Value: "gis_unknown"})
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	assert.Equal(t, "Session", anonymous.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, expired.Code)
+}
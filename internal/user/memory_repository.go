@@ -0,0 +1,228 @@
+package user
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
+
+type memoryUserRepository struct {
+	mu            sync.RWMutex
+	users         map[int]User
+	sessions      map[int]Session
+	tokens        map[string]Token
+	identities    map[[2]string]int
+	nextUserID    int
+	nextSessionID int
+}
+
+// NewMemoryUserRepository returns a thread-safe UserRepository that keeps
+// users, sessions and tokens in process memory. It is intended for local
+// development and tests.
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{
+		users:         make(map[int]User),
+		sessions:      make(map[int]Session),
+		tokens:        make(map[string]Token),
+		identities:    make(map[[2]string]int),
+		nextUserID:    1,
+		nextSessionID: 1,
+	}
+}
+
+func (m *memoryUserRepository) CreateUser(user *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return errs.NewConflictError(resourceName, "users_email_key", "email")
+		}
+	}
+	user.ID = m.nextUserID
+	user.CreatedAt = time.Now()
+	m.nextUserID++
+	m.users[user.ID] = *user
+	return nil
+}
+
+func (m *memoryUserRepository) GetUserById(id int) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (m *memoryUserRepository) GetUserByEmail(email string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryUserRepository) UpdatePassword(id int, hash string) error {
+	return m.updateUser(id, func(user *User) {
+		user.PasswordHash = hash
+	})
+}
+
+func (m *memoryUserRepository) MarkEmailVerified(id int, at time.Time) error {
+	return m.updateUser(id, func(user *User) {
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &at
+		}
+	})
+}
+
+func (m *memoryUserRepository) GetUserByIdentity(issuer, subject string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[m.identities[[2]string{issuer, subject}]]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (m *memoryUserRepository) LinkIdentity(userID int, issuer, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := [2]string{issuer, subject}
+	if _, ok := m.identities[key]; ok {
+		return errs.NewConflictError(resourceName, "user_identities_pkey", "subject")
+	}
+	m.identities[key] = userID
+	return nil
+}
+
+func (m *memoryUserRepository) CreateSession(session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.sessions {
+		if existing.TokenHash == session.TokenHash {
+			return errs.NewConflictError(resourceName, "user_sessions_token_hash_key", "token_hash")
+		}
+	}
+	session.ID = m.nextSessionID
+	session.LastSeenAt = session.CreatedAt
+	m.nextSessionID++
+	m.sessions[session.ID] = *session
+	return nil
+}
+
+func (m *memoryUserRepository) GetSessionByHash(hash string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.sessions {
+		if session.TokenHash == hash {
+			return &session, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryUserRepository) ListSessions(userID int, now time.Time) ([]*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]*Session, 0)
+	for id := 1; id < m.nextSessionID; id++ {
+		if session, ok := m.sessions[id]; ok && session.UserID == userID && session.Active(now) {
+			sessions = append(sessions, &session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memoryUserRepository) TouchSession(id int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		session.LastSeenAt = at
+		m.sessions[id] = session
+	}
+	return nil
+}
+
+func (m *memoryUserRepository) RevokeSession(userID, id int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if session.RevokedAt == nil {
+		session.RevokedAt = &at
+		m.sessions[id] = session
+	}
+	return nil
+}
+
+func (m *memoryUserRepository) RevokeAllSessions(userID int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+			m.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (m *memoryUserRepository) CreateToken(token *Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[token.Hash]; ok {
+		return errs.NewConflictError(resourceName, "user_tokens_pkey", "token_hash")
+	}
+	token.CreatedAt = time.Now()
+	m.tokens[token.Hash] = *token
+	return nil
+}
+
+func (m *memoryUserRepository) ConsumeToken(hash, purpose string, at time.Time) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[hash]
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || !at.Before(token.ExpiresAt) {
+		return nil, sql.ErrNoRows
+	}
+	token.UsedAt = &at
+	m.tokens[hash] = token
+	return &token, nil
+}
+
+// updateUser applies fn to the user with the given id under the write lock.
+func (m *memoryUserRepository) updateUser(id int, fn func(user *User)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	fn(&user)
+	m.users[id] = user
+	return nil
+}
@@ -0,0 +1,201 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/database"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+const (
+	userColumns    = "id, email, name, password_hash, roles, email_verified_at, created_at"
+	sessionColumns = "id, user_id, token_hash, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at"
+	tokenColumns   = "token_hash, user_id, purpose, expires_at, used_at, created_at"
+)
+
+type UserRepository interface {
+	CreateUser(user *User) error
+	GetUserById(id int) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	UpdatePassword(id int, hash string) error
+	MarkEmailVerified(id int, at time.Time) error
+	GetUserByIdentity(issuer, subject string) (*User, error)
+	LinkIdentity(userID int, issuer, subject string) error
+
+	CreateSession(session *Session) error
+	GetSessionByHash(hash string) (*Session, error)
+	// ListSessions returns the user's sessions that are active at now.
+	ListSessions(userID int, now time.Time) ([]*Session, error)
+	TouchSession(id int, at time.Time) error
+	RevokeSession(userID, id int, at time.Time) error
+	RevokeAllSessions(userID int, at time.Time) error
+
+	CreateToken(token *Token) error
+	// ConsumeToken marks an unused token that has not expired at time at as
+	// used and returns it, or sql.ErrNoRows if there is none.
+	ConsumeToken(hash, purpose string, at time.Time) (*Token, error)
+}
+
+type userRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// NewRepository returns the UserRepository implementation for the configured
+// database driver. Users need Postgres; other drivers fall back to an
+// in-memory store that is lost on restart.
+func NewRepository(driver string, db *sqlx.DB) (UserRepository, error) {
+	switch driver {
+	case database.DriverPostgres:
+		return NewUserRepository(db), nil
+	case database.DriverSQLite, database.DriverMemory:
+		logger.Warning("users are kept in memory", zap.String("driver", driver))
+		return NewMemoryUserRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func (r userRepository) CreateUser(user *User) error {
+	err := r.db.QueryRowx(
+		`INSERT INTO users (email, name, password_hash, roles, email_verified_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		user.Email, user.Name, user.PasswordHash, user.Roles, user.EmailVerifiedAt,
+	).Scan(&user.ID, &user.CreatedAt)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) GetUserById(id int) (*User, error) {
+	var user User
+	err := r.db.Get(&user, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &user, nil
+}
+
+func (r userRepository) GetUserByEmail(email string) (*User, error) {
+	var user User
+	err := r.db.Get(&user, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &user, nil
+}
+
+func (r userRepository) UpdatePassword(id int, hash string) error {
+	res, err := r.db.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", hash, id)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r userRepository) MarkEmailVerified(id int, at time.Time) error {
+	res, err := r.db.Exec("UPDATE users SET email_verified_at = COALESCE(email_verified_at, $1) WHERE id = $2", at, id)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r userRepository) GetUserByIdentity(issuer, subject string) (*User, error) {
+	var user User
+	err := r.db.Get(&user,
+		"SELECT "+userColumns+" FROM users WHERE id = (SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2)",
+		issuer, subject)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &user, nil
+}
+
+func (r userRepository) LinkIdentity(userID int, issuer, subject string) error {
+	_, err := r.db.Exec("INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)", issuer, subject, userID)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) CreateSession(session *Session) error {
+	err := r.db.QueryRowx(
+		`INSERT INTO user_sessions (user_id, token_hash, user_agent, ip, created_at, last_seen_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $5, $6) RETURNING id`,
+		session.UserID, session.TokenHash, session.UserAgent, session.IP, session.CreatedAt, session.ExpiresAt,
+	).Scan(&session.ID)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) GetSessionByHash(hash string) (*Session, error) {
+	var session Session
+	err := r.db.Get(&session, "SELECT "+sessionColumns+" FROM user_sessions WHERE token_hash = $1", hash)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &session, nil
+}
+
+func (r userRepository) ListSessions(userID int, now time.Time) ([]*Session, error) {
+	sessions := []*Session{}
+	err := r.db.Select(&sessions,
+		"SELECT "+sessionColumns+" FROM user_sessions WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2 ORDER BY id",
+		userID, now)
+	return sessions, errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) TouchSession(id int, at time.Time) error {
+	_, err := r.db.Exec("UPDATE user_sessions SET last_seen_at = $1 WHERE id = $2", at, id)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) RevokeSession(userID, id int, at time.Time) error {
+	res, err := r.db.Exec("UPDATE user_sessions SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2 AND user_id = $3", at, id, userID)
+	if err != nil {
+		return errs.FromPostgres(err, resourceName)
+	}
+	return requireRowsAffected(res)
+}
+
+func (r userRepository) RevokeAllSessions(userID int, at time.Time) error {
+	_, err := r.db.Exec("UPDATE user_sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", at, userID)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) CreateToken(token *Token) error {
+	err := r.db.QueryRowx(
+		`INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at)
+		 VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		token.Hash, token.UserID, token.Purpose, token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	return errs.FromPostgres(err, resourceName)
+}
+
+func (r userRepository) ConsumeToken(hash, purpose string, at time.Time) (*Token, error) {
+	var token Token
+	err := r.db.Get(&token,
+		`UPDATE user_tokens SET used_at = $1
+		 WHERE token_hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1
+		 RETURNING `+tokenColumns,
+		at, hash, purpose)
+	if err != nil {
+		return nil, errs.FromPostgres(err, resourceName)
+	}
+	return &token, nil
+}
+
+func requireRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
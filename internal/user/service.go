@@ -0,0 +1,468 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/auth"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+	"github.com/nilemarezz/go-init-template/pkg/mail"
+)
+
+// sessionPrefix marks strings as session tokens of this service, which helps
+// secret scanners recognise leaked tokens.
+const sessionPrefix = "gis_"
+
+// minPasswordLength is the shortest password accepted on registration and reset.
+const minPasswordLength = 8
+
+// auditResource names users in audit log entries.
+const auditResource = "user"
+
+// Login methods recorded in user.login audit entries.
+const (
+	loginPassword = "password"
+	loginIdentity = "identity"
+)
+
+// touchInterval limits how often a session's last_seen_at is written.
+const touchInterval = time.Minute
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	// ErrIdentityNotLinked is returned when an external identity claims the
+	// email of an existing account without the provider vouching for it.
+	ErrIdentityNotLinked = errors.New("an account with this email exists; sign in with your password")
+)
+
+// dummyHash is verified for unknown emails so that response times do not
+// reveal which accounts exist.
+var dummyHash, _ = auth.HashPassword("dummy password")
+
+// Options configures a UserService.
+type Options struct {
+	// BaseURL prefixes the links sent in verification and reset emails.
+	BaseURL              string
+	DefaultRoles         []string
+	RequireVerifiedEmail bool
+	SessionTTL           time.Duration
+	VerificationTTL      time.Duration
+	ResetTTL             time.Duration
+}
+
+// OptionsFromConfig returns the Options set in config.
+func OptionsFromConfig(config *config.Config) Options {
+	return Options{
+		BaseURL:              config.User.BaseURL,
+		DefaultRoles:         config.User.DefaultRoles,
+		RequireVerifiedEmail: config.User.RequireVerifiedEmail,
+		SessionTTL:           config.User.SessionTTL,
+		VerificationTTL:      config.User.VerificationTTL,
+		ResetTTL:             config.User.ResetTTL,
+	}
+}
+
+type UserService interface {
+	Register(req *RegisterRequest) (*User, error)
+	ResendVerification(email string) error
+	VerifyEmail(token string) error
+	Login(ctx context.Context, req *LoginRequest, userAgent, ip string) (*User, *Session, string, error)
+	LoginWithIdentity(ctx context.Context, identity *Identity, userAgent, ip string) (*User, *Session, string, error)
+	AuthenticateSession(token string) (*User, *Session, error)
+	GetUserById(id int) (*User, error)
+	ListSessions(userID int) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int) error
+	RequestPasswordReset(email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type userService struct {
+	repo    UserRepository
+	sender  mail.Sender
+	log     *audit.Log
+	options Options
+	now     func() time.Time
+}
+
+// NewUserService creates a UserService that records logins, identity links,
+// password resets and session revocations in log.
+func NewUserService(repo UserRepository, sender mail.Sender, log *audit.Log, options Options) UserService {
+	return &userService{repo: repo, sender: sender, log: log, options: options, now: time.Now}
+}
+
+// Register creates an account and emails a verification link.
+func (s userService) Register(req *RegisterRequest) (*User, error) {
+	if err := validatePassword(req.Password); err != nil {
+		return nil, err
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Email:        normalizeEmail(req.Email),
+		Name:         strings.TrimSpace(req.Name),
+		PasswordHash: hash,
+		Roles:        append([]string{}, s.options.DefaultRoles...),
+	}
+	if err := s.repo.CreateUser(user); err != nil {
+		return nil, err
+	}
+	if err := s.sendVerification(user); err != nil {
+		// The account exists; the user can ask for another email.
+		logger.Error("failed to send verification email", zap.Int("user_id", user.ID), zap.Error(err))
+	}
+	return user, nil
+}
+
+// ResendVerification emails a new verification link. Unknown and already
+// verified addresses are ignored so that callers cannot probe for accounts.
+func (s userService) ResendVerification(email string) error {
+	user, err := s.repo.GetUserByEmail(normalizeEmail(email))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.sendVerification(user)
+}
+
+func (s userService) VerifyEmail(token string) error {
+	consumed, err := s.repo.ConsumeToken(hashSecret(token), PurposeVerifyEmail, s.now())
+	if err == sql.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.MarkEmailVerified(consumed.UserID, s.now())
+}
+
+// Login checks the credentials and starts a session. The returned token is
+// the only copy of the session secret.
+func (s userService) Login(ctx context.Context, req *LoginRequest, userAgent, ip string) (*User, *Session, string, error) {
+	user, err := s.repo.GetUserByEmail(normalizeEmail(req.Email))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, "", err
+	}
+
+	hash := dummyHash
+	if user != nil && user.PasswordHash != "" {
+		hash = user.PasswordHash
+	}
+	match, err := auth.VerifyPassword(hash, req.Password)
+	if err != nil {
+		logger.Warning("login: cannot verify password hash", zap.String("email", req.Email), zap.Error(err))
+	}
+	if user == nil || user.PasswordHash == "" || !match {
+		return nil, nil, "", ErrInvalidCredentials
+	}
+	return s.startSession(ctx, user, loginPassword, userAgent, ip)
+}
+
+// LoginWithIdentity starts a session for a user authenticated by an external
+// provider. Unknown identities are linked to the account with the same email
+// if the provider verified it, or provisioned as a new passwordless account.
+// An account whose own email was never verified may have been registered by
+// someone else, so linking takes it over: its password and sessions are
+// dropped and its email counts as verified.
+func (s userService) LoginWithIdentity(ctx context.Context, identity *Identity, userAgent, ip string) (*User, *Session, string, error) {
+	user, err := s.repo.GetUserByIdentity(identity.Issuer, identity.Subject)
+	if err == nil {
+		return s.startSession(ctx, user, loginIdentity, userAgent, ip)
+	}
+	if err != sql.ErrNoRows {
+		return nil, nil, "", err
+	}
+
+	email := normalizeEmail(identity.Email)
+	if email == "" {
+		return nil, nil, "", errs.NewValidationError(resourceName, "email", "the identity provider did not share an email address")
+	}
+	user, err = s.repo.GetUserByEmail(email)
+	created, takenOver := false, false
+	switch {
+	case err == sql.ErrNoRows:
+		user = &User{
+			Email: email,
+			Name:  strings.TrimSpace(identity.Name),
+			Roles: append([]string{}, s.options.DefaultRoles...),
+		}
+		if identity.EmailVerified {
+			now := s.now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := s.repo.CreateUser(user); err != nil {
+			return nil, nil, "", err
+		}
+		created = true
+	case err != nil:
+		return nil, nil, "", err
+	case !identity.EmailVerified:
+		return nil, nil, "", ErrIdentityNotLinked
+	case user.EmailVerifiedAt == nil:
+		if err := s.takeOver(user); err != nil {
+			return nil, nil, "", err
+		}
+		takenOver = true
+	}
+
+	if err := s.repo.LinkIdentity(user.ID, identity.Issuer, identity.Subject); err != nil {
+		return nil, nil, "", err
+	}
+	s.record(ctx, audit.UserIdentityLink, user.ID, map[string]interface{}{
+		"issuer":     identity.Issuer,
+		"subject":    identity.Subject,
+		"created":    created,
+		"taken_over": takenOver,
+	})
+	if s.options.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		if err := s.sendVerification(user); err != nil {
+			logger.Error("failed to send verification email", zap.Int("user_id", user.ID), zap.Error(err))
+		}
+	}
+	return s.startSession(ctx, user, loginIdentity, userAgent, ip)
+}
+
+// takeOver hands an account with an unverified email to the identity that
+// the provider verified the email for.
+func (s userService) takeOver(user *User) error {
+	now := s.now()
+	if err := s.repo.UpdatePassword(user.ID, ""); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeAllSessions(user.ID, now); err != nil {
+		return err
+	}
+	if err := s.repo.MarkEmailVerified(user.ID, now); err != nil {
+		return err
+	}
+	user.PasswordHash = ""
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+// startSession starts a session for a user authenticated by method, unless
+// the user still has to verify their email.
+func (s userService) startSession(ctx context.Context, user *User, method, userAgent, ip string) (*User, *Session, string, error) {
+	if s.options.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, nil, "", ErrEmailNotVerified
+	}
+	token, hash, err := generateSecret(sessionPrefix)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	now := s.now()
+	session := &Session{
+		UserID:    user.ID,
+		TokenHash: hash,
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.options.SessionTTL),
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, nil, "", err
+	}
+	s.record(ctx, audit.UserLogin, user.ID, map[string]interface{}{"method": method, "session_id": session.ID, "client_ip": ip})
+	return user, session, token, nil
+}
+
+// AuthenticateSession returns the user and active session for token.
+func (s userService) AuthenticateSession(token string) (*User, *Session, error) {
+	if !strings.HasPrefix(token, sessionPrefix) {
+		return nil, nil, ErrInvalidSession
+	}
+	session, err := s.repo.GetSessionByHash(hashSecret(token))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	if !session.Active(now) {
+		return nil, nil, ErrInvalidSession
+	}
+
+	user, err := s.repo.GetUserById(session.UserID)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.Sub(session.LastSeenAt) >= touchInterval {
+		if err := s.repo.TouchSession(session.ID, now); err != nil {
+			return nil, nil, err
+		}
+		session.LastSeenAt = now
+	}
+	return user, session, nil
+}
+
+func (s userService) GetUserById(id int) (*User, error) {
+	user, err := s.repo.GetUserById(id)
+	if err == sql.ErrNoRows {
+		return nil, errs.NewNotFoundError(resourceName)
+	}
+	return user, err
+}
+
+func (s userService) ListSessions(userID int) ([]*Session, error) {
+	return s.repo.ListSessions(userID, s.now())
+}
+
+// RevokeSession ends one of the user's sessions. Sessions of other users are
+// reported as not found.
+func (s userService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	err := s.repo.RevokeSession(userID, sessionID, s.now())
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError("Session")
+	}
+	if err != nil {
+		return err
+	}
+	s.record(ctx, audit.UserSessionRevoke, userID, map[string]interface{}{"session_id": sessionID})
+	return nil
+}
+
+// RequestPasswordReset emails a reset link. Unknown addresses are ignored so
+// that callers cannot probe for accounts.
+func (s userService) RequestPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(normalizeEmail(email))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.createToken(user.ID, PurposeResetPassword, s.options.ResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for a reset, ignore this email.\n",
+			user.Name, s.options.ResetTTL, s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out
+// everywhere.
+func (s userService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := s.repo.ConsumeToken(hashSecret(token), PurposeResetPassword, s.now())
+	if err == sql.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(consumed.UserID, hash); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeAllSessions(consumed.UserID, s.now()); err != nil {
+		return err
+	}
+	s.record(ctx, audit.UserPasswordReset, consumed.UserID, nil)
+	return nil
+}
+
+func (s userService) record(ctx context.Context, eventType string, userID int, data interface{}) {
+	s.log.Record(ctx, audit.Event{
+		Type:       eventType,
+		Resource:   auditResource,
+		ResourceID: strconv.Itoa(userID),
+		Data:       data,
+	})
+}
+
+func (s userService) sendVerification(user *User) error {
+	token, err := s.createToken(user.ID, PurposeVerifyEmail, s.options.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address with this link. It expires in %s.\n\n%s\n",
+			user.Name, s.options.VerificationTTL, s.link("/verify-email", token)),
+	})
+}
+
+func (s userService) createToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	secret, hash, err := generateSecret("")
+	if err != nil {
+		return "", err
+	}
+	token := &Token{Hash: hash, UserID: userID, Purpose: purpose, ExpiresAt: s.now().Add(ttl)}
+	if err := s.repo.CreateToken(token); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func (s userService) link(path, token string) string {
+	return strings.TrimRight(s.options.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errs.NewValidationError(resourceName, "password", fmt.Sprintf("must be at least %d characters", minPasswordLength))
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// generateSecret returns a random secret with the given prefix and the hash
+// to store for it.
+func generateSecret(prefix string) (secret, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = prefix + hex.EncodeToString(raw)
+	return secret, hashSecret(secret), nil
+}
+
+// hashSecret hashes a session or email token. Tokens carry 256 bits of
+// randomness, so a fast hash is sufficient.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,356 @@
+package user
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/internal/audit"
+	"github.com/nilemarezz/go-init-template/internal/errs"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+	"github.com/nilemarezz/go-init-template/pkg/mail"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// recordingSender keeps sent messages instead of delivering them.
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (r *recordingSender) Send(msg mail.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+var tokenPattern = regexp.MustCompile(`token=([0-9a-f]+)`)
+
+// lastToken returns the token from the most recent message.
+func (r *recordingSender) lastToken(t *testing.T) string {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.NotEmpty(t, r.messages)
+	match := tokenPattern.FindStringSubmatch(r.messages[len(r.messages)-1].Body)
+	require.NotNil(t, match)
+	return match[1]
+}
+
+var testOptions = Options{
+	BaseURL:              "https://app.example.com",
+	DefaultRoles:         []string{"editor"},
+	RequireVerifiedEmail: true,
+	SessionTTL:           time.Hour,
+	VerificationTTL:      time.Hour,
+	ResetTTL:             time.Hour,
+}
+
+func newTestService() (*userService, UserRepository, *recordingSender) {
+	repo := NewMemoryUserRepository()
+	sender := &recordingSender{}
+	return NewUserService(repo, sender, audit.NewLog(audit.NewMemoryStore()), testOptions).(*userService), repo, sender
+}
+
+func register(t *testing.T, svc UserService, sender *recordingSender, verify bool) *User {
+	t.Helper()
+	user, err := svc.Register(&RegisterRequest{Email: " Jane@Example.com ", Name: "Jane", Password: "long enough"})
+	require.NoError(t, err)
+	if verify {
+		require.NoError(t, svc.VerifyEmail(sender.lastToken(t)))
+	}
+	return user
+}
+
+func TestRegister_HashesPasswordAndSendsVerification(t *testing.T) {
+	// Arrange
+	svc, repo, sender := newTestService()
+
+	// Act
+	user := register(t, svc, sender, false)
+
+	// Assert
+	stored, err := repo.GetUserById(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", stored.Email)
+	assert.NotEqual(t, "long enough", stored.PasswordHash)
+	assert.Equal(t, []string{"editor"}, []string(stored.Roles))
+	assert.Nil(t, stored.EmailVerifiedAt)
+	require.Len(t, sender.messages, 1)
+	assert.Equal(t, "jane@example.com", sender.messages[0].To)
+	assert.Contains(t, sender.messages[0].Body, "https://app.example.com/verify-email?token=")
+}
+
+func TestRegister_RejectsShortPasswordAndDuplicateEmail(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	register(t, svc, sender, false)
+
+	// Act
+	_, shortErr := svc.Register(&RegisterRequest{Email: "john@example.com", Name: "John", Password: "short"})
+	_, duplicateErr := svc.Register(&RegisterRequest{Email: "JANE@example.com", Name: "Jane", Password: "long enough"})
+
+	// Assert
+	assert.IsType(t, &errs.ValidationError{}, shortErr)
+	assert.IsType(t, &errs.ConflictError{}, duplicateErr)
+}
+
+func TestVerifyEmail_TokenIsSingleUse(t *testing.T) {
+	// Arrange
+	svc, repo, sender := newTestService()
+	user := register(t, svc, sender, false)
+	token := sender.lastToken(t)
+
+	// Act
+	first := svc.VerifyEmail(token)
+	second := svc.VerifyEmail(token)
+
+	// Assert
+	assert.NoError(t, first)
+	assert.ErrorIs(t, second, ErrInvalidToken)
+	stored, _ := repo.GetUserById(user.ID)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+}
+
+func TestVerifyEmail_RejectsExpiredToken(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	register(t, svc, sender, false)
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	// Act
+	err := svc.VerifyEmail(sender.lastToken(t))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLogin(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	register(t, svc, sender, false)
+	login := &LoginRequest{Email: "jane@example.com", Password: "long enough"}
+
+	// Act
+	_, _, _, unverifiedErr := svc.Login(context.Background(), login, "test", "192.0.2.1")
+	require.NoError(t, svc.VerifyEmail(sender.lastToken(t)))
+	_, _, _, wrongErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "wrong password"}, "test", "192.0.2.1")
+	_, _, _, unknownErr := svc.Login(context.Background(), &LoginRequest{Email: "nobody@example.com", Password: "long enough"}, "test", "192.0.2.1")
+	user, session, token, err := svc.Login(context.Background(), login, "test", "192.0.2.1")
+
+	// Assert
+	assert.ErrorIs(t, unverifiedErr, ErrEmailNotVerified)
+	assert.ErrorIs(t, wrongErr, ErrInvalidCredentials)
+	assert.ErrorIs(t, unknownErr, ErrInvalidCredentials)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "192.0.2.1", session.IP)
+	assert.Equal(t, hashSecret(token), session.TokenHash)
+
+	authenticated, authenticatedSession, err := svc.AuthenticateSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+	assert.Equal(t, session.ID, authenticatedSession.ID)
+}
+
+func TestAuthenticateSession_RejectsRevokedAndExpired(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	user := register(t, svc, sender, true)
+	login := &LoginRequest{Email: "jane@example.com", Password: "long enough"}
+	_, revoked, revokedToken, _ := svc.Login(context.Background(), login, "", "")
+	_, _, expiredToken, _ := svc.Login(context.Background(), login, "", "")
+
+	// Act
+	require.NoError(t, svc.RevokeSession(context.Background(), user.ID, revoked.ID))
+	_, _, revokedErr := svc.AuthenticateSession(revokedToken)
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, _, expiredErr := svc.AuthenticateSession(expiredToken)
+	_, _, garbageErr := svc.AuthenticateSession("garbage")
+
+	// Assert
+	assert.ErrorIs(t, revokedErr, ErrInvalidSession)
+	assert.ErrorIs(t, expiredErr, ErrInvalidSession)
+	assert.ErrorIs(t, garbageErr, ErrInvalidSession)
+}
+
+func TestRevokeSession_OtherUsersSessionIsNotFound(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	register(t, svc, sender, true)
+	_, session, _, err := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "long enough"}, "", "")
+	require.NoError(t, err)
+
+	// Act
+	err = svc.RevokeSession(context.Background(), 999, session.ID)
+
+	// Assert
+	assert.IsType(t, &errs.NotFoundError{}, err)
+}
+
+func TestResetPassword_ChangesPasswordAndRevokesSessions(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	register(t, svc, sender, true)
+	_, _, sessionToken, err := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "long enough"}, "", "")
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, svc.RequestPasswordReset("JANE@example.com"))
+	resetToken := sender.lastToken(t)
+	err = svc.ResetPassword(context.Background(), resetToken, "a new password")
+	reuseErr := svc.ResetPassword(context.Background(), resetToken, "another password")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.ErrorIs(t, reuseErr, ErrInvalidToken)
+	_, _, sessionErr := svc.AuthenticateSession(sessionToken)
+	assert.ErrorIs(t, sessionErr, ErrInvalidSession)
+	_, _, _, oldErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "long enough"}, "", "")
+	assert.ErrorIs(t, oldErr, ErrInvalidCredentials)
+	_, _, _, newErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "a new password"}, "", "")
+	assert.NoError(t, newErr)
+}
+
+func TestRequestPasswordReset_IgnoresUnknownEmail(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+
+	// Act
+	err := svc.RequestPasswordReset("nobody@example.com")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, sender.messages)
+}
+
+func TestLoginWithIdentity_ProvisionsAndReusesUser(t *testing.T) {
+	// Arrange
+	svc, repo, _ := newTestService()
+	identity := &Identity{Issuer: "https://idp.example.com", Subject: "abc", Email: "Jane@Example.com", EmailVerified: true, Name: "Jane"}
+
+	// Act
+	first, _, token, err := svc.LoginWithIdentity(context.Background(), identity, "test", "192.0.2.1")
+	require.NoError(t, err)
+	second, _, _, secondErr := svc.LoginWithIdentity(context.Background(), identity, "test", "192.0.2.1")
+	_, _, _, passwordErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: ""}, "test", "192.0.2.1")
+
+	// Assert
+	require.NoError(t, secondErr)
+	assert.Equal(t, first.ID, second.ID)
+	stored, err := repo.GetUserById(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", stored.Email)
+	assert.Empty(t, stored.PasswordHash)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+	assert.Equal(t, []string{"editor"}, []string(stored.Roles))
+	assert.ErrorIs(t, passwordErr, ErrInvalidCredentials)
+	_, _, err = svc.AuthenticateSession(token)
+	assert.NoError(t, err)
+}
+
+func TestLoginWithIdentity_LinksExistingAccountOnlyForVerifiedEmail(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	existing := register(t, svc, sender, true)
+	unverified := &Identity{Issuer: "https://idp.example.com", Subject: "abc", Email: "jane@example.com"}
+	verified := &Identity{Issuer: "https://idp.example.com", Subject: "abc", Email: "jane@example.com", EmailVerified: true}
+
+	// Act
+	_, _, _, unverifiedErr := svc.LoginWithIdentity(context.Background(), unverified, "test", "192.0.2.1")
+	user, _, _, err := svc.LoginWithIdentity(context.Background(), verified, "test", "192.0.2.1")
+
+	// Assert
+	assert.ErrorIs(t, unverifiedErr, ErrIdentityNotLinked)
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, user.ID)
+}
+
+func TestLoginWithIdentity_TakesOverAccountWithUnverifiedEmail(t *testing.T) {
+	// Arrange
+	svc, repo, sender := newTestService()
+	svc.options.RequireVerifiedEmail = false
+	squatter := register(t, svc, sender, false)
+	_, _, squatterToken, err := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "long enough"}, "test", "192.0.2.1")
+	require.NoError(t, err)
+	identity := &Identity{Issuer: "https://idp.example.com", Subject: "abc", Email: "jane@example.com", EmailVerified: true}
+
+	// Act
+	user, _, _, err := svc.LoginWithIdentity(context.Background(), identity, "test", "192.0.2.1")
+	_, _, sessionErr := svc.AuthenticateSession(squatterToken)
+	_, _, _, passwordErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "long enough"}, "test", "192.0.2.1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, squatter.ID, user.ID)
+	assert.ErrorIs(t, sessionErr, ErrInvalidSession)
+	assert.ErrorIs(t, passwordErr, ErrInvalidCredentials)
+	stored, err := repo.GetUserById(squatter.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.PasswordHash)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+}
+
+func TestLoginWithIdentity_RequiresVerifiedEmail(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	identity := &Identity{Issuer: "https://idp.example.com", Subject: "abc", Email: "jane@example.com", Name: "Jane"}
+
+	// Act
+	_, _, _, unverifiedErr := svc.LoginWithIdentity(context.Background(), identity, "test", "192.0.2.1")
+	require.NoError(t, svc.VerifyEmail(sender.lastToken(t)))
+	_, _, _, verifiedErr := svc.LoginWithIdentity(context.Background(), identity, "test", "192.0.2.1")
+
+	// Assert
+	assert.ErrorIs(t, unverifiedErr, ErrEmailNotVerified)
+	assert.NoError(t, verifiedErr)
+	assert.Len(t, sender.messages, 1)
+}
+
+func TestAccountEvents_AreAudited(t *testing.T) {
+	// Arrange
+	svc, _, sender := newTestService()
+	store := audit.NewMemoryStore()
+	svc.log = audit.NewLog(store)
+	user := register(t, svc, sender, true)
+	login := &LoginRequest{Email: "jane@example.com", Password: "long enough"}
+
+	// Act
+	_, _, _, failedErr := svc.Login(context.Background(), &LoginRequest{Email: "jane@example.com", Password: "wrong password"}, "", "")
+	_, session, _, err := svc.Login(context.Background(), login, "", "192.0.2.1")
+	require.NoError(t, err)
+	require.NoError(t, svc.RevokeSession(context.Background(), user.ID, session.ID))
+	_, _, _, err = svc.LoginWithIdentity(context.Background(), &Identity{Issuer: "https://idp.example.com", Subject: "jane", Email: "jane@example.com", EmailVerified: true}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, svc.RequestPasswordReset("jane@example.com"))
+	require.NoError(t, svc.ResetPassword(context.Background(), sender.lastToken(t), "a new password"))
+
+	// Assert
+	assert.ErrorIs(t, failedErr, ErrInvalidCredentials)
+	var entries []*audit.Entry
+	require.NoError(t, store.Entries(context.Background(), 0, func(e *audit.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	var types []string
+	for _, entry := range entries {
+		types = append(types, entry.Type)
+		assert.Equal(t, "user", entry.Resource)
+		assert.Equal(t, strconv.Itoa(user.ID), entry.ResourceID)
+	}
+	assert.Equal(t, []string{audit.UserLogin, audit.UserSessionRevoke, audit.UserIdentityLink, audit.UserLogin, audit.UserPasswordReset}, types)
+}
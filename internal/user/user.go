@@ -0,0 +1,107 @@
+package user
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// resourceName is the name used for users in domain errors.
+const resourceName = "User"
+
+// Purposes of single-use tokens.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// User is a person who can sign in to the API.
+type User struct {
+	// PasswordHash is empty for users provisioned from an external identity,
+	// who cannot log in with a password.
+	ID              int            `db:"id" json:"id"`
+	Email           string         `db:"email" json:"email" example:"jane@example.com"`
+	Name            string         `db:"name" json:"name" example:"Jane Doe"`
+	PasswordHash    string         `db:"password_hash" json:"-"`
+	Roles           pq.StringArray `db:"roles" json:"roles" swaggertype:"array,string" example:"reader"`
+	EmailVerifiedAt *time.Time     `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Identity is a user authenticated by an external OpenID Connect provider.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Session is a signed-in browser or client. Only a hash of the session token
+// is stored.
+type Session struct {
+	ID         int        `db:"id" json:"id"`
+	UserID     int        `db:"user_id" json:"-"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IP         string     `db:"ip" json:"ip"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	// Current marks the session making the request in session listings.
+	Current bool `db:"-" json:"current"`
+}
+
+// Token is a single-use token sent by email.
+type Token struct {
+	Hash      string     `db:"token_hash"`
+	UserID    int        `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// RegisterRequest is the body of a registration request.
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email" example:"jane@example.com"`
+	Name     string `json:"name" binding:"required" example:"Jane Doe"`
+	Password string `json:"password" binding:"required" example:"correct horse battery staple"`
+}
+
+// LoginRequest is the body of a login request.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required" example:"jane@example.com"`
+	Password string `json:"password" binding:"required" example:"correct horse battery staple"`
+}
+
+// LoginResponse returns the session token for clients that do not use the
+// session cookie.
+type LoginResponse struct {
+	User      *User     `json:"user"`
+	Token     string    `json:"token" example:"gis_..."`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenRequest is the body of a request that redeems an emailed token.
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest is the body of a request to resend a verification email or
+// send a password reset email.
+type EmailRequest struct {
+	Email string `json:"email" binding:"required" example:"jane@example.com"`
+}
+
+// PasswordResetConfirmRequest sets a new password with a reset token.
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Active reports whether the session may currently be used.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,68 @@
+package httputil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONWithValidators writes v as JSON with an ETag computed from the body
+// and, unless lastModified is zero, a Last-Modified header, so that clients
+// can revalidate what they have cached. A GET or HEAD whose If-None-Match,
+// or failing that If-Modified-Since, shows that the client already has this
+// representation gets 304 Not Modified without a body instead.
+//
+// Pass a zero lastModified for documents, such as lists, that can change
+// without any of their timestamps moving forward.
+func JSONWithValidators(c *gin.Context, status int, v interface{}, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		NewError(c, http.StatusInternalServerError, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := c.Writer.Header()
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// notModified evaluates the cache validators of a GET or HEAD request as
+// RFC 9110 section 13.2.2 orders them: If-None-Match wins when present.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses the weak comparison.
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have whole seconds.
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -1,6 +1,13 @@
 package httputil
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilemarezz/go-init-template/internal/errs"
+)
 
 // NewError creates a new HTTPError response.
 // @Summary Create a new error response
@@ -22,3 +29,39 @@ type HTTPError struct {
 	Code    int    `json:"code" example:"400"`
 	Message string `json:"message" example:"status bad request"`
 }
+
+// StatusFromError maps a domain error from internal/errs to the HTTP status
+// code that should be returned to the client. Unknown errors map to 500.
+func StatusFromError(err error) int {
+	var (
+		notFound   *errs.NotFoundError
+		conflict   *errs.ConflictError
+		validation *errs.ValidationError
+		timeout    *errs.TimeoutError
+		retryable  *errs.RetryableError
+		inUse      *errs.InUseError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &conflict), errors.As(err, &inUse):
+		return http.StatusConflict
+	case errors.As(err, &validation):
+		return http.StatusBadRequest
+	case errors.As(err, &timeout):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &retryable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// BindStatus maps an error from binding a request body to the status code
+// that should be returned: 413 if the body exceeded its size limit, else 400.
+func BindStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
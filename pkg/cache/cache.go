@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Supported values for the cache.backend config key.
+const (
+	BackendLRU   = "lru"
+	BackendRedis = "redis"
+)
+
+// Cache stores opaque values under string keys with a per-entry TTL.
+type Cache interface {
+	// Get returns the value stored under key and whether it was present.
+	Get(key string) ([]byte, bool, error)
+	// Set stores value under key until ttl elapses.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys. Missing keys are ignored.
+	Delete(keys ...string) error
+}
+
+// NewCache returns the Cache implementation for the configured backend.
+func NewCache(config *config.Config) (Cache, error) {
+	switch config.Cache.Backend {
+	case BackendLRU:
+		return NewLRU(config.Cache.Size), nil
+	case BackendRedis:
+		return NewRedis(config.Cache.RedisAddr)
+	}
+	return nil, fmt.Errorf("unsupported cache backend %q", config.Cache.Backend)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_SetAndGet(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+
+	// Act
+	_ = lru.Set("a", []byte("1"), time.Minute)
+	value, ok, err := lru.Get("a")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+	_ = lru.Set("a", []byte("1"), time.Minute)
+	_ = lru.Set("b", []byte("2"), time.Minute)
+	_, _, _ = lru.Get("a")
+
+	// Act
+	_ = lru.Set("c", []byte("3"), time.Minute)
+
+	// Assert
+	_, okA, _ := lru.Get("a")
+	_, okB, _ := lru.Get("b")
+	_, okC, _ := lru.Get("c")
+	assert.True(t, okA)
+	assert.False(t, okB)
+	assert.True(t, okC)
+	assert.Equal(t, 2, lru.Len())
+}
+
+func TestLRU_ExpiresEntries(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+	now := time.Now()
+	lru.now = func() time.Time { return now }
+	_ = lru.Set("a", []byte("1"), time.Second)
+
+	// Act
+	lru.now = func() time.Time { return now.Add(2 * time.Second) }
+	_, ok, _ := lru.Get("a")
+
+	// Assert
+	assert.False(t, ok)
+	assert.Equal(t, 0, lru.Len())
+}
+
+func TestLRU_Delete(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+	_ = lru.Set("a", []byte("1"), time.Minute)
+
+	// Act
+	err := lru.Delete("a", "missing")
+
+	// Assert
+	assert.NoError(t, err)
+	_, ok, _ := lru.Get("a")
+	assert.False(t, ok)
+}
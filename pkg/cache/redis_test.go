@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis_SetGetDelete(t *testing.T) {
+	// Arrange
+	server := miniredis.RunT(t)
+	redis, err := NewRedis(server.Addr())
+	require.NoError(t, err)
+	defer redis.Close()
+
+	// Act
+	setErr := redis.Set("a", []byte("1"), time.Minute)
+	value, ok, getErr := redis.Get("a")
+	deleteErr := redis.Delete("a")
+	_, okAfterDelete, _ := redis.Get("a")
+
+	// Assert
+	assert.NoError(t, setErr)
+	assert.NoError(t, getErr)
+	assert.NoError(t, deleteErr)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.False(t, okAfterDelete)
+}
+
+func TestRedis_ExpiresEntries(t *testing.T) {
+	// Arrange
+	server := miniredis.RunT(t)
+	redis, err := NewRedis(server.Addr())
+	require.NoError(t, err)
+	defer redis.Close()
+	_ = redis.Set("a", []byte("1"), time.Second)
+
+	// Act
+	server.FastForward(2 * time.Second)
+	_, ok, err := redis.Get("a")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
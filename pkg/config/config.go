@@ -1,32 +1,283 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Database DBConfig
-	Log      LogConfig
-	App      AppConfig
+	Database    DBConfig
+	Log         LogConfig
+	App         AppConfig
+	Cache       CacheConfig
+	Auth        AuthConfig
+	Authz       AuthzConfig
+	RateLimit   RateLimitConfig
+	HTTP        HTTPConfig
+	Idempotency IdempotencyConfig
+	Admin       AdminConfig
+	User        UserConfig
+	Mail        MailConfig
 }
 
 type DBConfig struct {
+	// Driver selects the storage backend: postgres (default), sqlite or memory.
+	Driver string
+	// Path is the SQLite database file, used only by the sqlite driver.
+	Path     string
 	Host     string
 	Port     int
 	User     string
 	DBName   string
 	Password string
 	SSLMode  string
+	// ChangeFeed enables the LISTEN/NOTIFY change listener (postgres only).
+	ChangeFeed bool
 }
 
 type LogConfig struct {
 	Path string
+	// Level is the initial log level: debug, info (default), warn or error.
+	Level string
 }
 
 type AppConfig struct {
 	Port string
+	TLS  TLSConfig
+}
+
+type AdminConfig struct {
+	// Enabled moves metrics, Swagger and operational endpoints from the public
+	// port to a separate admin listener.
+	Enabled bool
+	Port    string
+	Auth    AdminAuthConfig
+}
+
+type AdminAuthConfig struct {
+	// Enabled requires Basic auth with one of Users for everything on the
+	// admin listener except health checks.
+	Enabled bool
+	Users   []BasicAuthUser
+}
+
+type UserConfig struct {
+	// BaseURL prefixes the links sent in verification and reset emails.
+	BaseURL string
+	// DefaultRoles are given to newly registered users.
+	DefaultRoles []string
+	// RequireVerifiedEmail rejects logins until the email is verified.
+	RequireVerifiedEmail bool
+	SessionTTL           time.Duration
+	VerificationTTL      time.Duration
+	ResetTTL             time.Duration
+	// CookieSecure marks the session cookie Secure; disable only for plain
+	// HTTP development servers.
+	CookieSecure bool
+}
+
+type MailConfig struct {
+	// Backend selects how mail is delivered: file (default) or smtp.
+	Backend string
+	// Dir is where the file backend writes .eml files.
+	Dir      string
+	SMTPAddr string
+	Username string
+	Password string
+	From     string
+}
+
+type TLSConfig struct {
+	Enabled  bool
+	CertFile string
+	KeyFile  string
+	// MinVersion is the lowest accepted protocol version: 1.2 (default) or 1.3.
+	MinVersion string
+	// CipherSuites restricts TLS 1.2 cipher suites by their Go names; empty
+	// keeps the Go defaults.
+	CipherSuites []string
+	// ClientCAFile enables mutual TLS with client certificates signed by these CAs.
+	ClientCAFile string
+	// ClientAuth is none (default), request (verify certificates if sent) or
+	// require.
+	ClientAuth string
+	// ReloadInterval is how often the certificate files are checked for changes.
+	ReloadInterval time.Duration
+}
+
+type CacheConfig struct {
+	Enabled bool
+	// Backend selects the cache store: lru (default) or redis.
+	Backend     string
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	RedisAddr   string
+}
+
+type AuthConfig struct {
+	JWT   JWTConfig
+	Basic BasicAuthConfig
+	MTLS  MTLSConfig
+	OIDC  OIDCConfig
+}
+
+type OIDCConfig struct {
+	Enabled bool
+	// Issuer is the OpenID provider URL; its discovery document is read from
+	// <issuer>/.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this service's callback, e.g.
+	// https://api.example.com/auth/oidc/callback.
+	RedirectURL string
+	Scopes      []string
+	// PostLoginURL is where the browser is sent after signing in.
+	PostLoginURL string
+	ClockSkew    time.Duration
+	// MockIdP serves an embedded identity provider at the issuer URL's path
+	// that signs in MockUser without a password. Development only.
+	MockIdP  bool
+	MockUser OIDCMockUser
+}
+
+type OIDCMockUser struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+type MTLSConfig struct {
+	// Enabled authenticates requests by their verified client certificate.
+	// Needs app.tls.clientcafile.
+	Enabled bool
+}
+
+type BasicAuthConfig struct {
+	Enabled bool
+	// Source selects where credentials are stored: config (default) or database.
+	Source          string
+	Users           []BasicAuthUser
+	MaxFailures     int
+	LockoutDuration time.Duration
+}
+
+type BasicAuthUser struct {
+	Username string
+	// PasswordHash is a bcrypt or argon2id hash, see cmd/hashpassword.
+	PasswordHash string
+	Roles        []string
+}
+
+type JWTConfig struct {
+	Enabled bool
+	// JWKS is the path or http(s) URL of the JSON Web Key Set.
+	JWKS            string
+	Issuer          string
+	Audience        string
+	ClockSkew       time.Duration
+	RefreshInterval time.Duration
+}
+
+type AuthzConfig struct {
+	// Roles overrides or adds role to permission mappings.
+	Roles map[string][]string
+	// Ownership restricts editors to updating authors they created.
+	Ownership bool
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Store selects where buckets are kept: memory (default) or redis.
+	Store     string
+	RedisAddr string
+	// Default applies to routes without their own rule; a zero rate disables it.
+	Default RateLimitRule
+	Routes  []RateLimitRoute
+}
+
+type RateLimitRule struct {
+	// Rate is the number of requests per second a client may sustain.
+	Rate  float64
+	Burst int
+}
+
+type RateLimitRoute struct {
+	// Method is the HTTP method to match; empty matches every method.
+	Method string
+	// Path is the route pattern as registered, e.g. /authors/:id.
+	Path  string
+	Rate  float64
+	Burst int
+}
+
+type IdempotencyConfig struct {
+	Enabled bool
+	// TTL is how long a response is kept for replay.
+	TTL time.Duration
+}
+
+type HTTPConfig struct {
+	CORS    CORSConfig
+	Headers SecurityHeadersConfig
+	// MaxBodySize is the request body limit in bytes for routes without their
+	// own limit; zero disables it.
+	MaxBodySize int64
+	BodyLimits  []BodyLimitRoute
+	// TrustedProxies lists the addresses or CIDR ranges of the reverse
+	// proxies whose X-Forwarded-For header gives the client address. With
+	// none, the client address is that of the connection.
+	TrustedProxies []string
+}
+
+type CORSConfig struct {
+	Enabled bool
+	// AllowOrigins lists the allowed origins; "*" allows any origin, and
+	// cannot be combined with AllowCredentials.
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// ErrCORSAnyOriginWithCredentials is returned for a CORS configuration that
+// would let any site make requests with the user's cookies.
+var ErrCORSAnyOriginWithCredentials = errors.New(`http.cors: alloworigins "*" cannot be combined with allowcredentials`)
+
+// Validate rejects settings that are unsafe.
+func (c CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			return ErrCORSAnyOriginWithCredentials
+		}
+	}
+	return nil
+}
+
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge enables Strict-Transport-Security when non-zero.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// ContentSecurityPolicy applies to API responses; SwaggerContentSecurityPolicy
+	// replaces it for the Swagger UI, which needs scripts and styles.
+	ContentSecurityPolicy        string
+	SwaggerContentSecurityPolicy string
+}
+
+type BodyLimitRoute struct {
+	// Method is the HTTP method to match; empty matches every method.
+	Method string
+	// Path is the route pattern as registered, e.g. /authors/.
+	Path        string
+	MaxBodySize int64
 }
 
 func LoadConfig(env string) (Config, error) {
@@ -35,6 +286,40 @@ func LoadConfig(env string) (Config, error) {
 	viper.SetConfigName("config." + env)
 	viper.AddConfigPath("./config")
 	viper.SetConfigType("yaml")
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("admin.port", "8081")
+	viper.SetDefault("user.defaultroles", []string{"reader"})
+	viper.SetDefault("user.requireverifiedemail", true)
+	viper.SetDefault("user.sessionttl", "720h")
+	viper.SetDefault("user.verificationttl", "48h")
+	viper.SetDefault("user.resetttl", "1h")
+	viper.SetDefault("user.cookiesecure", true)
+	viper.SetDefault("mail.backend", "file")
+	viper.SetDefault("mail.dir", "./tmp/mail")
+	viper.SetDefault("mail.from", "no-reply@localhost")
+	viper.SetDefault("app.tls.minversion", "1.2")
+	viper.SetDefault("app.tls.clientauth", "none")
+	viper.SetDefault("app.tls.reloadinterval", "1m")
+	viper.SetDefault("cache.backend", "lru")
+	viper.SetDefault("cache.size", 10000)
+	viper.SetDefault("cache.ttl", "5m")
+	viper.SetDefault("cache.negativettl", "30s")
+	viper.SetDefault("auth.jwt.clockskew", "30s")
+	viper.SetDefault("auth.jwt.refreshinterval", "5m")
+	viper.SetDefault("auth.basic.source", "config")
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "email", "profile"})
+	viper.SetDefault("auth.oidc.postloginurl", "/")
+	viper.SetDefault("auth.oidc.clockskew", "30s")
+	viper.SetDefault("auth.basic.maxfailures", 5)
+	viper.SetDefault("auth.basic.lockoutduration", "15m")
+	viper.SetDefault("ratelimit.store", "memory")
+	viper.SetDefault("http.cors.allowmethods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	viper.SetDefault("http.cors.allowheaders", []string{"Authorization", "Content-Type", "X-API-Key", "Idempotency-Key", "If-None-Match", "If-Modified-Since"})
+	viper.SetDefault("http.cors.maxage", "10m")
+	viper.SetDefault("http.headers.contentsecuritypolicy", "default-src 'none'; frame-ancestors 'none'")
+	viper.SetDefault("http.headers.swaggercontentsecuritypolicy", "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'")
+	viper.SetDefault("http.maxbodysize", 1<<20)
+	viper.SetDefault("idempotency.ttl", "24h")
 
 	if err := viper.ReadInConfig(); err != nil {
 		return cfg, err
@@ -43,8 +328,9 @@ func LoadConfig(env string) (Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return cfg, err
 	}
-
-	fmt.Println(cfg)
+	if err := cfg.HTTP.CORS.Validate(); err != nil {
+		return cfg, err
+	}
 
 	return cfg, nil
 }
@@ -8,12 +8,51 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/nilemarezz/go-init-template/pkg/config"
+	_ "modernc.org/sqlite"
 )
 
+// Supported values for the database.driver config key.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// ConnectDB opens a connection for the configured database driver. It returns
+// a nil DB for the memory driver, which needs no connection.
 func ConnectDB(config *config.Config) (*sqlx.DB, error) {
-	// Database connection parameters
-	dbURI := fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=disable",
+	switch config.Database.Driver {
+	case DriverPostgres:
+		return connectPostgres(config)
+	case DriverSQLite:
+		return connectSQLite(config)
+	case DriverMemory:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", config.Database.Driver)
+}
+
+// connectSQLite opens the SQLite database file at config.Database.Path.
+func connectSQLite(config *config.Config) (*sqlx.DB, error) {
+	dsn := config.Database.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := sqlx.Connect(DriverSQLite, dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serialise access instead of failing with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	log.Printf("Connected to database")
+	return db, nil
+}
+
+// postgresDSN builds the Postgres connection parameters from config.
+func postgresDSN(config *config.Config) string {
+	return fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=disable",
 		config.Database.Host, config.Database.Port, config.Database.User, config.Database.DBName, config.Database.Password)
+}
+
+func connectPostgres(config *config.Config) (*sqlx.DB, error) {
+	dbURI := postgresDSN(config)
 
 	var db *sqlx.DB
 	var err error
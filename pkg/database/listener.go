@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// ChangeChannel is the NOTIFY channel used by the notify_row_change trigger.
+const ChangeChannel = "row_changes"
+
+// Change operations. OpResync tells subscribers that changes may have been
+// lost, for example because the change log was pruned while the listener was
+// disconnected, and that any derived state should be rebuilt.
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+	OpResync = "RESYNC"
+)
+
+const (
+	changeLogRetention = 24 * time.Hour
+	pruneInterval      = time.Hour
+	pingInterval       = 90 * time.Second
+	// backfillWindow is how far before a disconnect backfills start. Change
+	// times are taken when the writing transaction starts, and sequence
+	// numbers are handed out before commit, so changes committed after the
+	// disconnect can carry an earlier time or a lower seq than changes
+	// already seen. The window also absorbs clock skew with the database.
+	backfillWindow = 5 * time.Minute
+)
+
+// Change describes a single row change published by the database.
+type Change struct {
+	Seq   int64  `json:"seq" db:"seq"`
+	Table string `json:"table" db:"table_name"`
+	Op    string `json:"op" db:"op"`
+	ID    int    `json:"id" db:"row_id"`
+}
+
+// ChangeListener receives row change notifications over LISTEN/NOTIFY and fans
+// them out to in-process subscribers. After a reconnect it backfills missed
+// changes from the change_log table. Backfills replay every change from a
+// while before the disconnect, so subscribers may see a change more than
+// once.
+type ChangeListener struct {
+	dsn string
+	db  *sqlx.DB
+
+	mu          sync.RWMutex
+	subscribers map[int]func(Change)
+	nextID      int
+	// disconnectedAt is when the connection was last lost.
+	disconnectedAt time.Time
+}
+
+// NewChangeListener creates a listener for the configured Postgres database.
+// db is used to backfill and prune the change log.
+func NewChangeListener(config *config.Config, db *sqlx.DB) *ChangeListener {
+	return &ChangeListener{
+		dsn:         postgresDSN(config),
+		db:          db,
+		subscribers: make(map[int]func(Change)),
+	}
+}
+
+// Subscribe registers fn to receive every change, and returns a function that
+// removes the subscription. Subscribers are called sequentially from the
+// listener goroutine, must not block and must tolerate repeated changes.
+func (l *ChangeListener) Subscribe(fn func(Change)) (unsubscribe func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := l.nextID
+	l.nextID++
+	l.subscribers[id] = fn
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers, id)
+	}
+}
+
+// Run listens for changes until ctx is cancelled. Lost connections are retried
+// with backoff by the underlying pq.Listener.
+func (l *ChangeListener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Change listener event %d: %v", event, err)
+		}
+		if event == pq.ListenerEventDisconnected {
+			l.mu.Lock()
+			l.disconnectedAt = time.Now()
+			l.mu.Unlock()
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(ChangeChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			if n == nil {
+				// pq sends a nil notification after reconnecting.
+				l.backfill()
+				continue
+			}
+			l.dispatch(n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		case <-prune.C:
+			l.prune()
+		}
+	}
+}
+
+// dispatch decodes a NOTIFY payload and publishes it.
+func (l *ChangeListener) dispatch(payload string) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		log.Printf("Change listener: invalid payload %q: %v", payload, err)
+		return
+	}
+	l.publish(change)
+}
+
+func (l *ChangeListener) publish(change Change) {
+	l.mu.RLock()
+	subscribers := make([]func(Change), 0, len(l.subscribers))
+	for _, fn := range l.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	l.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+// backfill publishes the changes made from backfillWindow before the
+// connection was lost. If the change log may already have been pruned past
+// that point, subscribers get a resync first.
+func (l *ChangeListener) backfill() {
+	l.mu.RLock()
+	since := l.disconnectedAt.Add(-backfillWindow)
+	l.mu.RUnlock()
+
+	if since.Before(time.Now().Add(-changeLogRetention)) {
+		l.publish(Change{Op: OpResync})
+	}
+
+	var missed []Change
+	if err := l.db.Select(&missed, "SELECT seq, table_name, op, row_id FROM change_log WHERE changed_at >= $1 ORDER BY seq", since); err != nil {
+		log.Printf("Change listener: backfill failed: %v", err)
+		l.publish(Change{Op: OpResync})
+		return
+	}
+	for _, change := range missed {
+		l.publish(change)
+	}
+}
+
+func (l *ChangeListener) prune() {
+	cutoff := time.Now().Add(-changeLogRetention)
+	if _, err := l.db.Exec("DELETE FROM change_log WHERE changed_at < $1", cutoff); err != nil {
+		log.Printf("Change listener: failed to prune change log: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChangeListener() *ChangeListener {
+	return &ChangeListener{subscribers: make(map[int]func(Change))}
+}
+
+func TestChangeListener_FansOutToSubscribers(t *testing.T) {
+	// Arrange
+	listener := newTestChangeListener()
+	var first, second []Change
+	listener.Subscribe(func(c Change) { first = append(first, c) })
+	listener.Subscribe(func(c Change) { second = append(second, c) })
+
+	// Act
+	listener.dispatch(`{"seq":1,"table":"authors","op":"UPDATE","id":7}`)
+
+	// Assert
+	expected := []Change{{Seq: 1, Table: "authors", Op: OpUpdate, ID: 7}}
+	assert.Equal(t, expected, first)
+	assert.Equal(t, expected, second)
+}
+
+func TestChangeListener_Unsubscribe(t *testing.T) {
+	// Arrange
+	listener := newTestChangeListener()
+	calls := 0
+	unsubscribe := listener.Subscribe(func(Change) { calls++ })
+
+	// Act
+	unsubscribe()
+	listener.dispatch(`{"seq":1,"table":"authors","op":"INSERT","id":1}`)
+
+	// Assert
+	assert.Equal(t, 0, calls)
+}
+
+func TestChangeListener_DeliversChangesOutOfSeqOrder(t *testing.T) {
+	// Arrange
+	listener := newTestChangeListener()
+	var seqs []int64
+	listener.Subscribe(func(c Change) { seqs = append(seqs, c.Seq) })
+
+	// Act: the writer that got seq 11 commits after the one that got 12, and
+	// a backfill delivers 12 again.
+	listener.dispatch(`{"seq":12,"table":"authors","op":"UPDATE","id":2}`)
+	listener.dispatch(`{"seq":11,"table":"authors","op":"UPDATE","id":1}`)
+	listener.publish(Change{Seq: 12, Table: "authors", Op: OpUpdate, ID: 2})
+	listener.publish(Change{Op: OpResync})
+
+	// Assert
+	assert.Equal(t, []int64{12, 11, 12, 0}, seqs)
+}
+
+func TestChangeListener_IgnoresInvalidPayload(t *testing.T) {
+	// Arrange
+	listener := newTestChangeListener()
+	calls := 0
+	listener.Subscribe(func(Change) { calls++ })
+
+	// Act
+	listener.dispatch("not json")
+
+	// Assert
+	assert.Equal(t, 0, calls)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the embedded Postgres migrations that have not been applied
+// yet, in file name order. Each migration runs in its own transaction and is
+// recorded in the schema_migrations table.
+func Migrate(db *sqlx.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var applied []string
+	if err := db.Select(&applied, "SELECT version FROM schema_migrations"); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	done := make(map[string]bool, len(applied))
+	for _, version := range applied {
+		done[version] = true
+	}
+
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		if done[version] {
+			continue
+		}
+		if err := applyMigration(db, file, version); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %v", version, err)
+		}
+		log.Printf("Applied migration %s", version)
+	}
+	return nil
+}
+
+func applyMigration(db *sqlx.DB, file, version string) error {
+	script, err := migrations.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(string(script)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
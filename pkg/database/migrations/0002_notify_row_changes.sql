@@ -0,0 +1,39 @@
+-- change_log keeps a short history of row changes so listeners that were
+-- disconnected can backfill the notifications they missed.
+CREATE TABLE IF NOT EXISTS change_log (
+    seq        BIGSERIAL PRIMARY KEY,
+    table_name TEXT        NOT NULL,
+    op         TEXT        NOT NULL,
+    row_id     BIGINT      NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION notify_row_change() RETURNS trigger AS $$
+DECLARE
+    changed_id BIGINT;
+    change_seq BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_id := OLD.id;
+    ELSE
+        changed_id := NEW.id;
+    END IF;
+
+    INSERT INTO change_log (table_name, op, row_id)
+    VALUES (TG_TABLE_NAME, TG_OP, changed_id)
+    RETURNING seq INTO change_seq;
+
+    PERFORM pg_notify('row_changes', json_build_object(
+        'seq', change_seq,
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'id', changed_id
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS authors_notify_change ON authors;
+CREATE TRIGGER authors_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON authors
+    FOR EACH ROW EXECUTE FUNCTION notify_row_change();
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS basic_auth_credentials (
+    username      TEXT PRIMARY KEY,
+    password_hash TEXT   NOT NULL,
+    roles         TEXT[] NOT NULL DEFAULT '{}'
+);
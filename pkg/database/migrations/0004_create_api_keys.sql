@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           SERIAL PRIMARY KEY,
+    prefix       TEXT        NOT NULL UNIQUE,
+    key_hash     TEXT        NOT NULL,
+    name         TEXT        NOT NULL,
+    owner        TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    usage_count  BIGINT      NOT NULL DEFAULT 0,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_owner_idx ON api_keys (owner);
@@ -0,0 +1,10 @@
+-- Links users to accounts at external OpenID Connect providers.
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer     TEXT        NOT NULL,
+    subject    TEXT        NOT NULL,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS books (
+    id           SERIAL PRIMARY KEY,
+    title        TEXT NOT NULL,
+    isbn         TEXT NOT NULL UNIQUE,
+    published_on DATE,
+    language     TEXT NOT NULL DEFAULT ''
+);
+
+-- Authors credited on a book, in credit order. Deleting a book removes its
+-- credits; deleting an author who is still credited is refused.
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id   INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES authors (id) ON DELETE RESTRICT,
+    role      TEXT    NOT NULL DEFAULT 'author',
+    position  INTEGER NOT NULL CHECK (position > 0),
+    PRIMARY KEY (book_id, author_id, role),
+    UNIQUE (book_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
@@ -0,0 +1,10 @@
+ALTER TABLE authors
+    ADD COLUMN IF NOT EXISTS sort_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS given_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS family_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS birth_date TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS death_date TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS nationality TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS biography TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS links JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS identifiers JSONB NOT NULL DEFAULT '{}';
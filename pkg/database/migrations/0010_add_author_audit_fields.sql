@@ -0,0 +1,11 @@
+ALTER TABLE authors
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+
+-- Rows written before this migration were last changed by their creator as
+-- far as we know.
+UPDATE authors SET updated_by = created_by WHERE updated_by = '';
+
+-- Serves ?updated_since= delta queries.
+CREATE INDEX IF NOT EXISTS authors_updated_at_idx ON authors (updated_at, id);
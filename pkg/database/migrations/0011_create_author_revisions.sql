@@ -0,0 +1,14 @@
+-- One row per create, update, delete or revert of an author, written in the
+-- same transaction as the change. There is no foreign key to authors so the
+-- history outlives a deleted author.
+CREATE TABLE IF NOT EXISTS author_revisions (
+    author_id       INTEGER     NOT NULL,
+    revision        INTEGER     NOT NULL CHECK (revision > 0),
+    op              TEXT        NOT NULL,
+    before_snapshot JSONB,
+    after_snapshot  JSONB,
+    actor           TEXT        NOT NULL DEFAULT '',
+    request_id      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (author_id, revision)
+);
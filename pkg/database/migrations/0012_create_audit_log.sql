@@ -0,0 +1,30 @@
+-- Append-only, hash-chained audit log; see internal/audit. data is TEXT
+-- rather than JSONB so it is stored byte for byte and its hash can be
+-- recomputed. The triggers reject changes through SQL; changes made around
+-- them still break the hash chain, which "audit verify" reports.
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq         BIGINT      PRIMARY KEY CHECK (seq > 0),
+    time        TIMESTAMPTZ NOT NULL,
+    type        TEXT        NOT NULL,
+    actor       TEXT        NOT NULL DEFAULT '',
+    request_id  TEXT        NOT NULL DEFAULT '',
+    resource    TEXT        NOT NULL DEFAULT '',
+    resource_id TEXT        NOT NULL DEFAULT '',
+    data        TEXT        NOT NULL DEFAULT '',
+    prev_hash   TEXT        NOT NULL,
+    hash        TEXT        NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_or_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
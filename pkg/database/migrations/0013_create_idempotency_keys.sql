@@ -0,0 +1,14 @@
+-- Responses stored by Idempotency-Key so that retried POST and PATCH
+-- requests are replayed rather than applied again; see
+-- internal/idempotency. Expired rows are removed as new ones are stored.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key         TEXT        PRIMARY KEY,
+    fingerprint TEXT        NOT NULL,
+    status      INTEGER     NOT NULL,
+    header      TEXT        NOT NULL DEFAULT '{}',
+    body        BYTEA       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,3 @@
+-- Serves backfills, which replay the changes from a while before a
+-- disconnect, and pruning.
+CREATE INDEX IF NOT EXISTS change_log_changed_at_idx ON change_log (changed_at);
@@ -0,0 +1,395 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7396) documents to JSON documents.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two patch formats.
+const (
+	MediaTypeJSONPatch  = "application/json-patch+json"
+	MediaTypeMergePatch = "application/merge-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned for patch documents that are not valid
+	// JSON, or not valid patches.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation does not match the
+	// document.
+	ErrTestFailed = errors.New("test operation failed")
+	// ErrCannotApply is returned when an operation refers to a location that
+	// does not exist in the document or cannot hold a value.
+	ErrCannotApply = errors.New("patch cannot be applied")
+)
+
+// Operation is one step of a JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch document: operations applied in order, all or none.
+type Patch []Operation
+
+// DecodePatch parses a JSON Patch document.
+func DecodePatch(b []byte) (Patch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch must be an array of operations: %v", ErrInvalidPatch, err)
+	}
+	patch := make(Patch, len(raw))
+	for i, fields := range raw {
+		op := &patch[i]
+		for name, dest := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+			if value, ok := fields[name]; ok {
+				if err := json.Unmarshal(value, dest); err != nil {
+					return nil, fmt.Errorf("%w: operation %d: %q must be a string", ErrInvalidPatch, i, name)
+				}
+			}
+		}
+		// The value member may be null, which is present but decodes to
+		// nothing, so look it up rather than decoding the operation whole.
+		value, hasValue := fields["value"]
+		op.Value = value
+		_, hasPath := fields["path"]
+		_, hasFrom := fields["from"]
+		switch op.Op {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, fmt.Errorf("%w: operation %d: %s needs a value", ErrInvalidPatch, i, op.Op)
+			}
+		case "move", "copy":
+			if !hasFrom {
+				return nil, fmt.Errorf("%w: operation %d: %s needs a from", ErrInvalidPatch, i, op.Op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+		if !hasPath {
+			return nil, fmt.Errorf("%w: operation %d: %s needs a path", ErrInvalidPatch, i, op.Op)
+		}
+	}
+	return patch, nil
+}
+
+// Apply applies the patch to doc and returns the patched document. doc is
+// not modified; if any operation fails, no change is returned.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if root, err = op.apply(root); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: value is not valid JSON", ErrInvalidPatch)
+		}
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if root, _, err = remove(root, path); err != nil {
+				return nil, err
+			}
+			return add(root, path, value)
+		}
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: %s does not have the expected value", ErrTestFailed, op.Path)
+		}
+		return root, nil
+	case "remove":
+		root, _, err = remove(root, path)
+		return root, err
+	}
+
+	from, err := parsePointer(op.From)
+	if err != nil {
+		return nil, err
+	}
+	if op.Op == "move" {
+		if op.From == op.Path {
+			return root, nil
+		}
+		if isPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", ErrCannotApply, op.From)
+		}
+		root, value, err := remove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	}
+	value, err := get(root, from)
+	if err != nil {
+		return nil, err
+	}
+	// Copy through JSON so the two locations do not share containers.
+	encoded, _ := json.Marshal(value)
+	value, _ = decode(encoded)
+	return add(root, path, value)
+}
+
+// MergePatch applies a JSON Merge Patch to doc and returns the patched
+// document: members of patch replace those of doc, objects merge
+// recursively and null removes a member.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	merge, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergePatch(target, merge))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = mergePatch(object[name], value)
+		}
+	}
+	return object
+}
+
+// decode parses a JSON document, keeping numbers exact.
+func decode(b []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q is not a JSON Pointer", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index parses an array index token. end allows the index just past the
+// last element, which add uses to append.
+func index(token string, length int, end bool) (int, error) {
+	if end && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || strings.TrimLeft(token, "0123456789") != "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrCannotApply, token)
+	}
+	if i > length || (i == length && !end) {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrCannotApply, i)
+	}
+	return i, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrCannotApply, token)
+			}
+			node = value
+		case []interface{}:
+			i, err := index(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[i]
+		default:
+			return nil, fmt.Errorf("%w: %q does not refer into an object or array", ErrCannotApply, token)
+		}
+	}
+	return node, nil
+}
+
+// add sets the value at path and returns the new root. The parent of path
+// must exist.
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return root, nil
+	case []interface{}:
+		i, err := index(token, len(container), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := append(container[:i:i], value)
+		grown = append(grown, container[i:]...)
+		return replaceContainer(root, path[:len(path)-1], grown)
+	}
+	return nil, fmt.Errorf("%w: the parent of %q is not an object or array", ErrCannotApply, token)
+}
+
+// remove deletes the value at path, which must exist, and returns the new
+// root and the removed value.
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrCannotApply)
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		value, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q does not exist", ErrCannotApply, token)
+		}
+		delete(container, token)
+		return root, value, nil
+	case []interface{}:
+		i, err := index(token, len(container), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := container[i]
+		shrunk := append(container[:i:i], container[i+1:]...)
+		root, err = replaceContainer(root, path[:len(path)-1], shrunk)
+		return root, value, err
+	}
+	return nil, nil, fmt.Errorf("%w: the parent of %q is not an object or array", ErrCannotApply, token)
+}
+
+// replaceContainer stores a resized array back at path, since growing or
+// shrinking a slice does not update the slice held by its parent.
+func replaceContainer(root interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = array
+	case []interface{}:
+		i, _ := strconv.Atoi(token)
+		container[i] = array
+	}
+	return root, nil
+}
+
+// equal compares two decoded JSON values as RFC 6902 test does: numbers by
+// value, objects regardless of member order.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, value := range x {
+			other, ok := y[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, okx := new(big.Float).SetString(string(x))
+		fy, oky := new(big.Float).SetString(string(y))
+		return okx && oky && fx.Cmp(fy) == 0
+	}
+	return a == b
+}
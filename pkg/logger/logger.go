@@ -13,6 +13,10 @@ import (
 
 var Logger *zap.Logger
 
+// Level is the level of Logger. It can be changed at runtime and serves
+// GET/PUT requests with a JSON {"level": "..."} body as an http.Handler.
+var Level = zap.NewAtomicLevel()
+
 func InitLogger(config *config.Config) error {
 	// Define logs directory path
 	logsDir := filepath.Join(config.Log.Path)
@@ -43,17 +47,20 @@ func InitLogger(config *config.Config) error {
 	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 
 	// Configure log level
-	atomicLevel := zap.NewAtomicLevel()
-	atomicLevel.SetLevel(zapcore.InfoLevel)
+	if config.Log.Level != "" {
+		if err := Level.UnmarshalText([]byte(config.Log.Level)); err != nil {
+			return fmt.Errorf("invalid log level: %v", err)
+		}
+	}
 
 	// Create a Zap encoder
 	encoder := zapcore.NewJSONEncoder(encoderConfig)
 
 	// Create a Zap core for writing to the file
-	fileCore := zapcore.NewCore(encoder, zapcore.AddSync(logFile), atomicLevel)
+	fileCore := zapcore.NewCore(encoder, zapcore.AddSync(logFile), Level)
 
 	// Create a Zap core for writing to the console
-	consoleCore := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), atomicLevel)
+	consoleCore := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), Level)
 
 	// Combine both cores
 	multiCore := zapcore.NewTee(fileCore, consoleCore)
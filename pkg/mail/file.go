@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender writes each message to an .eml file instead of delivering it.
+// It stands in for SMTP during local development.
+type FileSender struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+// NewFileSender creates dir if needed and returns a FileSender writing to it.
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (f *FileSender) Send(msg Message) error {
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102T150405"), f.seq.Add(1))
+	return os.WriteFile(filepath.Join(f.dir, name), format(f.from, msg), 0o600)
+}
@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender_WritesMessages(t *testing.T) {
+	// Arrange
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender, err := NewFileSender(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	// Act
+	err1 := sender.Send(Message{To: "jane@example.com", Subject: "Hello", Body: "first"})
+	err2 := sender.Send(Message{To: "john@example.com", Subject: "Hello", Body: "second"})
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "From: no-reply@example.com\r\nTo: jane@example.com\r\nSubject: Hello\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nfirst")
+}
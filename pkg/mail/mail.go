@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"fmt"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Supported values for the mail.backend config key.
+const (
+	BackendFile = "file"
+	BackendSMTP = "smtp"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSender returns the Sender implementation for the configured backend.
+func NewSender(config *config.Config) (Sender, error) {
+	switch config.Mail.Backend {
+	case BackendFile:
+		return NewFileSender(config.Mail.Dir, config.Mail.From)
+	case BackendSMTP:
+		return NewSMTPSender(config.Mail.SMTPAddr, config.Mail.Username, config.Mail.Password, config.Mail.From), nil
+	}
+	return nil, fmt.Errorf("unsupported mail backend %q", config.Mail.Backend)
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message) []byte {
+	return []byte("From: " + from + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		msg.Body)
+}
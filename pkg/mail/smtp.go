@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+)
+
+// SMTPSender delivers messages through an SMTP relay. It authenticates with
+// PLAIN auth when a username is set, which net/smtp only allows over TLS or
+// to localhost.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates an SMTPSender for the relay at addr (host:port).
+func NewSMTPSender(addr, username, password, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{addr: addr, auth: auth, from: from}
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, format(s.from, msg))
+}
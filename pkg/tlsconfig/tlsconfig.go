@@ -0,0 +1,88 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+)
+
+// Supported values for the app.tls.clientauth config key.
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	ClientAuthNone:    tls.NoClientCert,
+	ClientAuthRequest: tls.VerifyClientCertIfGiven,
+	ClientAuthRequire: tls.RequireAndVerifyClientCert,
+}
+
+// NewServerConfig builds the server tls.Config from config. Certificates and
+// client CAs are read from reloader on every handshake.
+func NewServerConfig(config config.TLSConfig, reloader *Reloader) (*tls.Config, error) {
+	minVersion, ok := versions[config.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS min version %q", config.MinVersion)
+	}
+	clientAuth, ok := clientAuthTypes[config.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS client auth %q", config.ClientAuth)
+	}
+	if clientAuth != tls.NoClientCert && config.ClientCAFile == "" {
+		return nil, fmt.Errorf("TLS client auth %q needs a client CA file", config.ClientAuth)
+	}
+	cipherSuites, err := parseCipherSuites(config.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if clientAuth == tls.NoClientCert {
+		return base, nil
+	}
+
+	// ClientCAs is read from the Config itself, so hand out a copy carrying
+	// the current pool for each handshake.
+	server := base.Clone()
+	server.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		handshake := base.Clone()
+		handshake.ClientCAs = reloader.ClientCAs()
+		return handshake, nil
+	}
+	return server, nil
+}
+
+// parseCipherSuites maps Go cipher suite names to their IDs. Insecure suites
+// are rejected.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
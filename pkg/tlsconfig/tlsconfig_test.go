@@ -0,0 +1,195 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilemarezz/go-init-template/pkg/config"
+	"github.com/nilemarezz/go-init-template/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	os.Exit(m.Run())
+}
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue creates a certificate for subject signed by parent, or self-signed
+// when parent is nil.
+func issue(t *testing.T, subject pkix.Name, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	}
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestReloader_ReloadsChangedFiles(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := issue(t, pkix.Name{CommonName: "first"}, nil, false)
+	first.write(t, certFile, keyFile)
+	reloader, err := NewReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+
+	// Act
+	second := issue(t, pkix.Name{CommonName: "second"}, nil, false)
+	second.write(t, certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	changed := reloader.changed()
+	require.NoError(t, reloader.Reload())
+	cert, _ := reloader.GetCertificate(nil)
+
+	// Assert
+	assert.True(t, changed)
+	assert.False(t, reloader.changed())
+	assert.Equal(t, second.cert.Raw, cert.Certificate[0])
+}
+
+func TestReloader_KeepsCertificateWhenReloadFails(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	original := issue(t, pkix.Name{CommonName: "original"}, nil, false)
+	original.write(t, certFile, keyFile)
+	reloader, err := NewReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	reloadErr := reloader.Reload()
+	cert, _ := reloader.GetCertificate(nil)
+
+	// Assert
+	assert.Error(t, reloadErr)
+	assert.Equal(t, original.cert.Raw, cert.Certificate[0])
+}
+
+func TestNewServerConfig_Validates(t *testing.T) {
+	// Arrange
+	reloader := &Reloader{}
+	valid := config.TLSConfig{MinVersion: "1.2", ClientAuth: ClientAuthNone}
+
+	// Act
+	_, okErr := NewServerConfig(valid, reloader)
+	_, versionErr := NewServerConfig(config.TLSConfig{MinVersion: "1.0", ClientAuth: ClientAuthNone}, reloader)
+	_, clientAuthErr := NewServerConfig(config.TLSConfig{MinVersion: "1.2", ClientAuth: ClientAuthRequire}, reloader)
+	_, suiteErr := NewServerConfig(config.TLSConfig{MinVersion: "1.2", ClientAuth: ClientAuthNone, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, reloader)
+	suites, err := NewServerConfig(config.TLSConfig{MinVersion: "1.2", ClientAuth: ClientAuthNone, CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}, reloader)
+
+	// Assert
+	assert.NoError(t, okErr)
+	assert.Error(t, versionErr)
+	assert.Error(t, clientAuthErr)
+	assert.Error(t, suiteErr)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, suites.CipherSuites)
+}
+
+func TestNewServerConfig_MutualTLS(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := issue(t, pkix.Name{CommonName: "test ca"}, nil, true)
+	ca.write(t, caFile, "")
+	serverCert := issue(t, pkix.Name{CommonName: "localhost"}, ca, false)
+	serverCert.write(t, certFile, keyFile)
+	clientCert := issue(t, pkix.Name{CommonName: "catalog-sync", OrganizationalUnit: []string{"editor"}}, ca, false)
+	strangerCA := issue(t, pkix.Name{CommonName: "stranger ca"}, nil, true)
+	stranger := issue(t, pkix.Name{CommonName: "stranger"}, strangerCA, false)
+
+	reloader, err := NewReloader(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	serverConfig, err := NewServerConfig(config.TLSConfig{MinVersion: "1.3", ClientAuth: ClientAuthRequire, ClientCAFile: caFile}, reloader)
+	require.NoError(t, err)
+
+	var subject string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}))
+	server.TLS = serverConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(cert *testCert) *http.Client {
+		clientConfig := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			clientConfig.Certificates = []tls.Certificate{cert.tls()}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+	}
+
+	// Act
+	resp, trustedErr := client(clientCert).Get(server.URL)
+	if trustedErr == nil {
+		resp.Body.Close()
+	}
+	_, strangerErr := client(stranger).Get(server.URL)
+	_, anonymousErr := client(nil).Get(server.URL)
+
+	// Assert
+	assert.NoError(t, trustedErr)
+	assert.Equal(t, "catalog-sync", subject)
+	assert.Error(t, strangerErr)
+	assert.Error(t, anonymousErr)
+}
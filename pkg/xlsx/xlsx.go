@@ -0,0 +1,472 @@
+// Package xlsx reads and writes the first worksheet of Office Open XML
+// spreadsheets (.xlsx) as rows of strings. It covers what tabular exports
+// and imports need: written cells are plain text, and on reading formatting,
+// formulas and further worksheets are ignored, except that cells formatted
+// as dates are read as YYYY-MM-DD.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of .xlsx files.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// maxPartSize caps the uncompressed size of the parts that are read whole,
+// so that a small upload cannot expand into an unbounded amount of memory.
+const maxPartSize = 64 << 20
+
+var (
+	// ErrInvalidFile is returned for files that are not .xlsx spreadsheets.
+	ErrInvalidFile = errors.New("not a valid xlsx file")
+	// ErrPartTooLarge is returned when a part of the file exceeds the size
+	// the reader accepts.
+	ErrPartTooLarge = errors.New("xlsx file is too large")
+)
+
+const (
+	nsMain          = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+// staticParts are written ahead of the worksheet. They declare a workbook
+// with a single sheet named Sheet1.
+var staticParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRelationships + `">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// Writer writes rows to a spreadsheet as they come, so that the whole
+// sheet is never held in memory. Close must be called to complete the
+// file.
+type Writer struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// NewWriter starts a spreadsheet on w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range staticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="`+nsMain+`"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// Write appends a row. Every cell is written as text; empty cells are left
+// out.
+func (w *Writer) Write(record []string) error {
+	w.rows++
+	var b strings.Builder
+	row := strconv.Itoa(w.rows)
+	b.WriteString(`<row r="` + row + `">`)
+	for i, value := range record {
+		if value == "" {
+			continue
+		}
+		b.WriteString(`<c r="` + columnName(i) + row + `" t="inlineStr"><is><t xml:space="preserve">`)
+		// EscapeText also replaces characters that XML cannot carry.
+		xml.EscapeText(&b, []byte(value))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+// Close completes the spreadsheet. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// columnName returns the letters of the zero-based column i: A to Z, then
+// AA and so on.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// columnIndex returns the zero-based column of a cell reference such as
+// "AB12", or -1 if it has no column letters.
+func columnIndex(ref string) int {
+	i := 0
+	n := 0
+	for ; n < len(ref) && ref[n] >= 'A' && ref[n] <= 'Z'; n++ {
+		i = i*26 + int(ref[n]-'A'+1)
+	}
+	return i - 1
+}
+
+// Reader reads the rows of the first worksheet of a spreadsheet one at a
+// time.
+type Reader struct {
+	strings []string
+	// dateStyles marks the cell styles, by index, that format numbers as
+	// dates.
+	dateStyles map[int]bool
+	sheet      io.ReadCloser
+	decoder    *xml.Decoder
+	row        int
+}
+
+// NewReader opens the spreadsheet in r, which holds size bytes.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetName, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	sheetFile, ok := files[sheetName]
+	if !ok {
+		return nil, ErrInvalidFile
+	}
+	reader := &Reader{}
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if reader.strings, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := files["xl/styles.xml"]; ok {
+		if reader.dateStyles, err = readDateStyles(f); err != nil {
+			return nil, err
+		}
+	}
+	if reader.sheet, err = sheetFile.Open(); err != nil {
+		return nil, err
+	}
+	reader.decoder = xml.NewDecoder(reader.sheet)
+	return reader, nil
+}
+
+// Read returns the cells of the next row, up to its last cell that has a
+// value, or io.EOF after the last row. Rows without any cells may be left
+// out of the file; Row tells which row was read.
+func (r *Reader) Read() ([]string, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, ErrInvalidFile
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		r.row++
+		if ref, ok := attr(start, "r"); ok {
+			if n, err := strconv.Atoi(ref); err == nil && n >= r.row {
+				r.row = n
+			}
+		}
+		return r.readRow()
+	}
+}
+
+// Row returns the number, counting from 1, of the row last read.
+func (r *Reader) Row() int {
+	return r.row
+}
+
+// Close releases the worksheet.
+func (r *Reader) Close() error {
+	return r.sheet.Close()
+}
+
+// readRow reads the cells of the row whose start element was just read.
+func (r *Reader) readRow() ([]string, error) {
+	var cells []string
+	next := 0
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, ErrInvalidFile
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			column := next
+			if ref, ok := attr(t, "r"); ok && columnIndex(ref) >= 0 {
+				column = columnIndex(ref)
+			}
+			value, err := r.readCell(t)
+			if err != nil {
+				return nil, err
+			}
+			next = column + 1
+			if value == "" {
+				continue
+			}
+			for len(cells) < column {
+				cells = append(cells, "")
+			}
+			cells = append(cells[:column], value)
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return cells, nil
+			}
+		}
+	}
+}
+
+// readCell reads the value of the cell that starts with start.
+func (r *Reader) readCell(start xml.StartElement) (string, error) {
+	var cell struct {
+		Type   string   `xml:"t,attr"`
+		Style  int      `xml:"s,attr"`
+		Value  string   `xml:"v"`
+		Inline richText `xml:"is"`
+	}
+	if err := r.decoder.DecodeElement(&cell, &start); err != nil {
+		return "", ErrInvalidFile
+	}
+	switch cell.Type {
+	case "inlineStr":
+		return cell.Inline.String(), nil
+	case "s":
+		i, err := strconv.Atoi(cell.Value)
+		if err != nil || i < 0 || i >= len(r.strings) {
+			return "", ErrInvalidFile
+		}
+		return r.strings[i], nil
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "", "n":
+		if r.dateStyles[cell.Style] && cell.Value != "" {
+			if serial, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+				return dateFromSerial(serial), nil
+			}
+		}
+	}
+	// Formula results ("str") and errors ("e") are read as they are.
+	return cell.Value, nil
+}
+
+// excelEpoch is day 0 of the 1900 date system. It lies two days before
+// 1900-01-01 because the system counts a 29 February 1900 that did not
+// exist.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// dateFromSerial formats a date of the 1900 date system as YYYY-MM-DD. The
+// time of day, if any, is dropped.
+func dateFromSerial(serial float64) string {
+	return excelEpoch.AddDate(0, 0, int(math.Floor(serial))).Format("2006-01-02")
+}
+
+// richText is a string item: plain text, or runs of formatted text.
+// Phonetic hints are left out.
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// firstSheet returns the name of the part that holds the first worksheet
+// of the workbook.
+func firstSheet(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", ErrInvalidFile
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", ErrInvalidFile
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var table struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodeFile(f, &table); err != nil {
+		return nil, err
+	}
+	values := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		values[i] = item.String()
+	}
+	return values, nil
+}
+
+// builtinDateFormats are the ids of the built-in number formats that show
+// dates.
+var builtinDateFormats = map[int]bool{14: true, 15: true, 16: true, 17: true, 22: true, 27: true, 30: true, 36: true, 50: true, 57: true}
+
+// readDateStyles returns the cell styles whose number format shows a date.
+func readDateStyles(f *zip.File) (map[int]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeFile(f, &styles); err != nil {
+		return nil, err
+	}
+	dateFormats := make(map[int]bool, len(builtinDateFormats))
+	for id := range builtinDateFormats {
+		dateFormats[id] = true
+	}
+	for _, format := range styles.NumFmts {
+		dateFormats[format.ID] = isDateFormat(format.Code)
+	}
+	dateStyles := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		if dateFormats[xf.NumFmtID] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles, nil
+}
+
+// isDateFormat reports whether a custom number format code shows a date:
+// whether it has a day, month or year outside quoted text, escapes and
+// colour or condition brackets.
+func isDateFormat(code string) bool {
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			for i++; i < len(code) && code[i] != '"'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			for i++; i < len(code) && code[i] != ']'; i++ {
+			}
+		case 'd', 'D', 'y', 'Y':
+			return true
+		}
+	}
+	return false
+}
+
+func decodePart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return ErrInvalidFile
+	}
+	return decodeFile(f, v)
+}
+
+// decodeFile decodes the XML part f into v.
+func decodeFile(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > maxPartSize {
+		return ErrPartTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ErrInvalidFile
+	}
+	defer rc.Close()
+	// The declared size cannot be trusted, so the read is capped as well.
+	limited := &io.LimitedReader{R: rc, N: maxPartSize + 1}
+	if err := xml.NewDecoder(limited).Decode(v); err != nil {
+		if limited.N <= 0 {
+			return ErrPartTooLarge
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return nil
+}
+
+func attr(start xml.StartElement, name string) (string, bool) {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
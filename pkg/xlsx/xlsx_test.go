@@ -0,0 +1,147 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, file []byte) ([][]string, []int) {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	defer r.Close()
+	var rows [][]string
+	var numbers []int
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, numbers
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+		numbers = append(numbers, r.Row())
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	want := [][]string{
+		{"id", "name", "biography"},
+		{"1", "Ursula K. Le Guin", "Wrote <Earthsea> & \"The Dispossessed\"\nin Portland"},
+		{"2", "", "  leading spaces"},
+	}
+	wide := make([]string, 30)
+	wide[27] = "AB"
+	want = append(want, wide)
+
+	// Act
+	for _, row := range want {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+	rows, numbers := readAll(t, buf.Bytes())
+
+	// Assert
+	want[3] = want[3][:28]
+	assert.Equal(t, want, rows)
+	assert.Equal(t, []int{1, 2, 3, 4}, numbers)
+}
+
+// spreadsheet builds a file the way spreadsheet applications write them:
+// shared strings, styles and a sheet that is not named sheet1.
+func spreadsheet(t *testing.T, sheet string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRelationships + `">
+			<sheets><sheet name="Authors" sheetId="7" r:id="rId3"/><sheet name="Other" sheetId="8" r:id="rId4"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId4" Target="worksheets/other.xml"/>
+			<Relationship Id="rId3" Target="/xl/worksheets/authors.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="` + nsMain + `"><si><t>name</t></si><si><t>birth_date</t></si>
+			<si><r><t>Octavia </t></r><r><rPr><b/></rPr><t>Butler</t></r><rPh><t>ignored</t></rPh></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="` + nsMain + `"><numFmts count="2">
+			<numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/><numFmt numFmtId="165" formatCode="&quot;day&quot; 0.00"/></numFmts>
+			<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`,
+		"xl/worksheets/authors.xml": `<worksheet xmlns="` + nsMain + `"><sheetData>` + sheet + `</sheetData></worksheet>`,
+		"xl/worksheets/other.xml":   `<worksheet xmlns="` + nsMain + `"><sheetData><row r="1"><c t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(f, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReader_ApplicationFile(t *testing.T) {
+	// Arrange
+	file := spreadsheet(t, `
+		<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+		<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3" s="1"><v>10522</v></c><c r="D3" t="b"><v>1</v></c></row>
+		<row r="4"><c r="B4" s="2"><v>17461.5</v></c><c r="C4" s="3"><v>2.5</v></c><c r="E4" t="str"><f>A1</f><v>name</v></c></row>`)
+
+	// Act
+	rows, numbers := readAll(t, file)
+
+	// Assert
+	assert.Equal(t, [][]string{
+		{"name", "birth_date"},
+		{"Octavia Butler", "1928-10-21", "", "TRUE"},
+		{"", "1947-10-21", "2.5", "", "name"},
+	}, rows)
+	assert.Equal(t, []int{1, 3, 4}, numbers)
+}
+
+func TestNewReader_InvalidFile(t *testing.T) {
+	tests := map[string][]byte{
+		"not a zip":      []byte("name,birth_date\n"),
+		"missing sheet":  spreadsheetWithout(t, "xl/worksheets/authors.xml"),
+		"bad string ref": spreadsheet(t, `<row r="1"><c r="A1" t="s"><v>9</v></c></row>`),
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(file), int64(len(file)))
+			if err == nil {
+				_, err = r.Read()
+			}
+			assert.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
+
+// spreadsheetWithout rebuilds spreadsheet without the named part.
+func spreadsheetWithout(t *testing.T, name string) []byte {
+	t.Helper()
+	file := spreadsheet(t, "")
+	zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		if f.Name == name {
+			continue
+		}
+		require.NoError(t, zw.Copy(f))
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, columnName(i))
+		assert.Equal(t, i, columnIndex(want+"12"))
+	}
+}